@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	"temporal-hotel-sample/internal/api"
+	"temporal-hotel-sample/internal/bookingclient"
+	"temporal-hotel-sample/internal/config"
+)
+
+// shutdownTimeout 停止時に処理中のリクエストの完了を待つ時間
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	configFile := flag.String("config", os.Getenv(config.EnvWorkerConfigFile), "Temporalへの接続設定を読み込むワーカー設定ファイル（YAML/JSON）")
+	flag.Parse()
+
+	// Temporalクライアントの作成（接続先・TLS・タスクキューの設定はワーカーと共通）
+	clientConfig, err := config.LoadClientConfig(*configFile)
+	if err != nil {
+		log.Fatalln("Invalid temporal config", err)
+	}
+	clientOptions, err := clientConfig.Temporal.ClientOptions()
+	if err != nil {
+		log.Fatalln("Invalid temporal config", err)
+	}
+	c, err := client.Dial(clientOptions)
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	server := &http.Server{
+		Addr:              envOrDefault("API_LISTEN_ADDRESS", ":8081"),
+		Handler:           api.NewServer(bookingclient.New(c, clientConfig.TaskQueue)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Println("Unable to shut down API server", err)
+		}
+	}()
+
+	log.Println("Starting hotel booking API on", server.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalln("Unable to start API server", err)
+	}
+
+	log.Println("API server stopped")
+}
+
+// envOrDefault 環境変数の値を返す（未設定の場合は既定値）
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
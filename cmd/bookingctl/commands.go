@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"temporal-hotel-sample/internal/bookingclient"
+	"temporal-hotel-sample/internal/workflows"
+)
+
+// runStart 予約Sagaを開始する
+// -file のBookingRequestに、明示的に指定したフラグの値を上書きする
+func runStart(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	file := fs.String("file", "", "BookingRequestのJSONファイル（- で標準入力）")
+	wait := fs.Bool("wait", false, "予約Sagaの終了を待って結果を表示する")
+	watch := fs.Bool("watch", false, "予約が確定または失敗するまで状況を表示する")
+	interval := fs.Duration("interval", time.Second, "-watch の問い合わせ間隔")
+
+	var request workflows.BookingRequest
+	fs.StringVar(&request.BookingID, "booking-id", "", "予約ID（ワークフローID）")
+	fs.StringVar(&request.UserID, "user-id", "", "ユーザーID")
+	fs.StringVar(&request.Hotel.HotelID, "hotel-id", "", "ホテルID")
+	fs.Var((*timeFlag)(&request.Hotel.CheckIn), "check-in", "チェックイン日時（RFC3339）")
+	fs.Var((*timeFlag)(&request.Hotel.CheckOut), "check-out", "チェックアウト日時（RFC3339）")
+	fs.StringVar(&request.Hotel.RoomType, "room-type", "", "部屋タイプ")
+	parallel := fs.Bool("parallel", false, "依存関係のないステップを並行して実行する")
+
+	var dinner workflows.DinnerRequest
+	fs.StringVar(&dinner.MenuType, "dinner-menu", "", "ディナーのメニュー（指定するとディナーを予約する）")
+	fs.Var((*timeFlag)(&dinner.DateTime), "dinner-time", "ディナーの日時（RFC3339）")
+	fs.IntVar(&dinner.Guests, "guests", 0, "ディナーの人数")
+	fs.BoolVar(&dinner.BestEffort, "dinner-best-effort", false, "ディナーの予約に失敗してもホテル予約を取り消さない")
+
+	var parking workflows.ParkingRequest
+	fs.StringVar(&parking.SpaceType, "parking-space", "", "駐車場のスペースタイプ（指定すると駐車場を予約する）")
+	fs.Var((*timeFlag)(&parking.StartTime), "parking-start", "駐車場の利用開始日時（RFC3339）")
+	fs.Var((*timeFlag)(&parking.EndTime), "parking-end", "駐車場の利用終了日時（RFC3339）")
+	fs.BoolVar(&parking.BestEffort, "parking-best-effort", false, "駐車場の予約に失敗してもホテル予約を取り消さない")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var base workflows.BookingRequest
+	if *file != "" {
+		if err := readJSON(*file, &base); err != nil {
+			return err
+		}
+	}
+	request.Dinner, request.Parking = &dinner, &parking
+	request = mergeBookingRequest(base, request, setFlags(fs))
+	if *parallel {
+		request.Execution = workflows.ExecutionParallel
+	}
+
+	submission, err := c.Start(ctx, request)
+	if err != nil {
+		return err
+	}
+	if submission.Duplicate {
+		fmt.Fprintf(os.Stderr, "同じ内容の予約Sagaが既に開始されています: BookingID=%s RunID=%s\n", submission.BookingID, submission.RunID)
+	} else {
+		fmt.Fprintf(os.Stderr, "予約Sagaを開始しました: BookingID=%s RunID=%s\n", submission.BookingID, submission.RunID)
+	}
+
+	switch {
+	case *wait:
+		result, err := c.Result(ctx, request.BookingID)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	case *watch:
+		return watchBooking(ctx, c, request.BookingID, *interval, settledPhases)
+	default:
+		return printJSON(submission)
+	}
+}
+
+// runStatus 予約の現在の状況を表示する
+func runStatus(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	bookingID, err := parseWithBookingID(fs, args)
+	if err != nil {
+		return err
+	}
+	status, err := c.Status(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	return printJSON(status)
+}
+
+// settledPhases watchの既定の終了フェーズ（予約が確定した、または手動対応待ちになった）
+var settledPhases = []workflows.BookingPhase{workflows.PhaseAwaitingCheckIn, workflows.PhaseAwaitingManualResolution}
+
+// runWatch フェーズが変わるたびに予約の状況を表示する
+func runWatch(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", time.Second, "問い合わせ間隔")
+	until := fs.String("until", joinPhases(settledPhases), "表示を終了するフェーズ（カンマ区切り、doneは常に終了する）")
+	bookingID, err := parseWithBookingID(fs, args)
+	if err != nil {
+		return err
+	}
+	return watchBooking(ctx, c, bookingID, *interval, splitPhases(*until))
+}
+
+// watchBooking フェーズが変わるたびに1行ずつ表示し、最後に予約の状況を表示する
+func watchBooking(ctx context.Context, c *bookingclient.Client, bookingID string, interval time.Duration, until []workflows.BookingPhase) error {
+	status, err := c.Watch(ctx, bookingID, interval, until, func(s workflows.BookingStatus) {
+		fmt.Fprintf(os.Stderr, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), s.Phase, s.Message)
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(status)
+}
+
+// runResult 予約Sagaの終了を待って結果を表示する
+func runResult(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("result", flag.ContinueOnError)
+	bookingID, err := parseWithBookingID(fs, args)
+	if err != nil {
+		return err
+	}
+	result, err := c.Result(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
+
+// runCancel 予約のキャンセルを要求する
+func runCancel(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("cancel", flag.ContinueOnError)
+	var request workflows.CancellationRequest
+	fs.StringVar(&request.Reason, "reason", "", "キャンセル理由")
+	fs.StringVar(&request.RequestedBy, "requested-by", "bookingctl", "キャンセルの要求者")
+	bookingID, err := parseWithBookingID(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := c.Cancel(ctx, bookingID, request); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "キャンセルを要求しました: BookingID=%s（結果は status または result で確認してください）\n", bookingID)
+	return nil
+}
+
+// runModify 予約を変更し、変更後の予約結果を表示する
+func runModify(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("modify", flag.ContinueOnError)
+	file := fs.String("file", "", "ModificationRequestのJSONファイル（- で標準入力）")
+	reason := fs.String("reason", "", "変更理由（ファイルの値を上書きする）")
+	bookingID, err := parseWithBookingID(fs, args)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+	var request workflows.ModificationRequest
+	if err := readJSON(*file, &request); err != nil {
+		return err
+	}
+	if *reason != "" {
+		request.Reason = *reason
+	}
+	result, err := c.Modify(ctx, bookingID, request)
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
+
+// runList 最近の予約を一覧表示する
+func runList(ctx context.Context, c *bookingclient.Client, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", bookingclient.DefaultListLimit, "表示する件数")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	bookings, err := c.List(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	return printJSON(bookings)
+}
+
+// parseWithBookingID 予約IDとフラグを解析する（予約IDはフラグの前後どちらにも置ける）
+func parseWithBookingID(fs *flag.FlagSet, args []string) (string, error) {
+	var bookingID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		bookingID, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if bookingID == "" {
+		bookingID = fs.Arg(0)
+	}
+	if bookingID == "" {
+		return "", errors.New("booking-id is required")
+	}
+	return bookingID, nil
+}
+
+// setFlags 明示的に指定されたフラグ名
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// mergeBookingRequest ファイルのリクエストに、明示的に指定したフラグの値を上書きする
+// ディナー・駐車場はファイルの指定に項目ごとに上書きし、ファイルに指定がなければフラグの値だけで予約する
+// 実行方式はフラグの指定があれば呼び出し側で上書きする
+func mergeBookingRequest(base, flags workflows.BookingRequest, set map[string]bool) workflows.BookingRequest {
+	if set["booking-id"] {
+		base.BookingID = flags.BookingID
+	}
+	if set["user-id"] {
+		base.UserID = flags.UserID
+	}
+	if set["hotel-id"] {
+		base.Hotel.HotelID = flags.Hotel.HotelID
+	}
+	if set["check-in"] {
+		base.Hotel.CheckIn = flags.Hotel.CheckIn
+	}
+	if set["check-out"] {
+		base.Hotel.CheckOut = flags.Hotel.CheckOut
+	}
+	if set["room-type"] {
+		base.Hotel.RoomType = flags.Hotel.RoomType
+	}
+	if set["dinner-menu"] || set["dinner-time"] || set["guests"] || set["dinner-best-effort"] {
+		var dinner workflows.DinnerRequest
+		if base.Dinner != nil {
+			dinner = *base.Dinner
+		}
+		if set["dinner-menu"] {
+			dinner.MenuType = flags.Dinner.MenuType
+		}
+		if set["dinner-time"] {
+			dinner.DateTime = flags.Dinner.DateTime
+		}
+		if set["guests"] {
+			dinner.Guests = flags.Dinner.Guests
+		}
+		if set["dinner-best-effort"] {
+			dinner.BestEffort = flags.Dinner.BestEffort
+		}
+		base.Dinner = &dinner
+	}
+	if set["parking-space"] || set["parking-start"] || set["parking-end"] || set["parking-best-effort"] {
+		var parking workflows.ParkingRequest
+		if base.Parking != nil {
+			parking = *base.Parking
+		}
+		if set["parking-space"] {
+			parking.SpaceType = flags.Parking.SpaceType
+		}
+		if set["parking-start"] {
+			parking.StartTime = flags.Parking.StartTime
+		}
+		if set["parking-end"] {
+			parking.EndTime = flags.Parking.EndTime
+		}
+		if set["parking-best-effort"] {
+			parking.BestEffort = flags.Parking.BestEffort
+		}
+		base.Parking = &parking
+	}
+	return base
+}
+
+// readJSON JSONファイルを読み込む（- の場合は標準入力）
+func readJSON(path string, v interface{}) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+	return nil
+}
+
+// printJSON 結果をJSONで標準出力に表示する
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// joinPhases フェーズをカンマ区切りの文字列にする
+func joinPhases(phases []workflows.BookingPhase) string {
+	names := make([]string, len(phases))
+	for i, phase := range phases {
+		names[i] = string(phase)
+	}
+	return strings.Join(names, ",")
+}
+
+// splitPhases カンマ区切りの文字列をフェーズの一覧にする
+func splitPhases(s string) []workflows.BookingPhase {
+	var phases []workflows.BookingPhase
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			phases = append(phases, workflows.BookingPhase(name))
+		}
+	}
+	return phases
+}
+
+// timeFlag RFC3339形式で指定する日時のフラグ
+type timeFlag time.Time
+
+// String フラグの現在値
+func (t *timeFlag) String() string {
+	if t == nil || time.Time(*t).IsZero() {
+		return ""
+	}
+	return time.Time(*t).Format(time.RFC3339)
+}
+
+// Set RFC3339形式の文字列から変換
+func (t *timeFlag) Set(s string) error {
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("must be RFC3339 (e.g. 2026-11-01T15:00:00+09:00): %w", err)
+	}
+	*t = timeFlag(parsed)
+	return nil
+}
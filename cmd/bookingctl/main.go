@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
+
+	"temporal-hotel-sample/internal/bookingclient"
+	"temporal-hotel-sample/internal/config"
+)
+
+// command bookingctlのサブコマンド
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c *bookingclient.Client, args []string) error
+}
+
+var commands = []command{
+	{"start", "start [flags]                 予約Sagaを開始する（-file でJSONのBookingRequestを指定）", runStart},
+	{"status", "status <booking-id>           予約の現在の状況を問い合わせる", runStatus},
+	{"watch", "watch <booking-id> [flags]    フェーズが変わるたびに予約の状況を表示する", runWatch},
+	{"result", "result <booking-id>           予約Sagaの終了を待って結果を表示する", runResult},
+	{"cancel", "cancel <booking-id> [flags]   予約のキャンセルを要求する", runCancel},
+	{"modify", "modify <booking-id> [flags]   予約を変更する（-file でJSONのModificationRequestを指定）", runModify},
+	{"list", "list [flags]                  最近の予約を一覧表示する", runList},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run サブコマンドを実行し、終了コードを返す
+func run(args []string) int {
+	global := flag.NewFlagSet("bookingctl", flag.ExitOnError)
+	configFile := global.String("config", os.Getenv(config.EnvWorkerConfigFile), "Temporalへの接続設定を読み込むワーカー設定ファイル（YAML/JSON）")
+	address := global.String("address", "", "Temporalサーバーのアドレス（設定ファイルと環境変数 TEMPORAL_ADDRESS より優先）")
+	namespace := global.String("namespace", "", "Temporalのネームスペース（設定ファイルと環境変数 TEMPORAL_NAMESPACE より優先）")
+	global.Usage = func() { usage(global) }
+	_ = global.Parse(args)
+
+	if global.NArg() == 0 {
+		usage(global)
+		return 2
+	}
+	cmd, ok := findCommand(global.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", global.Arg(0))
+		usage(global)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// 接続先・TLS・タスクキューの設定はワーカーと共通で、フラグの指定を優先する
+	clientConfig, err := loadClientConfig(*configFile, *address, *namespace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid temporal config:", err)
+		return 1
+	}
+	options, err := clientConfig.Temporal.ClientOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid temporal config:", err)
+		return 1
+	}
+	// コマンドの出力に混ざらないよう、SDKのログは警告以上だけを表示する
+	options.Logger = log.NewStructuredLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	// サブコマンドのフラグの誤りはサーバーに接続する前に報告するため、最初の呼び出しで接続する
+	c, err := client.NewLazyClient(options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to create client:", err)
+		return 1
+	}
+	defer c.Close()
+
+	if err := cmd.run(ctx, bookingclient.New(c, clientConfig.TaskQueue), global.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// findCommand サブコマンドを名前で探す
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// usage 使い方を表示する
+func usage(global *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage: bookingctl [global flags] <command> [args]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(os.Stderr, "  "+cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nGlobal flags:")
+	global.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\n各コマンドのフラグは bookingctl <command> -h で確認できます")
+}
+
+// loadClientConfig ワーカー設定ファイルと環境変数の接続設定に、フラグで指定したアドレスとネームスペースを適用する
+func loadClientConfig(configFile, address, namespace string) (*config.ClientConfig, error) {
+	clientConfig, err := config.LoadClientConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if address != "" {
+		clientConfig.Temporal.Address = address
+	}
+	if namespace != "" {
+		clientConfig.Temporal.Namespace = namespace
+	}
+	if err := clientConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return clientConfig, nil
+}
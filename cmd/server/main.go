@@ -1,31 +1,98 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"time"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
 
 	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/admin"
+	"temporal-hotel-sample/internal/config"
+	"temporal-hotel-sample/internal/metrics"
 	"temporal-hotel-sample/internal/workflows"
 )
 
-const TaskQueue = "HOTEL_BOOKING_TASK_QUEUE"
-
 func main() {
-	// Temporalクライアントの作成
-	c, err := client.Dial(client.Options{})
+	configFile := flag.String("config", os.Getenv(config.EnvWorkerConfigFile), "ワーカー設定ファイル（YAML/JSON）")
+	printConfig := flag.Bool("print-config", false, "設定ファイルと環境変数を反映した設定を表示して終了する")
+	flag.Parse()
+
+	// ワーカー設定の読み込み（不正な設定の場合は起動しない）
+	cfg, err := config.LoadWorkerConfig(*configFile)
+	if err != nil {
+		log.Fatalln("Invalid worker config", err)
+	}
+	if *printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			log.Fatalln("Unable to print worker config", err)
+		}
+		fmt.Print(string(out))
+		return
+	}
+
+	// リトライポリシーの読み込み（不正なポリシーの場合は起動しない）
+	policies, err := config.LoadPolicyRegistry(cfg.Files.RetryPolicy)
+	if err != nil {
+		log.Fatalln("Invalid retry policy", err)
+	}
+	config.SetPolicies(policies)
+
+	// 予約ステップの構成の検証（依存関係が不正な場合は起動しない）
+	if err := workflows.ValidatePipelines(); err != nil {
+		log.Fatalln("Invalid booking pipeline", err)
+	}
+
+	// Temporalクライアントの作成（SDKと予約Sagaのメトリクスを/metricsで公開する）
+	clientOptions, err := cfg.ClientOptions()
+	if err != nil {
+		log.Fatalln("Invalid worker config", err)
+	}
+	registry := metrics.NewRegistry()
+	defer registry.Close()
+	clientOptions.MetricsHandler = registry.Handler()
+	c, err := client.Dial(clientOptions)
 	if err != nil {
 		log.Fatalln("Unable to create client", err)
 	}
 	defer c.Close()
 
-	// ワーカーの作成
-	w := worker.New(c, TaskQueue, worker.Options{})
+	// 手動対応待ち補償処理の記録先（指定がなければインメモリ）
+	if path := cfg.Files.StuckCompensation; path != "" {
+		activities.SetStuckCompensationSink(activities.NewFileStuckCompensationSink(path))
+	}
+
+	// 冪等性を保証する処理結果ストア（指定がなければインメモリ）
+	if path := cfg.Files.IdempotencyStore; path != "" {
+		store, err := activities.NewBoltIdempotencyStore(path, activities.DefaultIdempotencyTTL)
+		if err != nil {
+			log.Fatalln("Unable to open idempotency store", err)
+		}
+		defer store.Close()
+		// 処理結果と在庫の状態を同じファイルに保存し、再起動後も割り当て済みのリソースを保持する
+		if err := activities.SetPersistentStore(context.Background(), store); err != nil {
+			log.Fatalln("Unable to restore inventory", err)
+		}
+	}
+
+	// ワーカーの作成（致命的なエラーで停止した場合はreadyzを503にしてプロセスを終了する）
+	adminServer := admin.NewServer(c)
+	workerOptions := cfg.WorkerOptions()
+	workerOptions.OnFatalError = adminServer.Fail
+	w := worker.New(c, cfg.Worker.TaskQueue, workerOptions)
 
 	// ワークフローとアクティビティの登録
 	w.RegisterWorkflow(workflows.HotelBookingSaga)
-	
+	w.RegisterWorkflow(workflows.GroupBookingWorkflow)
+
 	// アクティビティの登録
 	w.RegisterActivity(activities.HotelRoomBookingActivity)
 	w.RegisterActivity(activities.CompensateHotelRoomActivity)
@@ -33,11 +100,33 @@ func main() {
 	w.RegisterActivity(activities.CompensateDinnerFoodActivity)
 	w.RegisterActivity(activities.ParkingBookingActivity)
 	w.RegisterActivity(activities.CompensateParkingActivity)
+	w.RegisterActivity(activities.RecordStuckCompensationActivity)
+	w.RegisterActivity(activities.UpdateStuckCompensationActivity)
+	w.RegisterActivity(activities.SendCheckInReminderActivity)
+
+	// 管理用HTTPサーバーの起動（healthz, readyz, drain, metrics）
+	adminServer.Handle("GET /metrics", registry.HTTPHandler())
+	if cfg.Worker.AdminAddress != "" {
+		httpServer := &http.Server{Addr: cfg.Worker.AdminAddress, Handler: adminServer, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			log.Printf("Admin server listening on %s", cfg.Worker.AdminAddress)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalln("Unable to start admin server", err)
+			}
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := httpServer.Shutdown(ctx); err != nil {
+				log.Println("Unable to shutdown admin server", err)
+			}
+		}()
+	}
 
-	log.Println("Starting hotel booking worker...")
-	err = w.Run(worker.InterruptCh())
+	log.Printf("Starting hotel booking worker (namespace: %s, task queue: %s)...", cfg.Temporal.Namespace, cfg.Worker.TaskQueue)
+	err = adminServer.Run(w, worker.InterruptCh())
 	if err != nil {
-		log.Fatalln("Unable to start worker", err)
+		log.Fatalln("Worker stopped with error", err)
 	}
 
 	log.Println("Worker stopped")
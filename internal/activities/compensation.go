@@ -1,7 +1,33 @@
 package activities
 
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+)
+
 // CompensationResult 補償処理結果
 type CompensationResult struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	Attempt int32  `json:"attempt,omitempty"` // 補償が完了した試行回数
+}
+
+// activityAttempt 現在のアクティビティの試行回数（アクティビティ外から呼ばれた場合は0）
+func activityAttempt(ctx context.Context) int32 {
+	if !activity.IsActivity(ctx) {
+		return 0
+	}
+	return activity.GetInfo(ctx).Attempt
+}
+
+// withAttempt 補償結果に現在の試行回数を記録する
+// キャッシュ済みの結果を書き換えないようにコピーを返す
+func withAttempt(ctx context.Context, result *CompensationResult) *CompensationResult {
+	if result == nil || !activity.IsActivity(ctx) {
+		return result
+	}
+	copied := *result
+	copied.Attempt = activityAttempt(ctx)
+	return &copied
 }
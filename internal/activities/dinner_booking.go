@@ -2,28 +2,65 @@ package activities
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	"temporal-hotel-sample/internal/inventory"
 )
 
+// MaxDinnerGuests 1件のディナー予約で受け付ける最大人数
+const MaxDinnerGuests = 10
+
 type (
 	// DinnerBookingRequest ディナー食材予約リクエスト
 	DinnerBookingRequest struct {
-		BookingID string `json:"booking_id"`
-		UserID    string `json:"user_id"`
-		MenuType  string `json:"menu_type"`
+		BookingID string    `json:"booking_id"`
+		UserID    string    `json:"user_id"`
+		MenuType  string    `json:"menu_type"`
+		DateTime  time.Time `json:"date_time"`
+		Guests    int       `json:"guests"`
+		Revision  int       `json:"revision,omitempty"` // 予約変更の版（初回の予約は0）
 	}
 	DinnerBookingResult struct {
-		Success    bool   `json:"success"`
-		ResourceID string `json:"resource_id"`
-		Message    string `json:"message"`
-		ErrorCode  string `json:"error_code"`
+		Success        bool                           `json:"success"`
+		ResourceID     string                         `json:"resource_id"` // 食材の引当ID
+		DateTime       time.Time                      `json:"date_time,omitempty"`
+		Guests         int                            `json:"guests,omitempty"`
+		Ingredients    []inventory.IngredientQuantity `json:"ingredients,omitempty"`      // 引き当てた食材
+		LedgerEntryIDs []string                       `json:"ledger_entry_ids,omitempty"` // 引当時の入出庫記録
+		Message        string                         `json:"message"`
+		ErrorCode      string                         `json:"error_code"`
+		Attempt        int32                          `json:"attempt,omitempty"` // 予約が完了した試行回数
 	}
 
 	DinnerActivity struct {
-		logger Logger
+		logger         Logger
+		store          IdempotencyStore
+		menus          inventory.MenuCatalog
+		ledger         *inventory.IngredientLedger
+		inventoryStore InventoryStore // nilの場合は食材台帳を保存しない
 	}
 )
 
+var (
+	// defaultMenuCatalog ワークフロー用アダプター関数が使用するメニューカタログ
+	defaultMenuCatalog = inventory.NewMenuCatalog(inventory.DefaultMenus())
+	// defaultIngredientLedger ワークフロー用アダプター関数が使用する食材台帳
+	defaultIngredientLedger = inventory.NewIngredientLedger(inventory.DefaultStock())
+)
+
+func (r *DinnerBookingResult) GetResourceID() string { return r.ResourceID }
+func (r *DinnerBookingResult) GetAttempt() int32     { return r.Attempt }
+
+// SetDinnerInventory ワークフロー用アダプター関数が使用するメニューカタログと食材台帳を設定
+// ワーカー起動時に呼び出すこと
+func SetDinnerInventory(menus inventory.MenuCatalog, ledger *inventory.IngredientLedger) {
+	defaultMenuCatalog = menus
+	defaultIngredientLedger = ledger
+}
+
 // Validate リクエストの妥当性チェック
 func (dr *DinnerBookingRequest) Validate() error {
 	if strings.TrimSpace(dr.BookingID) == "" {
@@ -35,23 +72,44 @@ func (dr *DinnerBookingRequest) Validate() error {
 	if strings.TrimSpace(dr.MenuType) == "" {
 		return NewBusinessError("MenuType is required", "INVALID_MENU_TYPE")
 	}
+	if dr.DateTime.IsZero() {
+		return NewBusinessError("DateTime is required", "INVALID_DATE_TIME")
+	}
+	if dr.Guests <= 0 {
+		return NewBusinessError("Guests must be greater than 0", "INVALID_GUESTS")
+	}
 	return nil
 }
 
-// dinnerCache 冪等性のための簡単なインメモリキャッシュ
-var dinnerCache = make(map[string]*DinnerBookingResult)
-
-func NewDinnerActivity(logger Logger) *DinnerActivity {
+func NewDinnerActivity(logger Logger, store IdempotencyStore, menus inventory.MenuCatalog, ledger *inventory.IngredientLedger) *DinnerActivity {
 	return &DinnerActivity{
 		logger: logger,
+		store:  store,
+		menus:  menus,
+		ledger: ledger,
 	}
 }
 
+// WithInventoryStore 食材台帳の変更を保存する先を設定
+func (a *DinnerActivity) WithInventoryStore(store InventoryStore) *DinnerActivity {
+	a.inventoryStore = store
+	return a
+}
+
+// saveInventory 変更後の食材台帳を保存
+func (a *DinnerActivity) saveInventory(ctx context.Context) error {
+	return saveInventory(ctx, a.inventoryStore, InventoryDinner, func() interface{} { return a.ledger.Snapshot() })
+}
+
 // DinnerFoodBookingActivity ワークフロー用アダプター関数
 func DinnerFoodBookingActivity(ctx context.Context, req DinnerBookingRequest) (*DinnerBookingResult, error) {
 	logger := NewTemporalLogger(ctx)
-	activity := NewDinnerActivity(logger)
-	return activity.BookDinner(ctx, req)
+	activity := NewDinnerActivity(logger, defaultIdempotencyStore, defaultMenuCatalog, defaultIngredientLedger).WithInventoryStore(defaultInventoryStore)
+	result, err := activity.BookDinner(ctx, req)
+	if result != nil {
+		result.Attempt = activityAttempt(ctx)
+	}
+	return result, toActivityError(ctx, err)
 }
 
 func (a *DinnerActivity) BookDinner(ctx context.Context, req DinnerBookingRequest) (*DinnerBookingResult, error) {
@@ -64,49 +122,73 @@ func (a *DinnerActivity) BookDinner(ctx context.Context, req DinnerBookingReques
 	}
 
 	// 冪等性チェック（既に処理済みかどうか）
-	if cached, exists := dinnerCache[req.BookingID]; exists {
+	reservationKey := ReservationKey(req.BookingID, req.Revision)
+	key := IdempotencyKey(reservationKey, OperationBookDinner)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached DinnerBookingResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
 		a.logger.Info("既に処理済みの予約リクエスト", "BookingID", req.BookingID)
-		return cached, nil
+		return &cached, nil
 	}
 
-	// 特定のBookingIDに基づくシミュレーション
-	switch req.BookingID {
-	case "booking-system-error":
-		// サーバーエラー（外部システム障害）をシミュレート
-		err := NewServerError("外部システムで障害が発生しました", "SYSTEM_ERROR")
-		a.logger.Error("サーバーエラーが発生", "Error", err)
+	// 1件の予約で用意できる人数を超える場合は受け付けない
+	if req.Guests > MaxDinnerGuests {
+		err := NewBusinessError(fmt.Sprintf("ディナーは%d名まで予約できます", MaxDinnerGuests), "TOO_MANY_GUESTS")
+		a.logger.Error("ビジネスエラーが発生", "Error", err)
 		return nil, err
+	}
 
-	case "booking-out-of-stock":
-		// ビジネスエラー（食材在庫不足）をシミュレート
-		err := NewBusinessError("指定されたメニューの食材が在庫不足です", "OUT_OF_STOCK")
+	// メニューの食材構成から人数分の食材を在庫台帳に引き当て
+	menu, err := a.menus.Lookup(req.MenuType)
+	if err != nil {
+		err := NewBusinessError("指定されたメニューが存在しません", "MENU_NOT_FOUND")
 		a.logger.Error("ビジネスエラーが発生", "Error", err)
 		return nil, err
+	}
+	reservation, err := a.ledger.ReserveFor(reservationKey, req.BookingID, menu, req.Guests)
+	if err != nil {
+		err := dinnerInventoryError(err)
+		a.logger.Error("食材の引当に失敗", "Error", err)
+		return nil, err
+	}
+	if err := a.saveInventory(ctx); err != nil {
+		a.logger.Error("食材台帳の保存に失敗", "Error", err)
+		return nil, err
+	}
+
+	result := &DinnerBookingResult{
+		Success:        true,
+		ResourceID:     reservation.ID,
+		DateTime:       req.DateTime,
+		Guests:         req.Guests,
+		Ingredients:    reservation.Items,
+		LedgerEntryIDs: reservation.EntryIDs,
+		Message:        "ディナー食材予約が完了しました",
+	}
+
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
+		return nil, err
+	}
+
+	a.logger.Info("ディナー食材予約が完了", "BookingID", req.BookingID, "ResourceID", result.ResourceID, "DateTime", req.DateTime, "Guests", req.Guests)
+	return result, nil
+}
 
-	case "booking-duplicate-dinner":
-		// 冪等性テストのための特別処理
-		result := &DinnerBookingResult{
-			Success:    true,
-			ResourceID: "food-duplicate",
-			Message:    "既に予約済みです",
-		}
-		// キャッシュに保存
-		dinnerCache[req.BookingID] = result
-		a.logger.Info("重複リクエストの処理完了", "BookingID", req.BookingID)
-		return result, nil
-
-	default:
-		// 正常な予約処理
-		result := &DinnerBookingResult{
-			Success:    true,
-			ResourceID: "food-123", // 実際のシステムでは動的に生成
-			Message:    "ディナー食材予約が完了しました",
-		}
-
-		// キャッシュに保存（冪等性保証）
-		dinnerCache[req.BookingID] = result
-
-		a.logger.Info("ディナー食材予約が完了", "BookingID", req.BookingID, "ResourceID", result.ResourceID)
-		return result, nil
+// dinnerInventoryError 食材台帳のエラーをアクティビティのエラーに変換
+// 在庫不足の場合は不足している食材をメッセージに列挙する
+func dinnerInventoryError(err error) error {
+	var outOfStock *inventory.OutOfStockError
+	if errors.As(err, &outOfStock) {
+		return NewOutOfStockError(fmt.Sprintf("指定されたメニューの食材が在庫不足です: %s", FormatShortages(outOfStock.Shortages)), outOfStock.Shortages)
 	}
+	return NewServerError(fmt.Sprintf("食材在庫の更新に失敗しました: %s", err.Error()), "INVENTORY_ERROR")
 }
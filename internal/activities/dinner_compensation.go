@@ -2,31 +2,39 @@ package activities
 
 import "context"
 
-// dinnerCompensationCache ディナー食材補償処理のキャッシュ
-var dinnerCompensationCache = make(map[string]*CompensationResult)
-
 // CompensateDinnerFoodActivity ワークフロー用アダプター関数
 func CompensateDinnerFoodActivity(ctx context.Context, bookingID string, resourceID string) (*CompensationResult, error) {
 	logger := NewTemporalLogger(ctx)
-	activity := NewDinnerActivity(logger)
-	return activity.CompensateDinner(ctx, bookingID, resourceID)
+	activity := NewDinnerActivity(logger, defaultIdempotencyStore, defaultMenuCatalog, defaultIngredientLedger).WithInventoryStore(defaultInventoryStore)
+	result, err := activity.CompensateDinner(ctx, bookingID, resourceID)
+	return withAttempt(ctx, result), toActivityError(ctx, err)
 }
 
 func (a *DinnerActivity) CompensateDinner(ctx context.Context, bookingID string, resourceID string) (*CompensationResult, error) {
 	a.logger.Info("ディナー食材補償処理を開始", "BookingID", bookingID, "ResourceID", resourceID)
 
 	// 冪等性チェック（既に補償済みかどうか）
-	if cached, exists := dinnerCompensationCache[bookingID]; exists {
-		a.logger.Info("既に補償済みの予約", "BookingID", bookingID)
-		return cached, nil
+	key := IdempotencyKey(bookingID, OperationCompensateDinner, resourceID)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached CompensationResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
+		a.logger.Info("既に補償済みの予約", "BookingID", bookingID, "ResourceID", resourceID)
+		return &cached, nil
 	}
 
-	// 実際のシステムでは以下のような処理を行う：
-	// 1. 食材仕入れシステムAPIを呼び出して注文をキャンセル
-	// 2. 仕入れ代金の返金処理
-	// 3. 在庫の調整処理
-
-	// シミュレーション: ログ出力のみ
+	// 引き当てた食材をそのまま在庫に戻す（戻し入れ済みの場合は何もしない）
+	a.ledger.Restock(bookingID, resourceID)
+	if err := a.saveInventory(ctx); err != nil {
+		a.logger.Error("食材台帳の保存に失敗", "Error", err)
+		return nil, err
+	}
 	a.logger.Info("ディナー食材注文をキャンセルしました", "BookingID", bookingID, "ResourceID", resourceID)
 
 	result := &CompensationResult{
@@ -34,8 +42,11 @@ func (a *DinnerActivity) CompensateDinner(ctx context.Context, bookingID string,
 		Message: "ディナー食材予約の補償処理が完了しました",
 	}
 
-	// キャッシュに保存（冪等性保証）
-	dinnerCompensationCache[bookingID] = result
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
+		return nil, err
+	}
 
 	a.logger.Info("ディナー食材補償処理が完了", "BookingID", bookingID, "ResourceID", resourceID)
 	return result, nil
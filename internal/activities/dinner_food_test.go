@@ -3,21 +3,28 @@ package activities
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"temporal-hotel-sample/internal/inventory"
 )
 
+var testDinnerTime = time.Date(2026, 11, 1, 19, 0, 0, 0, time.UTC)
+
 // テストケースについて
 // 正常系:
-//   - 正常なリクエストがされた場合、予約処理が完了する
+//   - 正常なリクエストがされた場合、人数分の食材が引き当てられる
 //
 // 異常系:
 //   - BookingIDが空の時、Businessエラーが返却される
-//   - BookingIDがbooking-system-error（サーバエラー）の時、Serverエラーが返却される
-//   - BookingIDがbooking-out-of-stockの時、Businessエラーが返却される
-//   - BookingIDがbooking-duplicateの時、冪等性が保証される
+//   - DateTimeが空の時、Businessエラーが返却される
+//   - Guestsが0の時、Businessエラーが返却される
+//   - Guestsが上限を超える時、Businessエラーが返却される
+//   - 存在しないメニューの時、Businessエラーが返却される
+//   - 食材の在庫が不足する時、不足する食材を列挙したOUT_OF_STOCKのBusinessエラーが返却される
 func Test_DinnerFoodBookingActivity(t *testing.T) {
 	testcases := map[string]struct {
+		stock          map[string]int
 		request        DinnerBookingRequest
 		expectedResult *DinnerBookingResult
 		expectedErr    error
@@ -27,11 +34,23 @@ func Test_DinnerFoodBookingActivity(t *testing.T) {
 				BookingID: "booking1",
 				UserID:    "user1",
 				MenuType:  "course",
+				DateTime:  testDinnerTime,
+				Guests:    2,
 			},
 			expectedResult: &DinnerBookingResult{
 				Success:    true,
-				ResourceID: "food-123",
-				Message:    "ディナー食材予約が完了しました",
+				ResourceID: "food-000001",
+				DateTime:   testDinnerTime,
+				Guests:     2,
+				Ingredients: []inventory.IngredientQuantity{
+					{Ingredient: "wagyu", Quantity: 2},
+					{Ingredient: "sea_bream", Quantity: 2},
+					{Ingredient: "vegetables", Quantity: 6},
+					{Ingredient: "rice", Quantity: 2},
+					{Ingredient: "dessert", Quantity: 2},
+				},
+				LedgerEntryIDs: []string{"ledger-000001", "ledger-000002", "ledger-000003", "ledger-000004", "ledger-000005"},
+				Message:        "ディナー食材予約が完了しました",
 			},
 			expectedErr: nil,
 		},
@@ -40,6 +59,8 @@ func Test_DinnerFoodBookingActivity(t *testing.T) {
 				BookingID: "",
 				UserID:    "user1",
 				MenuType:  "course",
+				DateTime:  testDinnerTime,
+				Guests:    2,
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
@@ -47,42 +68,78 @@ func Test_DinnerFoodBookingActivity(t *testing.T) {
 				Code:    "INVALID_BOOKING_ID",
 			},
 		},
-		"異常系: booking-system-errorの時、Serverエラーが返却される": {
+		"異常系: DateTimeが空の時、Businessエラーが返却される": {
 			request: DinnerBookingRequest{
-				BookingID: "booking-system-error",
+				BookingID: "booking1",
 				UserID:    "user1",
 				MenuType:  "course",
+				Guests:    2,
 			},
 			expectedResult: nil,
-			expectedErr: &ServerError{
-				Message: "外部システムで障害が発生しました",
-				Code:    "SYSTEM_ERROR",
+			expectedErr: &BusinessError{
+				Message: "DateTime is required",
+				Code:    "INVALID_DATE_TIME",
 			},
 		},
-		"異常系: booking-out-of-stockの時、Businessエラーが返却される": {
+		"異常系: Guestsが0の時、Businessエラーが返却される": {
 			request: DinnerBookingRequest{
-				BookingID: "booking-out-of-stock",
+				BookingID: "booking1",
 				UserID:    "user1",
 				MenuType:  "course",
+				DateTime:  testDinnerTime,
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
-				Message: "指定されたメニューの食材が在庫不足です",
-				Code:    "OUT_OF_STOCK",
+				Message: "Guests must be greater than 0",
+				Code:    "INVALID_GUESTS",
 			},
 		},
-		"異常系: booking-duplicateの時、冪等性が保証される": {
+		"異常系: Guestsが上限を超える時、Businessエラーが返却される": {
 			request: DinnerBookingRequest{
-				BookingID: "booking-duplicate-dinner",
+				BookingID: "booking1",
 				UserID:    "user1",
 				MenuType:  "course",
+				DateTime:  testDinnerTime,
+				Guests:    MaxDinnerGuests + 1,
 			},
-			expectedResult: &DinnerBookingResult{
-				Success:    true,
-				ResourceID: "food-duplicate",
-				Message:    "既に予約済みです",
+			expectedResult: nil,
+			expectedErr: &BusinessError{
+				Message: "ディナーは10名まで予約できます",
+				Code:    "TOO_MANY_GUESTS",
+			},
+		},
+		"異常系: 存在しないメニューの時、Businessエラーが返却される": {
+			request: DinnerBookingRequest{
+				BookingID: "booking1",
+				UserID:    "user1",
+				MenuType:  "unknown",
+				DateTime:  testDinnerTime,
+				Guests:    2,
+			},
+			expectedResult: nil,
+			expectedErr: &BusinessError{
+				Message: "指定されたメニューが存在しません",
+				Code:    "MENU_NOT_FOUND",
+			},
+		},
+		"異常系: 食材の在庫が不足する時、不足する食材を列挙したOUT_OF_STOCKのBusinessエラーが返却される": {
+			stock: map[string]int{"wagyu": 1, "vegetables": 100, "rice": 100, "dessert": 100},
+			request: DinnerBookingRequest{
+				BookingID: "booking1",
+				UserID:    "user1",
+				MenuType:  "course",
+				DateTime:  testDinnerTime,
+				Guests:    2,
+			},
+			expectedResult: nil,
+			expectedErr: &BusinessError{
+				Message: "指定されたメニューの食材が在庫不足です: sea_bream（必要 2 / 在庫 0）, wagyu（必要 2 / 在庫 1）",
+				Code:    "OUT_OF_STOCK",
+				Shortages: []inventory.Shortage{
+					{Ingredient: "sea_bream", Required: 2, Available: 0},
+					{Ingredient: "wagyu", Required: 2, Available: 1},
+				},
 			},
-			expectedErr: nil,
 		},
 	}
 
@@ -91,7 +148,11 @@ func Test_DinnerFoodBookingActivity(t *testing.T) {
 			// given
 			ctx := context.Background()
 			mockLogger := &MockLogger{}
-			sut := NewDinnerActivity(mockLogger)
+			stock := tc.stock
+			if stock == nil {
+				stock = inventory.DefaultStock()
+			}
+			sut := NewDinnerActivity(mockLogger, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), inventory.NewMenuCatalog(inventory.DefaultMenus()), inventory.NewIngredientLedger(stock))
 
 			// when
 			actualResult, actualErr := sut.BookDinner(ctx, tc.request)
@@ -102,3 +163,27 @@ func Test_DinnerFoodBookingActivity(t *testing.T) {
 		})
 	}
 }
+
+func TestDinnerActivity_CompensateDinner(t *testing.T) {
+	// given: 食材を引き当て済み
+	ctx := context.Background()
+	ledger := inventory.NewIngredientLedger(inventory.DefaultStock())
+	sut := NewDinnerActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), inventory.NewMenuCatalog(inventory.DefaultMenus()), ledger)
+	booked, err := sut.BookDinner(ctx, DinnerBookingRequest{BookingID: "booking1", UserID: "user1", MenuType: "course", DateTime: testDinnerTime, Guests: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, inventory.DefaultStock()["wagyu"]-3, ledger.Stock("wagyu"))
+
+	// when: 補償処理を2回実行する
+	first, err := sut.CompensateDinner(ctx, "booking1", booked.ResourceID)
+	assert.NoError(t, err)
+	second, err := sut.CompensateDinner(ctx, "booking1", booked.ResourceID)
+	assert.NoError(t, err)
+
+	// then: 引き当てた分だけ在庫が1回だけ戻される
+	assert.True(t, first.Success)
+	assert.Equal(t, first, second)
+	for ingredient, quantity := range inventory.DefaultStock() {
+		assert.Equal(t, quantity, ledger.Stock(ingredient), ingredient)
+	}
+	assert.Len(t, ledger.Entries(), 2*len(booked.Ingredients))
+}
@@ -1,9 +1,33 @@
 package activities
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+	"temporal-hotel-sample/internal/inventory"
+)
+
+// ApplicationErrorのエラー種別（リトライポリシーのNonRetryableErrorTypesで参照される）
+const (
+	BusinessErrorType = "BusinessError"
+	ServerErrorType   = "ServerError"
+	UnknownErrorType  = "UnknownError"
+)
+
+// エラーコード
+const (
+	CodeRoomFull    = "ROOM_FULL"
+	CodeOutOfStock  = "OUT_OF_STOCK"
+	CodeParkingFull = "PARKING_FULL"
+)
+
 // BusinessError ビジネスロジックエラー（リトライ不可）
 type BusinessError struct {
-	Message string
-	Code    string
+	Message   string
+	Code      string
+	Shortages []inventory.Shortage // 在庫不足の食材（OUT_OF_STOCKの場合のみ）
 }
 
 func (e *BusinessError) Error() string {
@@ -18,6 +42,24 @@ func NewBusinessError(message, code string) *BusinessError {
 	}
 }
 
+// NewOutOfStockError 在庫不足の食材一覧を持つOUT_OF_STOCKのビジネスエラーを作成
+func NewOutOfStockError(message string, shortages []inventory.Shortage) *BusinessError {
+	return &BusinessError{
+		Message:   message,
+		Code:      CodeOutOfStock,
+		Shortages: shortages,
+	}
+}
+
+// FormatShortages 在庫不足の食材一覧を「食材（必要 n / 在庫 m）」の形式で列挙する
+func FormatShortages(shortages []inventory.Shortage) string {
+	missing := make([]string, 0, len(shortages))
+	for _, s := range shortages {
+		missing = append(missing, fmt.Sprintf("%s（必要 %d / 在庫 %d）", s.Ingredient, s.Required, s.Available))
+	}
+	return strings.Join(missing, ", ")
+}
+
 // ServerError サーバーエラー（リトライ可能）
 type ServerError struct {
 	Message string
@@ -53,3 +95,86 @@ func NewUnknownError(message, code string) *UnknownError {
 		Code:    code,
 	}
 }
+
+// ToApplicationError アクティビティのエラーをTemporalのApplicationErrorに変換
+// エラーコード（在庫不足の場合は続けて不足する食材一覧）はdetailsとして保持され、ビジネスエラーはリトライ不可となる
+// ワークフロー用アダプター関数からはtoActivityErrorを通して使用する
+func ToApplicationError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var businessErr *BusinessError
+	var serverErr *ServerError
+	var unknownErr *UnknownError
+	switch {
+	case errors.As(err, &businessErr):
+		details := []interface{}{businessErr.Code}
+		if len(businessErr.Shortages) > 0 {
+			details = append(details, businessErr.Shortages)
+		}
+		return temporal.NewApplicationErrorWithOptions(businessErr.Message, BusinessErrorType, temporal.ApplicationErrorOptions{
+			NonRetryable: true,
+			Details:      details,
+		})
+	case errors.As(err, &serverErr):
+		return temporal.NewApplicationErrorWithOptions(serverErr.Message, ServerErrorType, temporal.ApplicationErrorOptions{
+			Details: []interface{}{serverErr.Code},
+		})
+	case errors.As(err, &unknownErr):
+		return temporal.NewApplicationErrorWithOptions(unknownErr.Message, UnknownErrorType, temporal.ApplicationErrorOptions{
+			Details: []interface{}{unknownErr.Code},
+		})
+	default:
+		return err
+	}
+}
+
+// FromActivityError ワークフロー側で受け取ったアクティビティのエラーを型付きのエラーに復元
+// BusinessError・ServerError・UnknownErrorのいずれでもない場合は元のエラーをそのまま返す
+func FromActivityError(err error) error {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+
+	var code string
+	if appErr.HasDetails() {
+		_ = appErr.Details(&code)
+	}
+
+	switch appErr.Type() {
+	case BusinessErrorType:
+		businessErr := NewBusinessError(appErr.Message(), code)
+		if code == CodeOutOfStock {
+			_ = appErr.Details(&code, &businessErr.Shortages)
+		}
+		return businessErr
+	case ServerErrorType:
+		return NewServerError(appErr.Message(), code)
+	case UnknownErrorType:
+		return NewUnknownError(appErr.Message(), code)
+	default:
+		return err
+	}
+}
+
+// ErrorCode アクティビティのエラーからエラーコードを取得（取得できない場合は空文字）
+func ErrorCode(err error) string {
+	switch e := FromActivityError(err).(type) {
+	case *BusinessError:
+		return e.Code
+	case *ServerError:
+		return e.Code
+	case *UnknownError:
+		return e.Code
+	default:
+		return ""
+	}
+}
+
+// IsBusinessError アクティビティのエラーがビジネスエラーかどうか
+func IsBusinessError(err error) bool {
+	_, ok := FromActivityError(err).(*BusinessError)
+	return ok
+}
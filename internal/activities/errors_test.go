@@ -0,0 +1,90 @@
+package activities
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/sdk/temporal"
+	"temporal-hotel-sample/internal/inventory"
+)
+
+// テストケースについて
+// 正常系:
+//   - BusinessErrorがリトライ不可のApplicationErrorに変換され、型付きのエラーに復元される
+//   - 在庫不足のBusinessErrorは不足する食材一覧を含めて復元される
+//   - ServerErrorがリトライ可能なApplicationErrorに変換され、型付きのエラーに復元される
+//   - UnknownErrorがリトライ可能なApplicationErrorに変換され、型付きのエラーに復元される
+//   - 分類不可のエラーはそのまま返却される
+func TestToApplicationError_FromActivityError(t *testing.T) {
+	plainErr := errors.New("予期しないエラー")
+
+	testcases := map[string]struct {
+		err                  error
+		expectedType         string
+		expectedNonRetryable bool
+		expectedRestored     error
+		expectedCode         string
+	}{
+		"正常系: BusinessErrorがリトライ不可のApplicationErrorに変換される": {
+			err:                  NewBusinessError("指定されたホテルは満室です", CodeRoomFull),
+			expectedType:         BusinessErrorType,
+			expectedNonRetryable: true,
+			expectedRestored:     &BusinessError{Message: "指定されたホテルは満室です", Code: CodeRoomFull},
+			expectedCode:         CodeRoomFull,
+		},
+		"正常系: 在庫不足のBusinessErrorは不足する食材一覧を含めて復元される": {
+			err:                  NewOutOfStockError("指定されたメニューの食材が在庫不足です", []inventory.Shortage{{Ingredient: "wagyu", Required: 2, Available: 1}}),
+			expectedType:         BusinessErrorType,
+			expectedNonRetryable: true,
+			expectedRestored: &BusinessError{
+				Message:   "指定されたメニューの食材が在庫不足です",
+				Code:      CodeOutOfStock,
+				Shortages: []inventory.Shortage{{Ingredient: "wagyu", Required: 2, Available: 1}},
+			},
+			expectedCode: CodeOutOfStock,
+		},
+		"正常系: ServerErrorがリトライ可能なApplicationErrorに変換される": {
+			err:                  NewServerError("ネットワークエラーが発生しました", "NETWORK_ERROR"),
+			expectedType:         ServerErrorType,
+			expectedNonRetryable: false,
+			expectedRestored:     &ServerError{Message: "ネットワークエラーが発生しました", Code: "NETWORK_ERROR"},
+			expectedCode:         "NETWORK_ERROR",
+		},
+		"正常系: UnknownErrorがリトライ可能なApplicationErrorに変換される": {
+			err:                  NewUnknownError("分類できないエラー", "UNKNOWN"),
+			expectedType:         UnknownErrorType,
+			expectedNonRetryable: false,
+			expectedRestored:     &UnknownError{Message: "分類できないエラー", Code: "UNKNOWN"},
+			expectedCode:         "UNKNOWN",
+		},
+		"正常系: 分類不可のエラーはそのまま返却される": {
+			err:              plainErr,
+			expectedRestored: plainErr,
+			expectedCode:     "",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given - テストケースで設定済み
+
+			// when
+			converted := ToApplicationError(tc.err)
+			restored := FromActivityError(converted)
+
+			// then
+			var appErr *temporal.ApplicationError
+			if tc.expectedType != "" {
+				if assert.True(t, errors.As(converted, &appErr)) {
+					assert.Equal(t, tc.expectedType, appErr.Type())
+					assert.Equal(t, tc.expectedNonRetryable, appErr.NonRetryable())
+				}
+			} else {
+				assert.False(t, errors.As(converted, &appErr))
+			}
+			assert.Equal(t, tc.expectedRestored, restored)
+			assert.Equal(t, tc.expectedCode, ErrorCode(converted))
+		})
+	}
+}
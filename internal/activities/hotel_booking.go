@@ -2,26 +2,54 @@ package activities
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	"temporal-hotel-sample/internal/inventory"
 )
 
 // HotelBookingRequest ホテル予約リクエスト
 type HotelBookingRequest struct {
-	BookingID string `json:"booking_id"`
-	UserID    string `json:"user_id"`
-	HotelID   string `json:"hotel_id"`
+	BookingID string    `json:"booking_id"`
+	UserID    string    `json:"user_id"`
+	HotelID   string    `json:"hotel_id"`
+	CheckIn   time.Time `json:"check_in"`
+	CheckOut  time.Time `json:"check_out"`
+	RoomType  string    `json:"room_type,omitempty"` // 未指定の場合はstandard
+	Revision  int       `json:"revision,omitempty"`  // 予約変更の版（初回の予約は0）
 }
 
 // HotelBookingResult ホテル予約結果
 type HotelBookingResult struct {
 	Success    bool   `json:"success"`
 	ResourceID string `json:"resource_id"`
+	RoomType   string `json:"room_type,omitempty"`
+	RoomNumber string `json:"room_number,omitempty"`
+	Nights     int    `json:"nights,omitempty"`
 	Message    string `json:"message"`
 	ErrorCode  string `json:"error_code"`
+	Attempt    int32  `json:"attempt,omitempty"` // 予約が完了した試行回数
 }
 
+func (r *HotelBookingResult) GetResourceID() string { return r.ResourceID }
+func (r *HotelBookingResult) GetAttempt() int32     { return r.Attempt }
+
 type HotelActivity struct {
-	logger Logger
+	logger         Logger
+	store          IdempotencyStore
+	inventory      *inventory.HotelInventory
+	inventoryStore InventoryStore // nilの場合は客室在庫を保存しない
+}
+
+// defaultHotelInventory ワークフロー用アダプター関数が使用する客室在庫
+var defaultHotelInventory = inventory.NewHotelInventory(inventory.DefaultHotels())
+
+// SetHotelInventory ワークフロー用アダプター関数が使用する客室在庫を設定
+// ワーカー起動時に呼び出すこと
+func SetHotelInventory(hotels *inventory.HotelInventory) {
+	defaultHotelInventory = hotels
 }
 
 // Validate リクエストの妥当性チェック
@@ -35,18 +63,34 @@ func (hr *HotelBookingRequest) Validate() error {
 	if strings.TrimSpace(hr.HotelID) == "" {
 		return NewBusinessError("HotelID is required", "INVALID_HOTEL_ID")
 	}
+	if hr.CheckIn.IsZero() {
+		return NewBusinessError("CheckIn is required", "INVALID_CHECK_IN")
+	}
+	if !hr.CheckOut.After(hr.CheckIn) {
+		return NewBusinessError("CheckOut must be after CheckIn", "INVALID_CHECK_OUT")
+	}
 	return nil
 }
 
-// bookingCache 冪等性のための簡単なインメモリキャッシュ
-var bookingCache = make(map[string]*HotelBookingResult)
-
-func NewHotelActivity(logger Logger) *HotelActivity {
+func NewHotelActivity(logger Logger, store IdempotencyStore, hotels *inventory.HotelInventory) *HotelActivity {
 	return &HotelActivity{
-		logger: logger,
+		logger:    logger,
+		store:     store,
+		inventory: hotels,
 	}
 }
 
+// WithInventoryStore 客室在庫の変更を保存する先を設定
+func (a *HotelActivity) WithInventoryStore(store InventoryStore) *HotelActivity {
+	a.inventoryStore = store
+	return a
+}
+
+// saveInventory 変更後の客室在庫を保存
+func (a *HotelActivity) saveInventory(ctx context.Context) error {
+	return saveInventory(ctx, a.inventoryStore, InventoryHotel, func() interface{} { return a.inventory.Allocations() })
+}
+
 // BookHotel ホテルルーム予約アクティビティ
 func (a *HotelActivity) BookHotel(ctx context.Context, req HotelBookingRequest) (*HotelBookingResult, error) {
 	a.logger.Info("ホテルルーム予約アクティビティを開始", "BookingID", req.BookingID)
@@ -58,56 +102,83 @@ func (a *HotelActivity) BookHotel(ctx context.Context, req HotelBookingRequest)
 	}
 
 	// 冪等性チェック（既に処理済みかどうか）
-	if cached, exists := bookingCache[req.BookingID]; exists {
+	reservationKey := ReservationKey(req.BookingID, req.Revision)
+	key := IdempotencyKey(reservationKey, OperationBookHotel)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached HotelBookingResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
 		a.logger.Info("既に処理済みの予約リクエスト", "BookingID", req.BookingID)
-		return cached, nil
+		return &cached, nil
 	}
 
-	// 特定のBookingIDに基づくシミュレーション
-	switch req.BookingID {
-	case "booking-network-error":
-		// サーバーエラー（ネットワークエラー）をシミュレート
-		err := NewServerError("ネットワークエラーが発生しました", "NETWORK_ERROR")
-		a.logger.Error("サーバーエラーが発生", "Error", err)
+	// 客室在庫から宿泊期間を通して空いている客室を割り当て
+	allocation, err := a.inventory.Reserve(inventory.Stay{
+		BookingID: reservationKey,
+		Owner:     req.BookingID,
+		HotelID:   req.HotelID,
+		RoomType:  req.RoomType,
+		CheckIn:   req.CheckIn,
+		CheckOut:  req.CheckOut,
+	})
+	if err != nil {
+		err := hotelInventoryError(err)
+		a.logger.Error("客室の割り当てに失敗", "Error", err)
 		return nil, err
+	}
+	if err := a.saveInventory(ctx); err != nil {
+		a.logger.Error("客室在庫の保存に失敗", "Error", err)
+		return nil, err
+	}
 
-	case "booking-full":
-		// ビジネスエラー（満室）をシミュレート
-		err := NewBusinessError("指定されたホテルは満室です", "HOTEL_FULL")
-		a.logger.Error("ビジネスエラーが発生", "Error", err)
+	result := &HotelBookingResult{
+		Success:    true,
+		ResourceID: allocation.ID,
+		RoomType:   allocation.RoomType,
+		RoomNumber: allocation.RoomNumber,
+		Nights:     len(allocation.Nights),
+		Message:    "ホテルルーム予約が完了しました",
+	}
+
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
 		return nil, err
+	}
 
-	case "booking-duplicate":
-		// 冪等性テストのための特別処理
-		result := &HotelBookingResult{
-			Success:    true,
-			ResourceID: "room-duplicate",
-			Message:    "既に予約済みです",
-		}
-		// キャッシュに保存
-		bookingCache[req.BookingID] = result
-		a.logger.Info("重複リクエストの処理完了", "BookingID", req.BookingID)
-		return result, nil
+	a.logger.Info("ホテルルーム予約が完了", "BookingID", req.BookingID, "ResourceID", result.ResourceID)
+	return result, nil
+}
 
+// hotelInventoryError 客室在庫のエラーをアクティビティのエラーに変換
+func hotelInventoryError(err error) error {
+	switch {
+	case errors.Is(err, inventory.ErrRoomFull):
+		return NewBusinessError("指定されたホテルは満室です", CodeRoomFull)
+	case errors.Is(err, inventory.ErrHotelNotFound):
+		return NewBusinessError("指定されたホテルが存在しません", "HOTEL_NOT_FOUND")
+	case errors.Is(err, inventory.ErrRoomTypeNotFound):
+		return NewBusinessError("指定された客室タイプが存在しません", "ROOM_TYPE_NOT_FOUND")
+	case errors.Is(err, inventory.ErrInvalidStay):
+		return NewBusinessError(fmt.Sprintf("宿泊期間が不正です: %s", err.Error()), "INVALID_STAY")
 	default:
-		// 正常な予約処理
-		result := &HotelBookingResult{
-			Success:    true,
-			ResourceID: "room-123", // 実際のシステムでは動的に生成
-			Message:    "ホテルルーム予約が完了しました",
-		}
-
-		// キャッシュに保存（冪等性保証）
-		bookingCache[req.BookingID] = result
-
-		a.logger.Info("ホテルルーム予約が完了", "BookingID", req.BookingID, "ResourceID", result.ResourceID)
-		return result, nil
+		return NewServerError(fmt.Sprintf("客室在庫の更新に失敗しました: %s", err.Error()), "INVENTORY_ERROR")
 	}
 }
 
 // HotelRoomBookingActivity ワークフロー用アダプター関数
 func HotelRoomBookingActivity(ctx context.Context, req HotelBookingRequest) (*HotelBookingResult, error) {
 	logger := NewTemporalLogger(ctx)
-	activity := NewHotelActivity(logger)
-	return activity.BookHotel(ctx, req)
+	activity := NewHotelActivity(logger, defaultIdempotencyStore, defaultHotelInventory).WithInventoryStore(defaultInventoryStore)
+	result, err := activity.BookHotel(ctx, req)
+	if result != nil {
+		result.Attempt = activityAttempt(ctx)
+	}
+	return result, toActivityError(ctx, err)
 }
@@ -2,25 +2,32 @@ package activities
 
 import "context"
 
-// hotelCompensationCache ホテルルーム補償処理のキャッシュ
-var hotelCompensationCache = make(map[string]*CompensationResult)
-
 // CompensateHotelRoomActivity ホテルルーム補償アクティビティ
 func (a *HotelActivity) CompensateHotel(ctx context.Context, bookingID string, resourceID string) (*CompensationResult, error) {
 	a.logger.Info("ホテルルーム補償処理を開始", "BookingID", bookingID, "ResourceID", resourceID)
 
 	// 冪等性チェック（既に補償済みかどうか）
-	if cached, exists := hotelCompensationCache[bookingID]; exists {
-		a.logger.Info("既に補償済みの予約", "BookingID", bookingID)
-		return cached, nil
+	key := IdempotencyKey(bookingID, OperationCompensateHotel, resourceID)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached CompensationResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
+		a.logger.Info("既に補償済みの予約", "BookingID", bookingID, "ResourceID", resourceID)
+		return &cached, nil
 	}
 
-	// 実際のシステムでは以下のような処理を行う：
-	// 1. ホテル予約システムAPIを呼び出して予約をキャンセル
-	// 2. 料金の返金処理
-	// 3. 在庫の復旧処理
-
-	// シミュレーション: ログ出力のみ
+	// 割り当てた客室の宿泊日を解放（解放済みの場合は何もしない）
+	a.inventory.Release(bookingID, resourceID)
+	if err := a.saveInventory(ctx); err != nil {
+		a.logger.Error("客室在庫の保存に失敗", "Error", err)
+		return nil, err
+	}
 	a.logger.Info("ホテルルーム予約をキャンセルしました", "BookingID", bookingID, "ResourceID", resourceID)
 
 	result := &CompensationResult{
@@ -28,8 +35,11 @@ func (a *HotelActivity) CompensateHotel(ctx context.Context, bookingID string, r
 		Message: "ホテルルーム予約の補償処理が完了しました",
 	}
 
-	// キャッシュに保存（冪等性保証）
-	hotelCompensationCache[bookingID] = result
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
+		return nil, err
+	}
 
 	a.logger.Info("ホテルルーム補償処理が完了", "BookingID", bookingID, "ResourceID", resourceID)
 	return result, nil
@@ -38,6 +48,7 @@ func (a *HotelActivity) CompensateHotel(ctx context.Context, bookingID string, r
 // CompensateHotelRoomActivity ワークフロー用アダプター関数
 func CompensateHotelRoomActivity(ctx context.Context, bookingID string, resourceID string) (*CompensationResult, error) {
 	logger := NewTemporalLogger(ctx)
-	activity := NewHotelActivity(logger)
-	return activity.CompensateHotel(ctx, bookingID, resourceID)
+	activity := NewHotelActivity(logger, defaultIdempotencyStore, defaultHotelInventory).WithInventoryStore(defaultInventoryStore)
+	result, err := activity.CompensateHotel(ctx, bookingID, resourceID)
+	return withAttempt(ctx, result), toActivityError(ctx, err)
 }
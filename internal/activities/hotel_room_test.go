@@ -3,23 +3,41 @@ package activities
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"temporal-hotel-sample/internal/inventory"
 )
 
+var (
+	testCheckIn  = time.Date(2026, 11, 1, 15, 0, 0, 0, time.UTC)
+	testCheckOut = time.Date(2026, 11, 3, 10, 0, 0, 0, time.UTC)
+)
+
+// newTestHotelInventory テスト用の客室在庫を作成
+func newTestHotelInventory() *inventory.HotelInventory {
+	return inventory.NewHotelInventory(inventory.DefaultHotels())
+}
+
 // テストケースについて
 // 正常系:
-//   - 正常なリクエストがされた場合、ホテル予約処理が完了する
-//   - booking-duplicateの時、冪等性が保証される
+//   - 正常なリクエストがされた場合、空いている客室が割り当てられる
+//   - RoomTypeが未指定の場合、standardの客室が割り当てられる
+//   - 他の予約で埋まっている客室は避けて割り当てられる
+//   - 宿泊日が重ならない場合、同じ客室が割り当てられる
+//   - 同じ予約IDで再実行された場合、同じ客室が返却される（冪等性）
 //
 // 異常系:
 //   - BookingIDが空の時、Businessエラーが返却される
 //   - UserIDが空の時、Businessエラーが返却される
 //   - HotelIDが空の時、Businessエラーが返却される
-//   - booking-network-errorの時、Serverエラーが返却される
-//   - booking-fullの時、Businessエラーが返却される
+//   - 存在しないホテルの時、Businessエラーが返却される
+//   - 存在しない客室タイプの時、Businessエラーが返却される
+//   - 宿泊期間が上限を超える時、Businessエラーが返却される
+//   - 空いている客室がない時、ROOM_FULLのBusinessエラーが返却される
 func Test_HotelRoomBookingActivity(t *testing.T) {
 	testcases := map[string]struct {
+		existing       []HotelBookingRequest
 		request        HotelBookingRequest
 		expectedResult *HotelBookingResult
 		expectedErr    error
@@ -28,33 +46,107 @@ func Test_HotelRoomBookingActivity(t *testing.T) {
 			request: HotelBookingRequest{
 				BookingID: "booking-123",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+				RoomType:  "deluxe",
 			},
 			expectedResult: &HotelBookingResult{
 				Success:    true,
-				ResourceID: "room-123",
+				ResourceID: "room-hotel-001-201-2026-11-01-booking-123",
+				RoomType:   "deluxe",
+				RoomNumber: "201",
+				Nights:     2,
 				Message:    "ホテルルーム予約が完了しました",
 			},
-			expectedErr: nil,
 		},
-		"正常系: booking-duplicateの時、冪等性が保証される": {
+		"正常系: RoomTypeが未指定の時、standardの客室が割り当てられる": {
 			request: HotelBookingRequest{
-				BookingID: "booking-duplicate",
+				BookingID: "booking-123",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedResult: &HotelBookingResult{
 				Success:    true,
-				ResourceID: "room-duplicate",
-				Message:    "既に予約済みです",
+				ResourceID: "room-hotel-001-101-2026-11-01-booking-123",
+				RoomType:   "standard",
+				RoomNumber: "101",
+				Nights:     2,
+				Message:    "ホテルルーム予約が完了しました",
+			},
+		},
+		"正常系: 他の予約で埋まっている客室は避けて割り当てられる": {
+			existing: []HotelBookingRequest{
+				{BookingID: "booking-other", UserID: "user-999", HotelID: "hotel-001", CheckIn: testCheckIn.AddDate(0, 0, 1), CheckOut: testCheckOut.AddDate(0, 0, 1), RoomType: "deluxe"},
+			},
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+				RoomType:  "deluxe",
+			},
+			expectedResult: &HotelBookingResult{
+				Success:    true,
+				ResourceID: "room-hotel-001-202-2026-11-01-booking-123",
+				RoomType:   "deluxe",
+				RoomNumber: "202",
+				Nights:     2,
+				Message:    "ホテルルーム予約が完了しました",
+			},
+		},
+		"正常系: 宿泊日が重ならない時、同じ客室が割り当てられる": {
+			existing: []HotelBookingRequest{
+				{BookingID: "booking-other", UserID: "user-999", HotelID: "hotel-001", CheckIn: testCheckIn.AddDate(0, 0, -2), CheckOut: testCheckIn, RoomType: "suite"},
+			},
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+				RoomType:  "suite",
+			},
+			expectedResult: &HotelBookingResult{
+				Success:    true,
+				ResourceID: "room-hotel-001-301-2026-11-01-booking-123",
+				RoomType:   "suite",
+				RoomNumber: "301",
+				Nights:     2,
+				Message:    "ホテルルーム予約が完了しました",
+			},
+		},
+		"正常系: 同じ予約IDで再実行された時、同じ客室が返却される": {
+			existing: []HotelBookingRequest{
+				{BookingID: "booking-123", UserID: "user-456", HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut, RoomType: "suite"},
+			},
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+				RoomType:  "suite",
+			},
+			expectedResult: &HotelBookingResult{
+				Success:    true,
+				ResourceID: "room-hotel-001-301-2026-11-01-booking-123",
+				RoomType:   "suite",
+				RoomNumber: "301",
+				Nights:     2,
+				Message:    "ホテルルーム予約が完了しました",
 			},
-			expectedErr: nil,
 		},
 		"異常系: BookingIDが空の時、Businessエラーが返却される": {
 			request: HotelBookingRequest{
 				BookingID: "",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
@@ -66,7 +158,9 @@ func Test_HotelRoomBookingActivity(t *testing.T) {
 			request: HotelBookingRequest{
 				BookingID: "booking-123",
 				UserID:    "",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
@@ -79,6 +173,8 @@ func Test_HotelRoomBookingActivity(t *testing.T) {
 				BookingID: "booking-123",
 				UserID:    "user-456",
 				HotelID:   "",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
@@ -86,28 +182,65 @@ func Test_HotelRoomBookingActivity(t *testing.T) {
 				Code:    "INVALID_HOTEL_ID",
 			},
 		},
-		"異常系: booking-network-errorの時、Serverエラーが返却される": {
+		"異常系: 存在しないホテルの時、Businessエラーが返却される": {
 			request: HotelBookingRequest{
-				BookingID: "booking-network-error",
+				BookingID: "booking-123",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-999",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedResult: nil,
-			expectedErr: &ServerError{
-				Message: "ネットワークエラーが発生しました",
-				Code:    "NETWORK_ERROR",
+			expectedErr: &BusinessError{
+				Message: "指定されたホテルが存在しません",
+				Code:    "HOTEL_NOT_FOUND",
 			},
 		},
-		"異常系: booking-fullの時、Businessエラーが返却される": {
+		"異常系: 存在しない客室タイプの時、Businessエラーが返却される": {
 			request: HotelBookingRequest{
-				BookingID: "booking-full",
+				BookingID: "booking-123",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-002",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+				RoomType:  "suite",
+			},
+			expectedResult: nil,
+			expectedErr: &BusinessError{
+				Message: "指定された客室タイプが存在しません",
+				Code:    "ROOM_TYPE_NOT_FOUND",
+			},
+		},
+		"異常系: 宿泊期間が上限を超える時、Businessエラーが返却される": {
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckIn.AddDate(0, 0, inventory.MaxStayNights+1),
+			},
+			expectedResult: nil,
+			expectedErr: &BusinessError{
+				Message: "宿泊期間が不正です: invalid stay: stay exceeds 30 nights",
+				Code:    "INVALID_STAY",
+			},
+		},
+		"異常系: 空いている客室がない時、ROOM_FULLのBusinessエラーが返却される": {
+			existing: []HotelBookingRequest{
+				{BookingID: "booking-other", UserID: "user-999", HotelID: "hotel-001", CheckIn: testCheckOut.AddDate(0, 0, -1), CheckOut: testCheckOut.AddDate(0, 0, 1), RoomType: "suite"},
+			},
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+				RoomType:  "suite",
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
 				Message: "指定されたホテルは満室です",
-				Code:    "HOTEL_FULL",
+				Code:    "ROOM_FULL",
 			},
 		},
 	}
@@ -117,7 +250,11 @@ func Test_HotelRoomBookingActivity(t *testing.T) {
 			// given
 			ctx := context.Background()
 			mockLogger := &MockLogger{}
-			sut := NewHotelActivity(mockLogger)
+			sut := NewHotelActivity(mockLogger, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), newTestHotelInventory())
+			for _, existing := range tc.existing {
+				_, err := sut.BookHotel(ctx, existing)
+				assert.NoError(t, err)
+			}
 
 			// when
 			actualResult, actualErr := sut.BookHotel(ctx, tc.request)
@@ -129,6 +266,98 @@ func Test_HotelRoomBookingActivity(t *testing.T) {
 	}
 }
 
+func TestHotelActivity_CompensateHotel(t *testing.T) {
+	// given: 1室しかないスイートが予約済み
+	ctx := context.Background()
+	hotels := newTestHotelInventory()
+	sut := NewHotelActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), hotels)
+	request := HotelBookingRequest{BookingID: "booking-123", UserID: "user-456", HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut, RoomType: "suite"}
+	booked, err := sut.BookHotel(ctx, request)
+	assert.NoError(t, err)
+
+	// when
+	result, err := sut.CompensateHotel(ctx, request.BookingID, booked.ResourceID)
+
+	// then: 宿泊日が解放され、別の予約で同じ客室が確保できる
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+	available, err := hotels.Available("hotel-001", "suite", testCheckIn, testCheckOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, available)
+
+	other := request
+	other.BookingID = "booking-other"
+	rebooked, err := sut.BookHotel(ctx, other)
+	assert.NoError(t, err)
+	assert.Equal(t, "301", rebooked.RoomNumber)
+}
+
+func TestHotelActivity_BookHotel_Revision(t *testing.T) {
+	// given: 同じ予約IDで標準客室が予約済み
+	ctx := context.Background()
+	hotels := newTestHotelInventory()
+	sut := NewHotelActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), hotels)
+	request := HotelBookingRequest{BookingID: "booking-123", UserID: "user-456", HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut}
+	original, err := sut.BookHotel(ctx, request)
+	assert.NoError(t, err)
+
+	// when: 予約変更の版を上げて同じ日程を予約する
+	modified := request
+	modified.Revision = 1
+	revised, err := sut.BookHotel(ctx, modified)
+
+	// then: 元の予約と同じ客室が確保され、空き客室は減らない
+	assert.NoError(t, err)
+	assert.Equal(t, original.RoomNumber, revised.RoomNumber)
+	available, err := hotels.Available("hotel-001", "standard", testCheckIn, testCheckOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, available)
+
+	// when: 元の予約を補償する
+	_, err = sut.CompensateHotel(ctx, request.BookingID, original.ResourceID)
+
+	// then: 変更後の予約は客室を確保したまま残る
+	assert.NoError(t, err)
+	available, err = hotels.Available("hotel-001", "standard", testCheckIn, testCheckOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, available)
+	assert.Len(t, hotels.Allocations(), 1)
+	assert.Equal(t, ReservationKey("booking-123", 1), hotels.Allocations()[0].BookingID)
+}
+
+func TestHotelActivity_BookHotel_RevisionShiftsDatesInSingleRoom(t *testing.T) {
+	// given: 1室しかない客室タイプを予約済み
+	ctx := context.Background()
+	hotels := newTestHotelInventory()
+	sut := NewHotelActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), hotels)
+	request := HotelBookingRequest{BookingID: "booking-123", UserID: "user-456", HotelID: "hotel-002", RoomType: "deluxe", CheckIn: testCheckIn, CheckOut: testCheckOut}
+	original, err := sut.BookHotel(ctx, request)
+	assert.NoError(t, err)
+
+	// when: 宿泊日を1日後ろにずらす予約変更をする
+	modified := request
+	modified.Revision = 1
+	modified.CheckIn = testCheckIn.AddDate(0, 0, 1)
+	modified.CheckOut = testCheckOut.AddDate(0, 0, 1)
+	revised, err := sut.BookHotel(ctx, modified)
+
+	// then: 元の予約と重なる宿泊日も含めて同じ客室が確保される
+	assert.NoError(t, err)
+	assert.Equal(t, original.RoomNumber, revised.RoomNumber)
+
+	// when: 変更後の予約を取り消す（ロールバック）
+	_, err = sut.CompensateHotel(ctx, ReservationKey(request.BookingID, 1), revised.ResourceID)
+
+	// then: 元の予約の宿泊日は確保されたまま残る
+	assert.NoError(t, err)
+	available, err := hotels.Available("hotel-002", "deluxe", testCheckIn, testCheckOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, available)
+	available, err = hotels.Available("hotel-002", "deluxe", testCheckOut, testCheckOut.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, available)
+}
+
 func TestHotelBookingRequest_Validate(t *testing.T) {
 	testcases := map[string]struct {
 		request     HotelBookingRequest
@@ -138,7 +367,9 @@ func TestHotelBookingRequest_Validate(t *testing.T) {
 			request: HotelBookingRequest{
 				BookingID: "booking-123",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedErr: nil,
 		},
@@ -146,7 +377,9 @@ func TestHotelBookingRequest_Validate(t *testing.T) {
 			request: HotelBookingRequest{
 				BookingID: "",
 				UserID:    "user-456",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedErr: &BusinessError{
 				Message: "BookingID is required",
@@ -157,7 +390,9 @@ func TestHotelBookingRequest_Validate(t *testing.T) {
 			request: HotelBookingRequest{
 				BookingID: "booking-123",
 				UserID:    "",
-				HotelID:   "hotel-789",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedErr: &BusinessError{
 				Message: "UserID is required",
@@ -169,12 +404,39 @@ func TestHotelBookingRequest_Validate(t *testing.T) {
 				BookingID: "booking-123",
 				UserID:    "user-456",
 				HotelID:   "",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
 			},
 			expectedErr: &BusinessError{
 				Message: "HotelID is required",
 				Code:    "INVALID_HOTEL_ID",
 			},
 		},
+		"異常系: CheckInが空": {
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckOut:  testCheckOut,
+			},
+			expectedErr: &BusinessError{
+				Message: "CheckIn is required",
+				Code:    "INVALID_CHECK_IN",
+			},
+		},
+		"異常系: CheckOutがCheckIn以前": {
+			request: HotelBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckIn,
+			},
+			expectedErr: &BusinessError{
+				Message: "CheckOut must be after CheckIn",
+				Code:    "INVALID_CHECK_OUT",
+			},
+		},
 	}
 
 	for name, tc := range testcases {
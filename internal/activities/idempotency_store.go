@@ -0,0 +1,357 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultIdempotencyTTL 処理結果を保持するデフォルトの期間
+const DefaultIdempotencyTTL = 7 * 24 * time.Hour
+
+// 冪等性キーの操作名
+const (
+	OperationBookHotel         = "book_hotel"
+	OperationCompensateHotel   = "compensate_hotel"
+	OperationBookDinner        = "book_dinner"
+	OperationCompensateDinner  = "compensate_dinner"
+	OperationBookParking       = "book_parking"
+	OperationCompensateParking = "compensate_parking"
+
+	OperationSendCheckInReminder = "send_check_in_reminder"
+)
+
+const (
+	idempotencyKeySeparator     = "/"
+	idempotencyBucket           = "idempotency"
+	idempotencySweepMinInterval = time.Minute
+)
+
+// IdempotencyKey 予約IDと操作名から冪等性キーを作成
+// 補償処理のように同じ予約で複数のリソースを扱う場合はリソースIDなどを追加で指定する
+func IdempotencyKey(bookingID, operation string, parts ...string) string {
+	return strings.Join(append([]string{bookingID, operation}, parts...), idempotencyKeySeparator)
+}
+
+// ReservationKey 予約変更の版ごとにリソースを確保するためのキー
+// 初回の予約（版0）は予約IDそのものとし、補償アクティビティにはこのキーを予約IDとして渡す
+func ReservationKey(bookingID string, revision int) string {
+	if revision == 0 {
+		return bookingID
+	}
+	return fmt.Sprintf("%s@r%d", bookingID, revision)
+}
+
+// IdempotencyStore 冪等性を保証するための処理結果ストア
+// 値はJSONとして保存されるため、取得時は保存時と同じ型のポインタを渡すこと
+type IdempotencyStore interface {
+	// Get キーに対応する処理結果をvalueに読み込む。存在しない・期限切れの場合はfalseを返す
+	Get(ctx context.Context, key string, value interface{}) (bool, error)
+	// Put 処理結果を保存する
+	Put(ctx context.Context, key string, value interface{}) error
+}
+
+// idempotencyRecord ストアに保存する処理結果
+type idempotencyRecord struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// expired 処理結果が期限切れかどうか
+func (r idempotencyRecord) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// newIdempotencyRecord 処理結果をJSONに変換して保存用のレコードを作成
+func newIdempotencyRecord(value interface{}, ttl time.Duration, now time.Time) (idempotencyRecord, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return idempotencyRecord{}, fmt.Errorf("marshal idempotency value: %w", err)
+	}
+	record := idempotencyRecord{Value: data}
+	if ttl > 0 {
+		record.ExpiresAt = now.Add(ttl)
+	}
+	return record, nil
+}
+
+// MemoryIdempotencyStore インメモリの処理結果ストア（TTL経過後に削除）
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]idempotencyRecord
+	ttl       time.Duration
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore インメモリの処理結果ストアを作成（ttlが0以下の場合は無期限）
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]idempotencyRecord),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string, value interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[key]
+	if !exists {
+		return false, nil
+	}
+	if record.expired(s.now()) {
+		delete(s.records, key)
+		return false, nil
+	}
+	if err := json.Unmarshal(record.Value, value); err != nil {
+		return false, fmt.Errorf("unmarshal idempotency value: %w", err)
+	}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(_ context.Context, key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	record, err := newIdempotencyRecord(value, s.ttl, now)
+	if err != nil {
+		return err
+	}
+	s.records[key] = record
+	s.sweep(now)
+	return nil
+}
+
+// sweep 期限切れの処理結果を削除する（呼び出し元でロックを取得済みであること）
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if s.ttl <= 0 || now.Sub(s.lastSweep) < idempotencySweepMinInterval {
+		return
+	}
+	for key, record := range s.records {
+		if record.expired(now) {
+			delete(s.records, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// BoltIdempotencyStore BoltDBファイルに保存する処理結果ストア
+// ワーカーが再起動しても処理結果を保持する。在庫の状態も同じファイルに保存する（InventoryStore）
+type BoltIdempotencyStore struct {
+	db  *bolt.DB
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewBoltIdempotencyStore BoltDBファイルを開いて処理結果ストアを作成（ttlが0以下の場合は無期限）
+func NewBoltIdempotencyStore(path string, ttl time.Duration) (*BoltIdempotencyStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open idempotency store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{idempotencyBucket, inventoryBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create idempotency buckets: %w", err)
+	}
+	return &BoltIdempotencyStore{
+		db:  db,
+		ttl: ttl,
+		now: time.Now,
+	}, nil
+}
+
+func (s *BoltIdempotencyStore) Get(_ context.Context, key string, value interface{}) (bool, error) {
+	var record idempotencyRecord
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(idempotencyBucket)).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &record)
+	})
+	if err != nil {
+		return false, fmt.Errorf("read idempotency record: %w", err)
+	}
+	if !found {
+		return false, nil
+	}
+	if record.expired(s.now()) {
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(idempotencyBucket)).Delete([]byte(key))
+		})
+		return false, err
+	}
+	if err := json.Unmarshal(record.Value, value); err != nil {
+		return false, fmt.Errorf("unmarshal idempotency value: %w", err)
+	}
+	return true, nil
+}
+
+func (s *BoltIdempotencyStore) Put(_ context.Context, key string, value interface{}) error {
+	now := s.now()
+	record, err := newIdempotencyRecord(value, s.ttl, now)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal idempotency record: %w", err)
+	}
+	sweep := s.sweepDue(now)
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(idempotencyBucket))
+		if err := bucket.Put([]byte(key), data); err != nil {
+			return err
+		}
+		if sweep {
+			return sweepIdempotencyBucket(bucket, now)
+		}
+		return nil
+	})
+	if err != nil {
+		if sweep {
+			s.resetSweep()
+		}
+		return err
+	}
+	return nil
+}
+
+// sweepDue 期限切れの処理結果を削除する時期かどうか（削除する場合は最終削除時刻を更新する）
+func (s *BoltIdempotencyStore) sweepDue(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ttl <= 0 || now.Sub(s.lastSweep) < idempotencySweepMinInterval {
+		return false
+	}
+	s.lastSweep = now
+	return true
+}
+
+// resetSweep トランザクションが失敗した場合に次回のPutで削除をやり直す
+func (s *BoltIdempotencyStore) resetSweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSweep = time.Time{}
+}
+
+// sweepIdempotencyBucket 期限切れの処理結果をバケットから削除する
+// 読み込めないレコードはGetでエラーとして扱うため、ここでは削除しない
+func sweepIdempotencyBucket(bucket *bolt.Bucket, now time.Time) error {
+	var expired [][]byte
+	err := bucket.ForEach(func(k, v []byte) error {
+		var record idempotencyRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return nil
+		}
+		if record.expired(now) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range expired {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close BoltDBファイルを閉じる
+func (s *BoltIdempotencyStore) Close() error {
+	return s.db.Close()
+}
+
+// loadIdempotentResult 処理済みの結果を取得（ストアの障害はリトライ可能なServerErrorとして返す）
+func loadIdempotentResult(ctx context.Context, store IdempotencyStore, key string, value interface{}) (bool, error) {
+	found, err := store.Get(ctx, key, value)
+	if err != nil {
+		return false, NewServerError(fmt.Sprintf("処理結果ストアの読み込みに失敗しました: %s", err.Error()), "IDEMPOTENCY_STORE_ERROR")
+	}
+	return found, nil
+}
+
+// saveIdempotentResult 処理結果を保存（ストアの障害はリトライ可能なServerErrorとして返す）
+func saveIdempotentResult(ctx context.Context, store IdempotencyStore, key string, value interface{}) error {
+	if err := store.Put(ctx, key, value); err != nil {
+		return NewServerError(fmt.Sprintf("処理結果ストアへの保存に失敗しました: %s", err.Error()), "IDEMPOTENCY_STORE_ERROR")
+	}
+	return nil
+}
+
+// keyedMutex キーごとの排他制御
+// 同じ冪等性キーに対する処理の同時実行を防ぐ
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+// Lock キーのロックを取得し、解放用の関数を返す
+func (m *keyedMutex) Lock(key string) func() {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedLock)
+	}
+	l, exists := m.locks[key]
+	if !exists {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.waiters++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		m.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+var (
+	// defaultIdempotencyStore ワークフロー用アダプター関数が使用する処理結果ストア
+	defaultIdempotencyStore IdempotencyStore = NewMemoryIdempotencyStore(DefaultIdempotencyTTL)
+	// idempotencyLocks 冪等性キーごとの排他制御
+	idempotencyLocks keyedMutex
+)
+
+// SetIdempotencyStore ワークフロー用アダプター関数が使用する処理結果ストアを設定
+// 在庫はインメモリのままのため、永続化するストアはSetPersistentStoreで設定すること
+// ワーカー起動時に呼び出すこと
+func SetIdempotencyStore(store IdempotencyStore) {
+	defaultIdempotencyStore = store
+}
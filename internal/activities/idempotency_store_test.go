@@ -0,0 +1,286 @@
+package activities
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+	"temporal-hotel-sample/internal/inventory"
+)
+
+// テストケースについて
+// 正常系:
+//   - 保存した処理結果が取得できる
+//   - 保存されていないキーはfalseが返却される
+//   - TTLを過ぎた処理結果はfalseが返却される
+func TestIdempotencyStore(t *testing.T) {
+	type clockSetter func(now func() time.Time)
+	stores := map[string]func(t *testing.T) (IdempotencyStore, clockSetter){
+		"インメモリ": func(t *testing.T) (IdempotencyStore, clockSetter) {
+			store := NewMemoryIdempotencyStore(time.Hour)
+			return store, func(now func() time.Time) { store.now = now }
+		},
+		"BoltDB": func(t *testing.T) (IdempotencyStore, clockSetter) {
+			store, err := NewBoltIdempotencyStore(filepath.Join(t.TempDir(), "idempotency.db"), time.Hour)
+			if err != nil {
+				t.Fatalf("ストアの作成に失敗しました: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store, func(now func() time.Time) { store.now = now }
+		},
+	}
+	saved := &HotelBookingResult{Success: true, ResourceID: "room-123", Message: "ホテルルーム予約が完了しました"}
+
+	testcases := map[string]struct {
+		getKey         string
+		elapsed        time.Duration
+		expectedFound  bool
+		expectedResult HotelBookingResult
+	}{
+		"正常系: 保存した処理結果が取得できる": {
+			getKey:         IdempotencyKey("booking-123", OperationBookHotel),
+			expectedFound:  true,
+			expectedResult: *saved,
+		},
+		"正常系: 保存されていないキーはfalseが返却される": {
+			getKey:        IdempotencyKey("booking-123", OperationBookParking),
+			expectedFound: false,
+		},
+		"正常系: TTLを過ぎた処理結果はfalseが返却される": {
+			getKey:        IdempotencyKey("booking-123", OperationBookHotel),
+			elapsed:       2 * time.Hour,
+			expectedFound: false,
+		},
+	}
+
+	for storeName, newStore := range stores {
+		for name, tc := range testcases {
+			t.Run(storeName+"/"+name, func(t *testing.T) {
+				// given
+				ctx := context.Background()
+				sut, setClock := newStore(t)
+				base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+				setClock(func() time.Time { return base })
+				assert.NoError(t, sut.Put(ctx, IdempotencyKey("booking-123", OperationBookHotel), saved))
+
+				// when
+				setClock(func() time.Time { return base.Add(tc.elapsed) })
+				var actual HotelBookingResult
+				found, err := sut.Get(ctx, tc.getKey, &actual)
+
+				// then
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedFound, found)
+				assert.Equal(t, tc.expectedResult, actual)
+			})
+		}
+	}
+}
+
+// テストケースについて
+// 正常系:
+//   - 保存時にTTLを過ぎた処理結果が削除される
+//   - 前回の削除から間隔が空いていない場合は削除しない
+func TestIdempotencyStore_Sweep(t *testing.T) {
+	type storeUnderTest struct {
+		store    IdempotencyStore
+		setClock func(now func() time.Time)
+		count    func() int
+	}
+	stores := map[string]func(t *testing.T) storeUnderTest{
+		"インメモリ": func(t *testing.T) storeUnderTest {
+			store := NewMemoryIdempotencyStore(time.Hour)
+			return storeUnderTest{
+				store:    store,
+				setClock: func(now func() time.Time) { store.now = now },
+				count:    func() int { return len(store.records) },
+			}
+		},
+		"BoltDB": func(t *testing.T) storeUnderTest {
+			store, err := NewBoltIdempotencyStore(filepath.Join(t.TempDir(), "idempotency.db"), time.Hour)
+			if err != nil {
+				t.Fatalf("ストアの作成に失敗しました: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return storeUnderTest{
+				store:    store,
+				setClock: func(now func() time.Time) { store.now = now },
+				count: func() int {
+					n := 0
+					store.db.View(func(tx *bolt.Tx) error {
+						n = tx.Bucket([]byte(idempotencyBucket)).Stats().KeyN
+						return nil
+					})
+					return n
+				},
+			}
+		},
+	}
+
+	type put struct {
+		key     string
+		elapsed time.Duration
+	}
+	testcases := map[string]struct {
+		puts          []put
+		expectedCount int
+	}{
+		"正常系: 保存時にTTLを過ぎた処理結果が削除される": {
+			puts: []put{
+				{key: "booking-123", elapsed: 0},
+				{key: "booking-456", elapsed: 2 * time.Hour},
+			},
+			expectedCount: 1,
+		},
+		"正常系: 前回の削除から間隔が空いていない場合は削除しない": {
+			puts: []put{
+				{key: "booking-123", elapsed: 0},
+				{key: "booking-456", elapsed: time.Hour - idempotencySweepMinInterval/2},
+				{key: "booking-789", elapsed: time.Hour},
+			},
+			expectedCount: 3,
+		},
+	}
+
+	for storeName, newStore := range stores {
+		for name, tc := range testcases {
+			t.Run(storeName+"/"+name, func(t *testing.T) {
+				// given
+				ctx := context.Background()
+				sut := newStore(t)
+				base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+				// when
+				for _, p := range tc.puts {
+					sut.setClock(func() time.Time { return base.Add(p.elapsed) })
+					assert.NoError(t, sut.store.Put(ctx, IdempotencyKey(p.key, OperationBookHotel), p.key))
+				}
+
+				// then
+				assert.Equal(t, tc.expectedCount, sut.count())
+			})
+		}
+	}
+}
+
+func TestSetPersistentStore_SurvivesRestart(t *testing.T) {
+	t.Cleanup(resetDefaultStores)
+
+	// given: 永続化するストアで客室・ディナー・駐車場を予約する
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "idempotency.db")
+	store := restartWorker(t, path)
+	hotelRequest := HotelBookingRequest{BookingID: "booking-restart", UserID: "user-456", HotelID: "hotel-002", RoomType: "deluxe", CheckIn: testCheckIn, CheckOut: testCheckOut}
+	hotel, err := defaultHotelActivity().BookHotel(ctx, hotelRequest)
+	assert.NoError(t, err)
+	dinner, err := defaultDinnerActivity().BookDinner(ctx, DinnerBookingRequest{BookingID: "booking-restart", UserID: "user-456", MenuType: "course", DateTime: testDinnerTime, Guests: 2})
+	assert.NoError(t, err)
+	parking, err := defaultParkingActivity().BookParking(ctx, ParkingBookingRequest{BookingID: "booking-restart", UserID: "user-456", SpaceType: "ev", StartTime: testParkingStart, EndTime: testCheckOut})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	// when: 再起動後に同じ予約がリトライされる
+	store = restartWorker(t, path)
+	retried, err := defaultHotelActivity().BookHotel(ctx, hotelRequest)
+
+	// then: 最初の予約と同じ結果が返却され、割り当ては在庫に残っている（同じ客室を別の予約に割り当てない）
+	assert.NoError(t, err)
+	assert.Equal(t, hotel, retried)
+	assert.Equal(t, []string{hotel.ResourceID}, roomAllocationIDs(defaultHotelInventory.Allocations()))
+	_, err = defaultHotelActivity().BookHotel(ctx, HotelBookingRequest{BookingID: "booking-other", UserID: "user-789", HotelID: "hotel-002", RoomType: "deluxe", CheckIn: testCheckIn, CheckOut: testCheckOut})
+	assert.Equal(t, &BusinessError{Message: "指定されたホテルは満室です", Code: CodeRoomFull}, err)
+	assert.Equal(t, inventory.DefaultStock()["wagyu"]-2, defaultIngredientLedger.Stock("wagyu"))
+	assert.Equal(t, parking.ResourceID, defaultParkingInventory.Allocations()[0].ID)
+
+	// when: 再起動後に補償し、もう一度再起動する
+	_, err = defaultHotelActivity().CompensateHotel(ctx, "booking-restart", hotel.ResourceID)
+	assert.NoError(t, err)
+	_, err = defaultDinnerActivity().CompensateDinner(ctx, "booking-restart", dinner.ResourceID)
+	assert.NoError(t, err)
+	_, err = defaultParkingActivity().CompensateParking(ctx, "booking-restart", parking.ResourceID)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+	store = restartWorker(t, path)
+	defer store.Close()
+
+	// then: 解放した在庫は再起動後も解放されたままになる
+	assert.Empty(t, defaultHotelInventory.Allocations())
+	assert.Equal(t, inventory.DefaultStock()["wagyu"], defaultIngredientLedger.Stock("wagyu"))
+	assert.Empty(t, defaultParkingInventory.Allocations())
+}
+
+// restartWorker ワーカーの再起動を模して、空の在庫とBoltDBファイルから処理結果ストアと在庫を復元する
+func restartWorker(t *testing.T, path string) *BoltIdempotencyStore {
+	t.Helper()
+	SetHotelInventory(newTestHotelInventory())
+	SetDinnerInventory(inventory.NewMenuCatalog(inventory.DefaultMenus()), inventory.NewIngredientLedger(inventory.DefaultStock()))
+	SetParkingInventory(inventory.NewParkingInventory(inventory.DefaultParkingLots()))
+	store, err := NewBoltIdempotencyStore(path, DefaultIdempotencyTTL)
+	if err != nil {
+		t.Fatalf("ストアの作成に失敗しました: %v", err)
+	}
+	if err := SetPersistentStore(context.Background(), store); err != nil {
+		t.Fatalf("在庫の復元に失敗しました: %v", err)
+	}
+	return store
+}
+
+// resetDefaultStores ワークフロー用アダプター関数が使用するストアと在庫を初期状態に戻す
+func resetDefaultStores() {
+	SetIdempotencyStore(NewMemoryIdempotencyStore(DefaultIdempotencyTTL))
+	defaultInventoryStore = nil
+	SetHotelInventory(newTestHotelInventory())
+	SetDinnerInventory(inventory.NewMenuCatalog(inventory.DefaultMenus()), inventory.NewIngredientLedger(inventory.DefaultStock()))
+	SetParkingInventory(inventory.NewParkingInventory(inventory.DefaultParkingLots()))
+}
+
+func defaultHotelActivity() *HotelActivity {
+	return NewHotelActivity(&MockLogger{}, defaultIdempotencyStore, defaultHotelInventory).WithInventoryStore(defaultInventoryStore)
+}
+
+func defaultDinnerActivity() *DinnerActivity {
+	return NewDinnerActivity(&MockLogger{}, defaultIdempotencyStore, defaultMenuCatalog, defaultIngredientLedger).WithInventoryStore(defaultInventoryStore)
+}
+
+func defaultParkingActivity() *ParkingActivity {
+	return NewParkingActivity(&MockLogger{}, defaultIdempotencyStore, defaultParkingInventory).WithInventoryStore(defaultInventoryStore)
+}
+
+// roomAllocationIDs 割り当てIDの一覧
+func roomAllocationIDs(allocations []inventory.RoomAllocation) []string {
+	ids := make([]string, 0, len(allocations))
+	for _, allocation := range allocations {
+		ids = append(ids, allocation.ID)
+	}
+	return ids
+}
+
+func TestHotelActivity_BookHotel_Concurrent(t *testing.T) {
+	// given
+	ctx := context.Background()
+	hotels := newTestHotelInventory()
+	sut := NewHotelActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), hotels)
+	request := HotelBookingRequest{BookingID: "booking-concurrent", UserID: "user-456", HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut}
+
+	// when: 同じ予約が同時に実行される
+	results := make([]*HotelBookingResult, 10)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = sut.BookHotel(ctx, request)
+		}(i)
+	}
+	wg.Wait()
+
+	// then: 全て同じ結果が返却され、客室は1室だけ割り当てられる
+	for _, result := range results {
+		assert.Equal(t, results[0], result)
+	}
+	assert.Len(t, hotels.Allocations(), 1)
+}
@@ -0,0 +1,119 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+	"temporal-hotel-sample/internal/inventory"
+)
+
+const inventoryBucket = "inventory"
+
+// 保存する在庫の名前
+const (
+	InventoryHotel   = "hotel"
+	InventoryDinner  = "dinner"
+	InventoryParking = "parking"
+)
+
+// InventoryStore 在庫の状態の保存先
+// 処理結果ストアを永続化する場合、再起動後にキャッシュした処理結果と在庫の割り当てが食い違わないよう在庫の状態も保存する
+type InventoryStore interface {
+	// LoadInventory 保存済みの在庫の状態をstateに読み込む。保存されていない場合はfalseを返す
+	LoadInventory(ctx context.Context, name string, state interface{}) (bool, error)
+	// SaveInventory 在庫の状態を保存する
+	SaveInventory(ctx context.Context, name string, state interface{}) error
+}
+
+// PersistentStore 処理結果と在庫の状態を同じ場所に保存するストア
+type PersistentStore interface {
+	IdempotencyStore
+	InventoryStore
+}
+
+func (s *BoltIdempotencyStore) LoadInventory(_ context.Context, name string, state interface{}) (bool, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte(inventoryBucket)).Get([]byte(name)); value != nil {
+			data = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("read inventory %s: %w", name, err)
+	}
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return false, fmt.Errorf("unmarshal inventory %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (s *BoltIdempotencyStore) SaveInventory(_ context.Context, name string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal inventory %s: %w", name, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(inventoryBucket)).Put([]byte(name), data)
+	})
+}
+
+var (
+	// defaultInventoryStore ワークフロー用アダプター関数が在庫の状態を保存する先（nilの場合はインメモリのみ）
+	defaultInventoryStore InventoryStore
+	// inventorySaveLocks 在庫ごとの保存の排他制御
+	// 状態の取得と保存をまとめて行い、古い状態で新しい状態を上書きしないようにする
+	inventorySaveLocks keyedMutex
+)
+
+// SetPersistentStore ワークフロー用アダプター関数が使用する処理結果ストアと在庫の保存先を設定
+// 保存済みの在庫の状態を客室在庫・食材台帳・駐車場の空き状況に復元してから切り替える
+// 処理結果だけを永続化すると、再起動後に存在しない割り当てをキャッシュから返して二重に割り当てるため、
+// 永続化するストアはSetIdempotencyStoreではなくこの関数で設定すること
+// ワーカー起動時に呼び出すこと
+func SetPersistentStore(ctx context.Context, store PersistentStore) error {
+	var rooms []inventory.RoomAllocation
+	if found, err := store.LoadInventory(ctx, InventoryHotel, &rooms); err != nil {
+		return err
+	} else if found {
+		defaultHotelInventory.Restore(rooms)
+	}
+
+	var ledger inventory.LedgerSnapshot
+	if found, err := store.LoadInventory(ctx, InventoryDinner, &ledger); err != nil {
+		return err
+	} else if found {
+		defaultIngredientLedger.Restore(ledger)
+	}
+
+	var spaces []inventory.SpaceAllocation
+	if found, err := store.LoadInventory(ctx, InventoryParking, &spaces); err != nil {
+		return err
+	} else if found {
+		defaultParkingInventory.Restore(spaces)
+	}
+
+	defaultIdempotencyStore = store
+	defaultInventoryStore = store
+	return nil
+}
+
+// saveInventory 変更後の在庫の状態を保存（保存先の障害はリトライ可能なServerErrorとして返す）
+// 処理結果を保存する前に呼び出し、キャッシュした処理結果が保存済みの在庫に必ず含まれるようにする
+func saveInventory(ctx context.Context, store InventoryStore, name string, snapshot func() interface{}) error {
+	if store == nil {
+		return nil
+	}
+	unlock := inventorySaveLocks.Lock(name)
+	defer unlock()
+
+	if err := store.SaveInventory(ctx, name, snapshot()); err != nil {
+		return NewServerError(fmt.Sprintf("在庫の保存に失敗しました: %s", err.Error()), "INVENTORY_STORE_ERROR")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package activities
+
+import (
+	"context"
+	"errors"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// MetricRetryAttempts リトライ可能なエラーで失敗したアクティビティの試行（activity_type, error_code）
+// リトライポリシーに従って再実行される試行を数える（試行回数の上限に達した最後の試行も含む）
+const MetricRetryAttempts = "hotel_booking_retry_attempts_total"
+
+// errorCodeUnclassified エラーコードを持たないエラーのerror_codeタグの値
+const errorCodeUnclassified = "UNCLASSIFIED"
+
+// toActivityError アクティビティのエラーをApplicationErrorに変換し、リトライ可能な失敗を記録する
+// ワークフロー用アダプター関数からエラーを返す際に使用する
+func toActivityError(ctx context.Context, err error) error {
+	err = ToApplicationError(err)
+	recordFailedAttempt(ctx, err)
+	return err
+}
+
+// recordFailedAttempt リトライ可能なエラーで失敗した試行をアクティビティとエラーコードごとに記録する
+// ビジネスエラーなどリトライ不可のエラーは記録しない
+func recordFailedAttempt(ctx context.Context, err error) {
+	if err == nil || !activity.IsActivity(ctx) {
+		return
+	}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.NonRetryable() {
+		return
+	}
+	code := ErrorCode(err)
+	if code == "" {
+		code = errorCodeUnclassified
+	}
+	activity.GetMetricsHandler(ctx).WithTags(map[string]string{
+		"activity_type": activity.GetInfo(ctx).ActivityType.Name,
+		"error_code":    code,
+	}).Counter(MetricRetryAttempts).Inc(1)
+}
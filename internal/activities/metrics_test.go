@@ -0,0 +1,104 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/sdk/testsuite"
+	"temporal-hotel-sample/internal/metrics"
+	"temporal-hotel-sample/internal/metrics/metricstest"
+)
+
+// テストケースについて
+// 正常系:
+//   - 補償が成功した時、リトライの試行は記録されない
+//
+// 準異常系:
+//   - Serverエラーで失敗した時、エラーコードごとにリトライの試行が記録される
+//   - Businessエラーで失敗した時、リトライされないため記録されない
+func Test_RecordFailedAttempt(t *testing.T) {
+	testcases := map[string]struct {
+		store    IdempotencyStore
+		activity interface{}
+		args     []interface{}
+
+		expectedErr    bool
+		expectedLabels map[string]string
+		expectedCount  float64
+	}{
+		"正常系: 補償が成功した時、リトライの試行は記録されない": {
+			activity:       CompensateHotelRoomActivity,
+			args:           []interface{}{"booking-metrics-001", "room-hotel-001-101-2026-11-01"},
+			expectedLabels: map[string]string{},
+			expectedCount:  0,
+		},
+		"準異常系: Serverエラーで失敗した時、エラーコードごとにリトライの試行が記録される": {
+			store:    &failingIdempotencyStore{err: errors.New("connection refused")},
+			activity: HotelRoomBookingActivity,
+			args: []interface{}{HotelBookingRequest{
+				BookingID: "booking-metrics-003",
+				UserID:    "user-456",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+			}},
+			expectedErr:    true,
+			expectedLabels: map[string]string{"activity_type": "HotelRoomBookingActivity", "error_code": "IDEMPOTENCY_STORE_ERROR"},
+			expectedCount:  1,
+		},
+		"準異常系: Businessエラーで失敗した時、リトライされないため記録されない": {
+			activity: HotelRoomBookingActivity,
+			args: []interface{}{HotelBookingRequest{
+				BookingID: "booking-metrics-002",
+				HotelID:   "hotel-001",
+				CheckIn:   testCheckIn,
+				CheckOut:  testCheckOut,
+			}},
+			expectedErr:    true,
+			expectedLabels: map[string]string{},
+			expectedCount:  0,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			if tc.store != nil {
+				SetIdempotencyStore(tc.store)
+				t.Cleanup(func() { SetIdempotencyStore(NewMemoryIdempotencyStore(DefaultIdempotencyTTL)) })
+			}
+			scope := tally.NewTestScope("", nil)
+			testSuite := &testsuite.WorkflowTestSuite{}
+			testSuite.SetMetricsHandler(metrics.NewHandler(scope))
+			env := testSuite.NewTestActivityEnvironment()
+			env.RegisterActivity(tc.activity)
+
+			// when
+			_, err := env.ExecuteActivity(tc.activity, tc.args...)
+
+			// then
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedCount, metricstest.Value(scope, MetricRetryAttempts, tc.expectedLabels))
+		})
+	}
+}
+
+// failingIdempotencyStore 読み書きが常に失敗する処理結果ストア
+type failingIdempotencyStore struct {
+	err error
+}
+
+func (s *failingIdempotencyStore) Get(context.Context, string, interface{}) (bool, error) {
+	return false, s.err
+}
+
+func (s *failingIdempotencyStore) Put(context.Context, string, interface{}) error {
+	return s.err
+}
@@ -0,0 +1,152 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckInReminder チェックイン前のリマインダー
+type CheckInReminder struct {
+	BookingID string    `json:"booking_id"`
+	UserID    string    `json:"user_id"`
+	HotelID   string    `json:"hotel_id"`
+	CheckIn   time.Time `json:"check_in"`
+}
+
+// Validate リマインダーの妥当性チェック
+func (r *CheckInReminder) Validate() error {
+	if strings.TrimSpace(r.BookingID) == "" {
+		return NewBusinessError("BookingID is required", "INVALID_BOOKING_ID")
+	}
+	if strings.TrimSpace(r.UserID) == "" {
+		return NewBusinessError("UserID is required", "INVALID_USER_ID")
+	}
+	if r.CheckIn.IsZero() {
+		return NewBusinessError("CheckIn is required", "INVALID_CHECK_IN")
+	}
+	return nil
+}
+
+// NotificationResult 通知の送信結果
+type NotificationResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Attempt int32  `json:"attempt,omitempty"` // 送信が完了した試行回数
+}
+
+// NotificationSender お客様への通知の送信先
+type NotificationSender interface {
+	// SendCheckInReminder チェックイン前のリマインダーを送信する
+	SendCheckInReminder(ctx context.Context, reminder CheckInReminder) error
+}
+
+// InMemoryNotificationSender 送信した通知をメモリに保持する送信先
+type InMemoryNotificationSender struct {
+	mu        sync.Mutex
+	reminders []CheckInReminder
+}
+
+// NewInMemoryNotificationSender インメモリの送信先を作成
+func NewInMemoryNotificationSender() *InMemoryNotificationSender {
+	return &InMemoryNotificationSender{}
+}
+
+func (s *InMemoryNotificationSender) SendCheckInReminder(_ context.Context, reminder CheckInReminder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reminders = append(s.reminders, reminder)
+	return nil
+}
+
+// Reminders 送信したリマインダーの一覧
+func (s *InMemoryNotificationSender) Reminders() []CheckInReminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]CheckInReminder(nil), s.reminders...)
+}
+
+// NotificationActivity お客様への通知アクティビティ
+type NotificationActivity struct {
+	logger Logger
+	store  IdempotencyStore
+	sender NotificationSender
+}
+
+// defaultNotificationSender ワークフロー用アダプター関数が使用する送信先
+var defaultNotificationSender NotificationSender = NewInMemoryNotificationSender()
+
+// SetNotificationSender ワークフロー用アダプター関数が使用する送信先を設定
+// ワーカー起動時に呼び出すこと
+func SetNotificationSender(sender NotificationSender) {
+	defaultNotificationSender = sender
+}
+
+func NewNotificationActivity(logger Logger, store IdempotencyStore, sender NotificationSender) *NotificationActivity {
+	return &NotificationActivity{
+		logger: logger,
+		store:  store,
+		sender: sender,
+	}
+}
+
+// SendCheckInReminder チェックイン前のリマインダーを送信（同じ予約には一度だけ送信する）
+func (a *NotificationActivity) SendCheckInReminder(ctx context.Context, reminder CheckInReminder) (*NotificationResult, error) {
+	a.logger.Info("チェックインのリマインダー送信を開始", "BookingID", reminder.BookingID, "CheckIn", reminder.CheckIn)
+
+	if err := reminder.Validate(); err != nil {
+		a.logger.Error("リクエストの妥当性チェックに失敗", "Error", err)
+		return nil, err
+	}
+
+	// 冪等性チェック（既に送信済みかどうか）
+	key := IdempotencyKey(reminder.BookingID, OperationSendCheckInReminder)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached NotificationResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
+		a.logger.Info("既に送信済みのリマインダー", "BookingID", reminder.BookingID)
+		return &cached, nil
+	}
+
+	if err := a.sender.SendCheckInReminder(ctx, reminder); err != nil {
+		err := NewServerError(fmt.Sprintf("リマインダーの送信に失敗しました: %s", err.Error()), "NOTIFICATION_ERROR")
+		a.logger.Error("リマインダーの送信に失敗", "Error", err)
+		return nil, err
+	}
+
+	result := &NotificationResult{
+		Success: true,
+		Message: "チェックインのリマインダーを送信しました",
+	}
+
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
+		return nil, err
+	}
+
+	a.logger.Info("チェックインのリマインダー送信が完了", "BookingID", reminder.BookingID)
+	return result, nil
+}
+
+// SendCheckInReminderActivity ワークフロー用アダプター関数
+func SendCheckInReminderActivity(ctx context.Context, reminder CheckInReminder) (*NotificationResult, error) {
+	logger := NewTemporalLogger(ctx)
+	activity := NewNotificationActivity(logger, defaultIdempotencyStore, defaultNotificationSender)
+	result, err := activity.SendCheckInReminder(ctx, reminder)
+	if result != nil {
+		result.Attempt = activityAttempt(ctx)
+	}
+	return result, toActivityError(ctx, err)
+}
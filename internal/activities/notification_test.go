@@ -0,0 +1,100 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingNotificationSender 常に送信に失敗する送信先
+type failingNotificationSender struct{}
+
+func (failingNotificationSender) SendCheckInReminder(context.Context, CheckInReminder) error {
+	return errors.New("メールサーバーに接続できません")
+}
+
+// テストケースについて
+// 正常系:
+//   - リマインダーが送信される
+//   - 同じ予約で再実行されても、リマインダーは一度だけ送信される
+//
+// 異常系:
+//   - 送信先が失敗した時、NOTIFICATION_ERRORのServerErrorが返却される
+//   - CheckInが未指定の時、INVALID_CHECK_INのBusinessErrorが返却される
+func TestNotificationActivity_SendCheckInReminder(t *testing.T) {
+	reminder := CheckInReminder{
+		BookingID: "booking-123",
+		UserID:    "user-123",
+		HotelID:   "hotel-001",
+		CheckIn:   time.Date(2026, 11, 1, 15, 0, 0, 0, time.UTC),
+	}
+
+	testcases := map[string]struct {
+		reminder      CheckInReminder
+		failingSender bool
+		calls         int
+
+		expectedSent int
+		expectedErr  error
+	}{
+		"正常系: リマインダーが送信される": {
+			reminder:     reminder,
+			calls:        1,
+			expectedSent: 1,
+		},
+		"正常系: 再実行されてもリマインダーは一度だけ送信される": {
+			reminder:     reminder,
+			calls:        2,
+			expectedSent: 1,
+		},
+		"異常系: 送信先が失敗した時、NOTIFICATION_ERRORが返却される": {
+			reminder:      reminder,
+			failingSender: true,
+			calls:         1,
+			expectedErr: &ServerError{
+				Message: "リマインダーの送信に失敗しました: メールサーバーに接続できません",
+				Code:    "NOTIFICATION_ERROR",
+			},
+		},
+		"異常系: CheckInが未指定の時、INVALID_CHECK_INが返却される": {
+			reminder: CheckInReminder{BookingID: "booking-123", UserID: "user-123", HotelID: "hotel-001"},
+			calls:    1,
+			expectedErr: &BusinessError{
+				Message: "CheckIn is required",
+				Code:    "INVALID_CHECK_IN",
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			ctx := context.Background()
+			sender := NewInMemoryNotificationSender()
+			sut := NewNotificationActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), sender)
+			if tc.failingSender {
+				sut = NewNotificationActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), failingNotificationSender{})
+			}
+
+			// when
+			var result *NotificationResult
+			var err error
+			for i := 0; i < tc.calls; i++ {
+				result, err = sut.SendCheckInReminder(ctx, tc.reminder)
+			}
+
+			// then
+			if tc.expectedErr != nil {
+				assert.Nil(t, result)
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, result.Success)
+			assert.Len(t, sender.Reminders(), tc.expectedSent)
+		})
+	}
+}
@@ -2,26 +2,55 @@ package activities
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	"temporal-hotel-sample/internal/inventory"
 )
 
 // ParkingBookingRequest 駐車場予約リクエスト
 type ParkingBookingRequest struct {
-	BookingID string `json:"booking_id"`
-	UserID    string `json:"user_id"`
-	SpaceType string `json:"space_type"`
+	BookingID string    `json:"booking_id"`
+	UserID    string    `json:"user_id"`
+	SpaceType string    `json:"space_type"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Revision  int       `json:"revision,omitempty"` // 予約変更の版（初回の予約は0）
 }
 
 // ParkingBookingResult 駐車場予約結果
 type ParkingBookingResult struct {
-	Success    bool   `json:"success"`
-	ResourceID string `json:"resource_id"`
-	Message    string `json:"message"`
-	ErrorCode  string `json:"error_code"`
+	Success    bool      `json:"success"`
+	ResourceID string    `json:"resource_id"`
+	LotID      string    `json:"lot_id,omitempty"`
+	SpaceID    string    `json:"space_id,omitempty"`
+	SpaceType  string    `json:"space_type,omitempty"` // 実際に割り当てた駐車スペースの種類
+	StartTime  time.Time `json:"start_time,omitempty"`
+	EndTime    time.Time `json:"end_time,omitempty"`
+	Message    string    `json:"message"`
+	ErrorCode  string    `json:"error_code"`
+	Attempt    int32     `json:"attempt,omitempty"` // 予約が完了した試行回数
 }
 
+func (r *ParkingBookingResult) GetResourceID() string { return r.ResourceID }
+func (r *ParkingBookingResult) GetAttempt() int32     { return r.Attempt }
+
 type ParkingActivity struct {
-	logger Logger
+	logger         Logger
+	store          IdempotencyStore
+	inventory      *inventory.ParkingInventory
+	inventoryStore InventoryStore // nilの場合は駐車場の空き状況を保存しない
+}
+
+// defaultParkingInventory ワークフロー用アダプター関数が使用する駐車場の空き状況
+var defaultParkingInventory = inventory.NewParkingInventory(inventory.DefaultParkingLots())
+
+// SetParkingInventory ワークフロー用アダプター関数が使用する駐車場の空き状況を設定
+// ワーカー起動時に呼び出すこと
+func SetParkingInventory(lots *inventory.ParkingInventory) {
+	defaultParkingInventory = lots
 }
 
 // Validate リクエストの妥当性チェック
@@ -35,18 +64,34 @@ func (pr *ParkingBookingRequest) Validate() error {
 	if strings.TrimSpace(pr.SpaceType) == "" {
 		return NewBusinessError("SpaceType is required", "INVALID_SPACE_TYPE")
 	}
+	if pr.StartTime.IsZero() {
+		return NewBusinessError("StartTime is required", "INVALID_START_TIME")
+	}
+	if !pr.EndTime.After(pr.StartTime) {
+		return NewBusinessError("EndTime must be after StartTime", "INVALID_END_TIME")
+	}
 	return nil
 }
 
-// parkingCache 冪等性のための簡単なインメモリキャッシュ
-var parkingCache = make(map[string]*ParkingBookingResult)
-
-func NewParkingActivity(logger Logger) *ParkingActivity {
+func NewParkingActivity(logger Logger, store IdempotencyStore, lots *inventory.ParkingInventory) *ParkingActivity {
 	return &ParkingActivity{
-		logger: logger,
+		logger:    logger,
+		store:     store,
+		inventory: lots,
 	}
 }
 
+// WithInventoryStore 駐車場の空き状況の変更を保存する先を設定
+func (a *ParkingActivity) WithInventoryStore(store InventoryStore) *ParkingActivity {
+	a.inventoryStore = store
+	return a
+}
+
+// saveInventory 変更後の駐車場の空き状況を保存
+func (a *ParkingActivity) saveInventory(ctx context.Context) error {
+	return saveInventory(ctx, a.inventoryStore, InventoryParking, func() interface{} { return a.inventory.Allocations() })
+}
+
 // BookParking 駐車場予約アクティビティ
 func (a *ParkingActivity) BookParking(ctx context.Context, req ParkingBookingRequest) (*ParkingBookingResult, error) {
 	a.logger.Info("駐車場予約アクティビティを開始", "BookingID", req.BookingID)
@@ -58,56 +103,82 @@ func (a *ParkingActivity) BookParking(ctx context.Context, req ParkingBookingReq
 	}
 
 	// 冪等性チェック（既に処理済みかどうか）
-	if cached, exists := parkingCache[req.BookingID]; exists {
+	reservationKey := ReservationKey(req.BookingID, req.Revision)
+	key := IdempotencyKey(reservationKey, OperationBookParking)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached ParkingBookingResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
 		a.logger.Info("既に処理済みの予約リクエスト", "BookingID", req.BookingID)
-		return cached, nil
+		return &cached, nil
 	}
 
-	// 特定のBookingIDに基づくシミュレーション
-	switch req.BookingID {
-	case "booking-connection-error":
-		// サーバーエラー（駐車場管理システム接続エラー）をシミュレート
-		err := NewServerError("駐車場管理システムへの接続に失敗しました", "CONNECTION_ERROR")
-		a.logger.Error("サーバーエラーが発生", "Error", err)
+	// 利用時間を通して空いている駐車スペースを割り当て
+	allocation, err := a.inventory.Reserve(inventory.ParkingWindow{
+		BookingID: reservationKey,
+		Owner:     req.BookingID,
+		SpaceType: req.SpaceType,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	})
+	if err != nil {
+		err := parkingInventoryError(err)
+		a.logger.Error("駐車スペースの割り当てに失敗", "Error", err)
 		return nil, err
+	}
+	if err := a.saveInventory(ctx); err != nil {
+		a.logger.Error("駐車場の空き状況の保存に失敗", "Error", err)
+		return nil, err
+	}
 
-	case "booking-full":
-		// ビジネスエラー（駐車場満車）をシミュレート
-		err := NewBusinessError("指定された駐車場は満車です", "PARKING_FULL")
-		a.logger.Error("ビジネスエラーが発生", "Error", err)
+	result := &ParkingBookingResult{
+		Success:    true,
+		ResourceID: allocation.ID,
+		LotID:      allocation.LotID,
+		SpaceID:    allocation.SpaceID,
+		SpaceType:  allocation.SpaceType,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+		Message:    "駐車場予約が完了しました",
+	}
+
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
 		return nil, err
+	}
 
-	case "booking-duplicate-parking":
-		// 冪等性テストのための特別処理
-		result := &ParkingBookingResult{
-			Success:    true,
-			ResourceID: "parking-duplicate",
-			Message:    "既に予約済みです",
-		}
-		// キャッシュに保存
-		parkingCache[req.BookingID] = result
-		a.logger.Info("重複リクエストの処理完了", "BookingID", req.BookingID)
-		return result, nil
+	a.logger.Info("駐車場予約が完了", "BookingID", req.BookingID, "ResourceID", result.ResourceID, "StartTime", req.StartTime, "EndTime", req.EndTime)
+	return result, nil
+}
 
+// parkingInventoryError 駐車場の空き状況のエラーをアクティビティのエラーに変換
+func parkingInventoryError(err error) error {
+	switch {
+	case errors.Is(err, inventory.ErrParkingFull):
+		return NewBusinessError("指定された駐車場は満車です", CodeParkingFull)
+	case errors.Is(err, inventory.ErrSpaceTypeNotFound):
+		return NewBusinessError("指定された駐車スペースの種類が存在しません", "SPACE_TYPE_NOT_FOUND")
+	case errors.Is(err, inventory.ErrInvalidWindow):
+		return NewBusinessError(fmt.Sprintf("駐車場の利用時間が不正です: %s", err.Error()), "INVALID_PARKING_WINDOW")
 	default:
-		// 正常な予約処理
-		result := &ParkingBookingResult{
-			Success:    true,
-			ResourceID: "parking-123", // 実際のシステムでは動的に生成
-			Message:    "駐車場予約が完了しました",
-		}
-
-		// キャッシュに保存（冪等性保証）
-		parkingCache[req.BookingID] = result
-
-		a.logger.Info("駐車場予約が完了", "BookingID", req.BookingID, "ResourceID", result.ResourceID)
-		return result, nil
+		return NewServerError(fmt.Sprintf("駐車場の空き状況の更新に失敗しました: %s", err.Error()), "INVENTORY_ERROR")
 	}
 }
 
 // ParkingBookingActivity ワークフロー用アダプター関数
 func ParkingBookingActivity(ctx context.Context, req ParkingBookingRequest) (*ParkingBookingResult, error) {
 	logger := NewTemporalLogger(ctx)
-	activity := NewParkingActivity(logger)
-	return activity.BookParking(ctx, req)
+	activity := NewParkingActivity(logger, defaultIdempotencyStore, defaultParkingInventory).WithInventoryStore(defaultInventoryStore)
+	result, err := activity.BookParking(ctx, req)
+	if result != nil {
+		result.Attempt = activityAttempt(ctx)
+	}
+	return result, toActivityError(ctx, err)
 }
@@ -2,25 +2,32 @@ package activities
 
 import "context"
 
-// parkingCompensationCache 駐車場補償処理のキャッシュ
-var parkingCompensationCache = make(map[string]*CompensationResult)
-
 // CompensateParkingActivity 駐車場補償アクティビティ
 func (a *ParkingActivity) CompensateParking(ctx context.Context, bookingID string, resourceID string) (*CompensationResult, error) {
 	a.logger.Info("駐車場補償処理を開始", "BookingID", bookingID, "ResourceID", resourceID)
 
 	// 冪等性チェック（既に補償済みかどうか）
-	if cached, exists := parkingCompensationCache[bookingID]; exists {
-		a.logger.Info("既に補償済みの予約", "BookingID", bookingID)
-		return cached, nil
+	key := IdempotencyKey(bookingID, OperationCompensateParking, resourceID)
+	unlock := idempotencyLocks.Lock(key)
+	defer unlock()
+
+	var cached CompensationResult
+	found, err := loadIdempotentResult(ctx, a.store, key, &cached)
+	if err != nil {
+		a.logger.Error("処理結果ストアの読み込みに失敗", "Error", err)
+		return nil, err
+	}
+	if found {
+		a.logger.Info("既に補償済みの予約", "BookingID", bookingID, "ResourceID", resourceID)
+		return &cached, nil
 	}
 
-	// 実際のシステムでは以下のような処理を行う：
-	// 1. 駐車場管理システムAPIを呼び出して予約をキャンセル
-	// 2. 駐車料金の返金処理
-	// 3. 駐車スペースの開放処理
-
-	// シミュレーション: ログ出力のみ
+	// 割り当てた駐車スペースを解放（解放済みの場合は何もしない）
+	a.inventory.Release(bookingID, resourceID)
+	if err := a.saveInventory(ctx); err != nil {
+		a.logger.Error("駐車場の空き状況の保存に失敗", "Error", err)
+		return nil, err
+	}
 	a.logger.Info("駐車場予約をキャンセルしました", "BookingID", bookingID, "ResourceID", resourceID)
 
 	result := &CompensationResult{
@@ -28,8 +35,11 @@ func (a *ParkingActivity) CompensateParking(ctx context.Context, bookingID strin
 		Message: "駐車場予約の補償処理が完了しました",
 	}
 
-	// キャッシュに保存（冪等性保証）
-	parkingCompensationCache[bookingID] = result
+	// 処理結果を保存（冪等性保証）
+	if err := saveIdempotentResult(ctx, a.store, key, result); err != nil {
+		a.logger.Error("処理結果ストアへの保存に失敗", "Error", err)
+		return nil, err
+	}
 
 	a.logger.Info("駐車場補償処理が完了", "BookingID", bookingID, "ResourceID", resourceID)
 	return result, nil
@@ -38,6 +48,7 @@ func (a *ParkingActivity) CompensateParking(ctx context.Context, bookingID strin
 // CompensateParkingActivity ワークフロー用アダプター関数
 func CompensateParkingActivity(ctx context.Context, bookingID string, resourceID string) (*CompensationResult, error) {
 	logger := NewTemporalLogger(ctx)
-	activity := NewParkingActivity(logger)
-	return activity.CompensateParking(ctx, bookingID, resourceID)
+	activity := NewParkingActivity(logger, defaultIdempotencyStore, defaultParkingInventory).WithInventoryStore(defaultInventoryStore)
+	result, err := activity.CompensateParking(ctx, bookingID, resourceID)
+	return withAttempt(ctx, result), toActivityError(ctx, err)
 }
@@ -3,59 +3,136 @@ package activities
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"temporal-hotel-sample/internal/inventory"
 )
 
+var (
+	testParkingStart = time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC)
+	testParkingEnd   = time.Date(2026, 11, 3, 11, 0, 0, 0, time.UTC)
+)
+
+// newTestParkingInventory テスト用の駐車場の空き状況を作成（普通車2台・大型1台・EV1台）
+func newTestParkingInventory() *inventory.ParkingInventory {
+	return inventory.NewParkingInventory([]inventory.ParkingLot{
+		{ID: "lot-test", Spaces: []inventory.ParkingSpace{
+			{ID: "S-01", Type: inventory.SpaceTypeStandard},
+			{ID: "S-02", Type: inventory.SpaceTypeStandard},
+			{ID: "O-01", Type: inventory.SpaceTypeOversize},
+			{ID: "EV-01", Type: inventory.SpaceTypeEV},
+		}},
+	})
+}
+
+// testParkingRequest テスト用の駐車場予約リクエストを作成
+func testParkingRequest(bookingID, spaceType string, start, end time.Time) ParkingBookingRequest {
+	return ParkingBookingRequest{BookingID: bookingID, UserID: "user-456", SpaceType: spaceType, StartTime: start, EndTime: end}
+}
+
 // テストケースについて
 // 正常系:
-//   - 正常なリクエストがされた場合、駐車場予約処理が完了する
-//   - booking-duplicate-parkingの時、冪等性が保証される
+//   - 正常なリクエストがされた場合、指定した種類の駐車スペースが割り当てられる
+//   - 利用時間が重なる予約とは別の駐車スペースが割り当てられる
+//   - 利用時間が重ならない予約とは同じ駐車スペースが割り当てられる
+//   - 普通車のスペースが埋まっている場合、大型スペースが割り当てられる
+//   - 同じ予約IDで再実行された場合、同じ駐車スペースが返却される（冪等性）
 //
 // 異常系:
 //   - BookingIDが空の時、Businessエラーが返却される
 //   - UserIDが空の時、Businessエラーが返却される
 //   - SpaceTypeが空の時、Businessエラーが返却される
-//   - booking-connection-errorの時、Serverエラーが返却される
-//   - booking-fullの時、Businessエラーが返却される
+//   - EndTimeがStartTime以前の時、Businessエラーが返却される
+//   - 存在しない種類の時、Businessエラーが返却される
+//   - 互換性のある駐車スペースが空いていない時、PARKING_FULLのBusinessエラーが返却される
 func Test_ParkingBookingActivity(t *testing.T) {
 	testcases := map[string]struct {
+		existing       []ParkingBookingRequest
 		request        ParkingBookingRequest
 		expectedResult *ParkingBookingResult
 		expectedErr    error
 	}{
 		"正常系: 想定通りのリクエストが来た時、駐車場予約が成功する": {
-			request: ParkingBookingRequest{
-				BookingID: "booking-123",
-				UserID:    "user-456",
-				SpaceType: "standard",
+			request: testParkingRequest("booking-123", "ev", testParkingStart, testParkingEnd),
+			expectedResult: &ParkingBookingResult{
+				Success:    true,
+				ResourceID: "parking-lot-test-EV-01-20261101T1400",
+				LotID:      "lot-test",
+				SpaceID:    "EV-01",
+				SpaceType:  "ev",
+				StartTime:  testParkingStart,
+				EndTime:    testParkingEnd,
+				Message:    "駐車場予約が完了しました",
 			},
+		},
+		"正常系: 利用時間が重なる予約とは別の駐車スペースが割り当てられる": {
+			existing: []ParkingBookingRequest{
+				testParkingRequest("booking-other", "standard", testParkingEnd.Add(-time.Hour), testParkingEnd.Add(time.Hour)),
+			},
+			request: testParkingRequest("booking-123", "standard", testParkingStart, testParkingEnd),
 			expectedResult: &ParkingBookingResult{
 				Success:    true,
-				ResourceID: "parking-123",
+				ResourceID: "parking-lot-test-S-02-20261101T1400",
+				LotID:      "lot-test",
+				SpaceID:    "S-02",
+				SpaceType:  "standard",
+				StartTime:  testParkingStart,
+				EndTime:    testParkingEnd,
 				Message:    "駐車場予約が完了しました",
 			},
-			expectedErr: nil,
 		},
-		"正常系: booking-duplicate-parkingの時、冪等性が保証される": {
-			request: ParkingBookingRequest{
-				BookingID: "booking-duplicate-parking",
-				UserID:    "user-456",
-				SpaceType: "standard",
+		"正常系: 利用時間が重ならない予約とは同じ駐車スペースが割り当てられる": {
+			existing: []ParkingBookingRequest{
+				testParkingRequest("booking-other", "standard", testParkingStart.Add(-3*time.Hour), testParkingStart),
 			},
+			request: testParkingRequest("booking-123", "standard", testParkingStart, testParkingEnd),
 			expectedResult: &ParkingBookingResult{
 				Success:    true,
-				ResourceID: "parking-duplicate",
-				Message:    "既に予約済みです",
+				ResourceID: "parking-lot-test-S-01-20261101T1400",
+				LotID:      "lot-test",
+				SpaceID:    "S-01",
+				SpaceType:  "standard",
+				StartTime:  testParkingStart,
+				EndTime:    testParkingEnd,
+				Message:    "駐車場予約が完了しました",
 			},
-			expectedErr: nil,
 		},
-		"異常系: BookingIDが空の時、Businessエラーが返却される": {
-			request: ParkingBookingRequest{
-				BookingID: "",
-				UserID:    "user-456",
-				SpaceType: "standard",
+		"正常系: 普通車のスペースが埋まっている時、大型スペースが割り当てられる": {
+			existing: []ParkingBookingRequest{
+				testParkingRequest("booking-other-1", "standard", testParkingStart, testParkingEnd),
+				testParkingRequest("booking-other-2", "standard", testParkingStart, testParkingEnd),
+			},
+			request: testParkingRequest("booking-123", "standard", testParkingStart, testParkingEnd),
+			expectedResult: &ParkingBookingResult{
+				Success:    true,
+				ResourceID: "parking-lot-test-O-01-20261101T1400",
+				LotID:      "lot-test",
+				SpaceID:    "O-01",
+				SpaceType:  "oversize",
+				StartTime:  testParkingStart,
+				EndTime:    testParkingEnd,
+				Message:    "駐車場予約が完了しました",
+			},
+		},
+		"正常系: 同じ予約IDで再実行された時、同じ駐車スペースが返却される": {
+			existing: []ParkingBookingRequest{
+				testParkingRequest("booking-123", "ev", testParkingStart, testParkingEnd),
 			},
+			request: testParkingRequest("booking-123", "ev", testParkingStart, testParkingEnd),
+			expectedResult: &ParkingBookingResult{
+				Success:    true,
+				ResourceID: "parking-lot-test-EV-01-20261101T1400",
+				LotID:      "lot-test",
+				SpaceID:    "EV-01",
+				SpaceType:  "ev",
+				StartTime:  testParkingStart,
+				EndTime:    testParkingEnd,
+				Message:    "駐車場予約が完了しました",
+			},
+		},
+		"異常系: BookingIDが空の時、Businessエラーが返却される": {
+			request:        testParkingRequest("", "standard", testParkingStart, testParkingEnd),
 			expectedResult: nil,
 			expectedErr: &BusinessError{
 				Message: "BookingID is required",
@@ -67,6 +144,8 @@ func Test_ParkingBookingActivity(t *testing.T) {
 				BookingID: "booking-123",
 				UserID:    "",
 				SpaceType: "standard",
+				StartTime: testParkingStart,
+				EndTime:   testParkingEnd,
 			},
 			expectedResult: nil,
 			expectedErr: &BusinessError{
@@ -75,35 +154,34 @@ func Test_ParkingBookingActivity(t *testing.T) {
 			},
 		},
 		"異常系: SpaceTypeが空の時、Businessエラーが返却される": {
-			request: ParkingBookingRequest{
-				BookingID: "booking-123",
-				UserID:    "user-456",
-				SpaceType: "",
-			},
+			request:        testParkingRequest("booking-123", "", testParkingStart, testParkingEnd),
 			expectedResult: nil,
 			expectedErr: &BusinessError{
 				Message: "SpaceType is required",
 				Code:    "INVALID_SPACE_TYPE",
 			},
 		},
-		"異常系: booking-connection-errorの時、Serverエラーが返却される": {
-			request: ParkingBookingRequest{
-				BookingID: "booking-connection-error",
-				UserID:    "user-456",
-				SpaceType: "standard",
+		"異常系: EndTimeがStartTime以前の時、Businessエラーが返却される": {
+			request:        testParkingRequest("booking-123", "standard", testParkingEnd, testParkingStart),
+			expectedResult: nil,
+			expectedErr: &BusinessError{
+				Message: "EndTime must be after StartTime",
+				Code:    "INVALID_END_TIME",
 			},
+		},
+		"異常系: 存在しない種類の時、Businessエラーが返却される": {
+			request:        testParkingRequest("booking-123", "helipad", testParkingStart, testParkingEnd),
 			expectedResult: nil,
-			expectedErr: &ServerError{
-				Message: "駐車場管理システムへの接続に失敗しました",
-				Code:    "CONNECTION_ERROR",
+			expectedErr: &BusinessError{
+				Message: "指定された駐車スペースの種類が存在しません",
+				Code:    "SPACE_TYPE_NOT_FOUND",
 			},
 		},
-		"異常系: booking-fullの時、Businessエラーが返却される": {
-			request: ParkingBookingRequest{
-				BookingID: "booking-full",
-				UserID:    "user-456",
-				SpaceType: "standard",
+		"異常系: 互換性のある駐車スペースが空いていない時、PARKING_FULLのBusinessエラーが返却される": {
+			existing: []ParkingBookingRequest{
+				testParkingRequest("booking-other", "ev", testParkingStart.Add(time.Hour), testParkingStart.Add(2*time.Hour)),
 			},
+			request:        testParkingRequest("booking-123", "ev", testParkingStart, testParkingEnd),
 			expectedResult: nil,
 			expectedErr: &BusinessError{
 				Message: "指定された駐車場は満車です",
@@ -117,7 +195,11 @@ func Test_ParkingBookingActivity(t *testing.T) {
 			// given
 			ctx := context.Background()
 			mockLogger := &MockLogger{}
-			sut := NewParkingActivity(mockLogger)
+			sut := NewParkingActivity(mockLogger, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), newTestParkingInventory())
+			for _, existing := range tc.existing {
+				_, err := sut.BookParking(ctx, existing)
+				assert.NoError(t, err)
+			}
 
 			// when
 			actualResult, actualErr := sut.BookParking(ctx, tc.request)
@@ -129,6 +211,27 @@ func Test_ParkingBookingActivity(t *testing.T) {
 	}
 }
 
+func TestParkingActivity_CompensateParking(t *testing.T) {
+	// given: EVスペースが1つだけ予約済み
+	ctx := context.Background()
+	lots := newTestParkingInventory()
+	sut := NewParkingActivity(&MockLogger{}, NewMemoryIdempotencyStore(DefaultIdempotencyTTL), lots)
+	booked, err := sut.BookParking(ctx, testParkingRequest("booking-123", "ev", testParkingStart, testParkingEnd))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, lots.Available(inventory.SpaceTypeEV, testParkingStart, testParkingEnd))
+
+	// when
+	result, err := sut.CompensateParking(ctx, "booking-123", booked.ResourceID)
+
+	// then: 割り当てたスペースが解放され、別の予約で確保できる
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 1, lots.Available(inventory.SpaceTypeEV, testParkingStart, testParkingEnd))
+	rebooked, err := sut.BookParking(ctx, testParkingRequest("booking-other", "ev", testParkingStart, testParkingEnd))
+	assert.NoError(t, err)
+	assert.Equal(t, "EV-01", rebooked.SpaceID)
+}
+
 func TestParkingBookingRequest_Validate(t *testing.T) {
 	testcases := map[string]struct {
 		request     ParkingBookingRequest
@@ -139,6 +242,8 @@ func TestParkingBookingRequest_Validate(t *testing.T) {
 				BookingID: "booking-123",
 				UserID:    "user-456",
 				SpaceType: "standard",
+				StartTime: testParkingStart,
+				EndTime:   testParkingEnd,
 			},
 			expectedErr: nil,
 		},
@@ -147,6 +252,8 @@ func TestParkingBookingRequest_Validate(t *testing.T) {
 				BookingID: "",
 				UserID:    "user-456",
 				SpaceType: "standard",
+				StartTime: testParkingStart,
+				EndTime:   testParkingEnd,
 			},
 			expectedErr: &BusinessError{
 				Message: "BookingID is required",
@@ -158,17 +265,33 @@ func TestParkingBookingRequest_Validate(t *testing.T) {
 				BookingID: "booking-123",
 				UserID:    "",
 				SpaceType: "standard",
+				StartTime: testParkingStart,
+				EndTime:   testParkingEnd,
 			},
 			expectedErr: &BusinessError{
 				Message: "UserID is required",
 				Code:    "INVALID_USER_ID",
 			},
 		},
+		"異常系: StartTimeが空": {
+			request: ParkingBookingRequest{
+				BookingID: "booking-123",
+				UserID:    "user-456",
+				SpaceType: "standard",
+				EndTime:   testParkingEnd,
+			},
+			expectedErr: &BusinessError{
+				Message: "StartTime is required",
+				Code:    "INVALID_START_TIME",
+			},
+		},
 		"異常系: SpaceTypeが空": {
 			request: ParkingBookingRequest{
 				BookingID: "booking-123",
 				UserID:    "user-456",
 				SpaceType: "",
+				StartTime: testParkingStart,
+				EndTime:   testParkingEnd,
 			},
 			expectedErr: &BusinessError{
 				Message: "SpaceType is required",
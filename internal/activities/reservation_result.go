@@ -0,0 +1,10 @@
+package activities
+
+// ReservationResult 予約アクティビティの結果に共通する情報
+// Sagaのステップは、この情報を使って補償処理の登録と試行回数の記録を行う
+type ReservationResult interface {
+	// GetResourceID 確保したリソースのID（補償処理の対象）
+	GetResourceID() string
+	// GetAttempt 予約が完了した試行回数
+	GetAttempt() int32
+}
@@ -0,0 +1,292 @@
+package activities
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StuckCompensationStatus 手動対応待ち補償処理の状態
+type StuckCompensationStatus string
+
+const (
+	// StuckCompensationPending 手動対応待ち
+	StuckCompensationPending StuckCompensationStatus = "pending"
+	// StuckCompensationRetried オペレーターの再実行により補償が完了した
+	StuckCompensationRetried StuckCompensationStatus = "retried"
+	// StuckCompensationManuallyResolved オペレーターが手動で解決済みとした
+	StuckCompensationManuallyResolved StuckCompensationStatus = "manually_resolved"
+)
+
+// StuckCompensation リトライ上限まで失敗した補償処理の記録
+type StuckCompensation struct {
+	BookingID  string                  `json:"booking_id"`
+	WorkflowID string                  `json:"workflow_id,omitempty"`
+	RunID      string                  `json:"run_id,omitempty"`
+	Step       string                  `json:"step"`
+	ResourceID string                  `json:"resource_id,omitempty"`
+	Attempts   int32                   `json:"attempts"`
+	LastError  string                  `json:"last_error"`
+	Status     StuckCompensationStatus `json:"status"`
+	Note       string                  `json:"note,omitempty"`
+	RecordedAt time.Time               `json:"recorded_at"`
+	UpdatedAt  time.Time               `json:"updated_at"`
+}
+
+// Key 手動対応待ち補償処理を一意に識別するキー
+func (s StuckCompensation) Key() string {
+	return fmt.Sprintf("%s/%s/%s", s.BookingID, s.Step, s.ResourceID)
+}
+
+// StuckCompensationSink 手動対応待ち補償処理の記録先
+type StuckCompensationSink interface {
+	// Record 手動対応待ちの補償処理を記録する
+	Record(ctx context.Context, entry StuckCompensation) error
+	// UpdateStatus 記録済みの補償処理の状態を更新する
+	UpdateStatus(ctx context.Context, bookingID, step, resourceID string, status StuckCompensationStatus, note string) error
+	// List 記録済みの補償処理を一覧で返す
+	List(ctx context.Context) ([]StuckCompensation, error)
+}
+
+// ErrStuckCompensationNotFound 指定された補償処理の記録が存在しない
+var ErrStuckCompensationNotFound = errors.New("stuck compensation not found")
+
+// InMemoryStuckCompensationSink インメモリの記録先
+type InMemoryStuckCompensationSink struct {
+	mu      sync.Mutex
+	entries map[string]StuckCompensation
+	now     func() time.Time
+}
+
+// NewInMemoryStuckCompensationSink インメモリの記録先を作成
+func NewInMemoryStuckCompensationSink() *InMemoryStuckCompensationSink {
+	return &InMemoryStuckCompensationSink{
+		entries: make(map[string]StuckCompensation),
+		now:     time.Now,
+	}
+}
+
+func (s *InMemoryStuckCompensationSink) Record(_ context.Context, entry StuckCompensation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[entry.Key()] = newStuckEntry(entry, s.now())
+	return nil
+}
+
+func (s *InMemoryStuckCompensationSink) UpdateStatus(_ context.Context, bookingID, step, resourceID string, status StuckCompensationStatus, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := StuckCompensation{BookingID: bookingID, Step: step, ResourceID: resourceID}.Key()
+	entry, exists := s.entries[key]
+	if !exists {
+		return ErrStuckCompensationNotFound
+	}
+	entry.Status = status
+	entry.Note = note
+	entry.UpdatedAt = s.now()
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *InMemoryStuckCompensationSink) List(_ context.Context) ([]StuckCompensation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedStuckEntries(s.entries), nil
+}
+
+// FileStuckCompensationSink JSON Lines形式のファイルに追記する記録先
+// 状態の更新も1行として追記し、読み込み時に最新の状態へ畳み込む
+type FileStuckCompensationSink struct {
+	mu   sync.Mutex
+	path string
+	now  func() time.Time
+}
+
+// NewFileStuckCompensationSink ファイルベースの記録先を作成
+func NewFileStuckCompensationSink(path string) *FileStuckCompensationSink {
+	return &FileStuckCompensationSink{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+func (s *FileStuckCompensationSink) Record(_ context.Context, entry StuckCompensation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.append(newStuckEntry(entry, s.now()))
+}
+
+func (s *FileStuckCompensationSink) UpdateStatus(_ context.Context, bookingID, step, resourceID string, status StuckCompensationStatus, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry, exists := entries[StuckCompensation{BookingID: bookingID, Step: step, ResourceID: resourceID}.Key()]
+	if !exists {
+		return ErrStuckCompensationNotFound
+	}
+	entry.Status = status
+	entry.Note = note
+	entry.UpdatedAt = s.now()
+	return s.append(entry)
+}
+
+func (s *FileStuckCompensationSink) List(_ context.Context) ([]StuckCompensation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return sortedStuckEntries(entries), nil
+}
+
+// append 1件の記録をファイルに追記する
+func (s *FileStuckCompensationSink) append(entry StuckCompensation) error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open stuck compensation file: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal stuck compensation: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write stuck compensation: %w", err)
+	}
+	return f.Sync()
+}
+
+// load ファイルを読み込み、キーごとに最新の状態へ畳み込む
+func (s *FileStuckCompensationSink) load() (map[string]StuckCompensation, error) {
+	entries := make(map[string]StuckCompensation)
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open stuck compensation file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry StuckCompensation
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("unmarshal stuck compensation: %w", err)
+		}
+		entries[entry.Key()] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stuck compensation file: %w", err)
+	}
+	return entries, nil
+}
+
+// newStuckEntry 記録用のエントリを初期化する
+func newStuckEntry(entry StuckCompensation, now time.Time) StuckCompensation {
+	if entry.Status == "" {
+		entry.Status = StuckCompensationPending
+	}
+	if entry.RecordedAt.IsZero() {
+		entry.RecordedAt = now
+	}
+	entry.UpdatedAt = now
+	return entry
+}
+
+// sortedStuckEntries 記録日時順に並べた一覧を返す
+func sortedStuckEntries(entries map[string]StuckCompensation) []StuckCompensation {
+	list := make([]StuckCompensation, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].RecordedAt.Equal(list[j].RecordedAt) {
+			return list[i].RecordedAt.Before(list[j].RecordedAt)
+		}
+		return list[i].Key() < list[j].Key()
+	})
+	return list
+}
+
+// StuckCompensationActivity 手動対応待ち補償処理を記録するアクティビティ
+type StuckCompensationActivity struct {
+	logger Logger
+	sink   StuckCompensationSink
+}
+
+// defaultStuckCompensationSink ワークフロー用アダプター関数が使用する記録先
+var defaultStuckCompensationSink StuckCompensationSink = NewInMemoryStuckCompensationSink()
+
+// SetStuckCompensationSink ワークフロー用アダプター関数が使用する記録先を設定
+// ワーカー起動時に呼び出すこと
+func SetStuckCompensationSink(sink StuckCompensationSink) {
+	defaultStuckCompensationSink = sink
+}
+
+func NewStuckCompensationActivity(logger Logger, sink StuckCompensationSink) *StuckCompensationActivity {
+	return &StuckCompensationActivity{
+		logger: logger,
+		sink:   sink,
+	}
+}
+
+// RecordStuckCompensation 手動対応待ちの補償処理を記録
+func (a *StuckCompensationActivity) RecordStuckCompensation(ctx context.Context, entry StuckCompensation) error {
+	a.logger.Error("補償処理がリトライ上限に達したため手動対応待ちとして記録",
+		"BookingID", entry.BookingID, "Step", entry.Step, "ResourceID", entry.ResourceID, "LastError", entry.LastError)
+
+	if err := a.sink.Record(ctx, entry); err != nil {
+		return NewServerError(fmt.Sprintf("手動対応待ち補償処理の記録に失敗しました: %s", err.Error()), "STUCK_COMPENSATION_RECORD_FAILED")
+	}
+	return nil
+}
+
+// UpdateStuckCompensation 手動対応待ちの補償処理の状態を更新
+func (a *StuckCompensationActivity) UpdateStuckCompensation(ctx context.Context, entry StuckCompensation) error {
+	a.logger.Info("手動対応待ち補償処理の状態を更新",
+		"BookingID", entry.BookingID, "Step", entry.Step, "ResourceID", entry.ResourceID, "Status", entry.Status)
+
+	err := a.sink.UpdateStatus(ctx, entry.BookingID, entry.Step, entry.ResourceID, entry.Status, entry.Note)
+	if errors.Is(err, ErrStuckCompensationNotFound) {
+		return NewBusinessError("手動対応待ち補償処理が見つかりません", "STUCK_COMPENSATION_NOT_FOUND")
+	}
+	if err != nil {
+		return NewServerError(fmt.Sprintf("手動対応待ち補償処理の更新に失敗しました: %s", err.Error()), "STUCK_COMPENSATION_UPDATE_FAILED")
+	}
+	return nil
+}
+
+// RecordStuckCompensationActivity ワークフロー用アダプター関数
+func RecordStuckCompensationActivity(ctx context.Context, entry StuckCompensation) error {
+	logger := NewTemporalLogger(ctx)
+	activity := NewStuckCompensationActivity(logger, defaultStuckCompensationSink)
+	return ToApplicationError(activity.RecordStuckCompensation(ctx, entry))
+}
+
+// UpdateStuckCompensationActivity ワークフロー用アダプター関数
+func UpdateStuckCompensationActivity(ctx context.Context, entry StuckCompensation) error {
+	logger := NewTemporalLogger(ctx)
+	activity := NewStuckCompensationActivity(logger, defaultStuckCompensationSink)
+	return ToApplicationError(activity.UpdateStuckCompensation(ctx, entry))
+}
@@ -0,0 +1,89 @@
+package activities
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - 記録した補償処理がpendingとして一覧に含まれる
+//   - 状態を更新すると一覧に最新の状態が反映される
+//
+// 異常系:
+//   - 記録されていない補償処理の状態を更新するとErrStuckCompensationNotFoundが返却される
+func TestStuckCompensationSink(t *testing.T) {
+	sinks := map[string]func(t *testing.T) StuckCompensationSink{
+		"インメモリ": func(t *testing.T) StuckCompensationSink {
+			return NewInMemoryStuckCompensationSink()
+		},
+		"ファイル": func(t *testing.T) StuckCompensationSink {
+			return NewFileStuckCompensationSink(filepath.Join(t.TempDir(), "stuck.jsonl"))
+		},
+	}
+	entry := StuckCompensation{
+		BookingID:  "booking-123",
+		Step:       "ホテルルーム補償",
+		ResourceID: "room-123",
+		Attempts:   3,
+		LastError:  "補償処理システムがダウンしています",
+	}
+
+	testcases := map[string]struct {
+		update         *StuckCompensation
+		expectedStatus StuckCompensationStatus
+		expectedNote   string
+		expectedErr    error
+	}{
+		"正常系: 記録した補償処理がpendingとして一覧に含まれる": {
+			expectedStatus: StuckCompensationPending,
+		},
+		"正常系: 状態を更新すると一覧に最新の状態が反映される": {
+			update: &StuckCompensation{
+				BookingID: "booking-123", Step: "ホテルルーム補償", ResourceID: "room-123",
+				Status: StuckCompensationManuallyResolved, Note: "電話でキャンセル済み",
+			},
+			expectedStatus: StuckCompensationManuallyResolved,
+			expectedNote:   "電話でキャンセル済み",
+		},
+		"異常系: 記録されていない補償処理を更新するとエラーが返却される": {
+			update: &StuckCompensation{
+				BookingID: "booking-999", Step: "ホテルルーム補償", ResourceID: "room-999",
+				Status: StuckCompensationRetried,
+			},
+			expectedStatus: StuckCompensationPending,
+			expectedErr:    ErrStuckCompensationNotFound,
+		},
+	}
+
+	for sinkName, newSink := range sinks {
+		for name, tc := range testcases {
+			t.Run(sinkName+"/"+name, func(t *testing.T) {
+				// given
+				ctx := context.Background()
+				sut := newSink(t)
+				assert.NoError(t, sut.Record(ctx, entry))
+
+				// when
+				var actualErr error
+				if tc.update != nil {
+					actualErr = sut.UpdateStatus(ctx, tc.update.BookingID, tc.update.Step, tc.update.ResourceID, tc.update.Status, tc.update.Note)
+				}
+				list, listErr := sut.List(ctx)
+
+				// then
+				assert.Equal(t, tc.expectedErr, actualErr)
+				assert.NoError(t, listErr)
+				if assert.Len(t, list, 1) {
+					assert.Equal(t, entry.Key(), list[0].Key())
+					assert.Equal(t, entry.LastError, list[0].LastError)
+					assert.Equal(t, tc.expectedStatus, list[0].Status)
+					assert.Equal(t, tc.expectedNote, list[0].Note)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// healthCheckTimeout readyzでTemporalへの接続を確認する時間の上限
+const healthCheckTimeout = 2 * time.Second
+
+// HealthChecker Temporalへの接続確認（client.Clientが実装する）
+type HealthChecker interface {
+	CheckHealth(ctx context.Context, request *client.CheckHealthRequest) (*client.CheckHealthResponse, error)
+}
+
+// Runner 管理サーバーが起動・停止するワーカー（worker.Workerが実装する）
+type Runner interface {
+	Start() error
+	Stop()
+}
+
+// Server ワーカーの管理用HTTPサーバー
+//   - GET /healthz: プロセスが応答できるか（生存確認）
+//   - GET /readyz: ワーカーが起動済みで停止処理中でも異常終了してもおらず、Temporalに接続できるか
+//   - POST /drain: ポーリングを止め、実行中のアクティビティの完了を待ってワーカーを停止する
+//   - Handleで追加したエンドポイント（/metricsなど）
+//
+// オーケストレーターはdrainを呼んでからプロセスの終了を待つことで、予約のステップを途中で止めずにワーカーを入れ替えられる
+type Server struct {
+	health HealthChecker
+	mux    *http.ServeMux
+
+	started   atomic.Bool
+	draining  atomic.Bool
+	drainOnce sync.Once
+	drain     chan struct{} // 停止が要求されたら閉じる
+
+	failure  atomic.Pointer[error]
+	failOnce sync.Once
+	failed   chan struct{} // ワーカーが致命的なエラーで停止したら閉じる
+}
+
+// NewServer 管理用HTTPサーバーを作成する
+func NewServer(health HealthChecker) *Server {
+	s := &Server{
+		health: health,
+		mux:    http.NewServeMux(),
+		drain:  make(chan struct{}),
+		failed: make(chan struct{}),
+	}
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+	s.mux.HandleFunc("GET /readyz", s.readyz)
+	s.mux.HandleFunc("POST /drain", s.drainWorker)
+	return s
+}
+
+// ServeHTTP http.Handlerの実装
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Handle 管理用のエンドポイントを追加する
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run ワーカーを起動し、割り込みシグナルか停止の要求を受けたらワーカーを停止する
+// ワーカーの停止はポーリングを止めてから、実行中のアクティビティの完了をワーカーの停止待ち時間まで待つ
+// ワーカーが致命的なエラーで停止した場合（Fail）はそのエラーを返す
+func (s *Server) Run(w Runner, interruptCh <-chan interface{}) error {
+	if err := w.Start(); err != nil {
+		return err
+	}
+	s.started.Store(true)
+
+	select {
+	case <-interruptCh:
+		log.Println("Interrupt received, draining worker...")
+	case <-s.drain:
+		log.Println("Drain requested, draining worker...")
+	case <-s.failed:
+		w.Stop()
+		return fmt.Errorf("worker failed: %w", *s.failure.Load())
+	}
+	s.Drain()
+	w.Stop()
+	log.Println("Worker drained")
+	return nil
+}
+
+// Fail ワーカーが致命的なエラーで停止したことを記録する（readyzは以降503を返し、Runはエラーを返す）
+// worker.OptionsのOnFatalErrorに設定する
+func (s *Server) Fail(err error) {
+	s.failOnce.Do(func() {
+		s.failure.Store(&err)
+		close(s.failed)
+	})
+}
+
+// Drain ワーカーの停止を要求する（readyzは以降503を返す）
+func (s *Server) Drain() {
+	s.drainOnce.Do(func() {
+		s.draining.Store(true)
+		close(s.drain)
+	})
+}
+
+// status エンドポイントのレスポンス
+type status struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// healthz プロセスが応答できれば常に200を返す
+func (s *Server) healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, status{Status: "ok"})
+}
+
+// readyz ワーカーが新しいタスクを処理できる状態かどうか
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	switch failure := s.failure.Load(); {
+	case failure != nil:
+		writeJSON(w, http.StatusServiceUnavailable, status{Status: "unavailable", Reason: "worker failed: " + (*failure).Error()})
+		return
+	case s.draining.Load():
+		writeJSON(w, http.StatusServiceUnavailable, status{Status: "unavailable", Reason: "worker is draining"})
+		return
+	case !s.started.Load():
+		writeJSON(w, http.StatusServiceUnavailable, status{Status: "unavailable", Reason: "worker is not started"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	if _, err := s.health.CheckHealth(ctx, &client.CheckHealthRequest{}); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, status{Status: "unavailable", Reason: "temporal is unreachable: " + err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, status{Status: "ok"})
+}
+
+// drainWorker ワーカーの停止を要求する
+// 停止は非同期に行うため、受け付けた時点で202を返す（完了はプロセスの終了で分かる）
+func (s *Server) drainWorker(w http.ResponseWriter, _ *http.Request) {
+	s.Drain()
+	writeJSON(w, http.StatusAccepted, status{Status: "draining"})
+}
+
+// writeJSON JSONのレスポンスを返す
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Unable to write response", err)
+	}
+}
@@ -0,0 +1,234 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/sdk/client"
+)
+
+// fakeHealthChecker 指定したエラーを返すTemporalへの接続確認
+type fakeHealthChecker struct {
+	err error
+}
+
+func (f *fakeHealthChecker) CheckHealth(context.Context, *client.CheckHealthRequest) (*client.CheckHealthResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &client.CheckHealthResponse{}, nil
+}
+
+// fakeRunner 起動と停止を記録するワーカー
+type fakeRunner struct {
+	startErr error
+	started  chan struct{}
+	stopped  bool
+}
+
+func newFakeRunner(startErr error) *fakeRunner {
+	return &fakeRunner{startErr: startErr, started: make(chan struct{})}
+}
+
+func (f *fakeRunner) Start() error {
+	if f.startErr != nil {
+		return f.startErr
+	}
+	close(f.started)
+	return nil
+}
+
+func (f *fakeRunner) Stop() { f.stopped = true }
+
+// testケース
+// 正常系:
+//   - healthzはワーカーの状態に関わらず200が返却される
+//   - ワーカーの起動前はreadyzで503が返却される
+//   - ワーカーが起動済みでTemporalに接続できる時、readyzで200が返却される
+//   - drainを呼ぶと202が返却され、readyzは503になり、ワーカーが停止する
+//   - drainを複数回呼んでも202が返却される
+//   - Handleで追加したエンドポイントが応答する
+//
+// 準異常系:
+//   - Temporalに接続できない時、readyzで503が返却される
+//   - ワーカーの起動に失敗した時、エラーが返却されreadyzは503のままになる
+//
+// 異常系:
+//   - ワーカーが致命的なエラーで停止した時、エラーが返却されreadyzは503になる
+func TestServer(t *testing.T) {
+	t.Run("正常系: healthzはワーカーの状態に関わらず200が返却される", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{err: errors.New("connection refused")})
+
+		// when
+		code, body := call(s, http.MethodGet, "/healthz")
+
+		// then
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"status":"ok"}`, body)
+	})
+
+	t.Run("正常系: ワーカーの起動前はreadyzで503が返却される", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+
+		// when
+		code, body := call(s, http.MethodGet, "/readyz")
+
+		// then
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.JSONEq(t, `{"status":"unavailable","reason":"worker is not started"}`, body)
+	})
+
+	t.Run("正常系: ワーカーが起動済みでTemporalに接続できる時、readyzで200が返却される", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+		runner := newFakeRunner(nil)
+		interruptCh := make(chan interface{})
+		done := runAsync(s, runner, interruptCh)
+		<-runner.started
+
+		// when
+		code, body := call(s, http.MethodGet, "/readyz")
+
+		// then
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"status":"ok"}`, body)
+
+		close(interruptCh)
+		assert.NoError(t, wait(t, done))
+		assert.True(t, runner.stopped)
+	})
+
+	t.Run("正常系: drainを呼ぶと202が返却され、readyzは503になり、ワーカーが停止する", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+		runner := newFakeRunner(nil)
+		done := runAsync(s, runner, make(chan interface{}))
+		<-runner.started
+
+		// when
+		code, body := call(s, http.MethodPost, "/drain")
+
+		// then
+		assert.Equal(t, http.StatusAccepted, code)
+		assert.JSONEq(t, `{"status":"draining"}`, body)
+		assert.NoError(t, wait(t, done))
+		assert.True(t, runner.stopped)
+
+		code, body = call(s, http.MethodGet, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.JSONEq(t, `{"status":"unavailable","reason":"worker is draining"}`, body)
+	})
+
+	t.Run("正常系: drainを複数回呼んでも202が返却される", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+
+		// when
+		first, _ := call(s, http.MethodPost, "/drain")
+		second, _ := call(s, http.MethodPost, "/drain")
+
+		// then
+		assert.Equal(t, http.StatusAccepted, first)
+		assert.Equal(t, http.StatusAccepted, second)
+	})
+
+	t.Run("正常系: Handleで追加したエンドポイントが応答する", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+		s.Handle("GET /metrics", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("hotel_booking_started_total 1\n"))
+		}))
+
+		// when
+		code, body := call(s, http.MethodGet, "/metrics")
+
+		// then
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hotel_booking_started_total 1\n", body)
+	})
+
+	t.Run("準異常系: Temporalに接続できない時、readyzで503が返却される", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{err: errors.New("connection refused")})
+		runner := newFakeRunner(nil)
+		interruptCh := make(chan interface{})
+		done := runAsync(s, runner, interruptCh)
+		<-runner.started
+
+		// when
+		code, body := call(s, http.MethodGet, "/readyz")
+
+		// then
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.JSONEq(t, `{"status":"unavailable","reason":"temporal is unreachable: connection refused"}`, body)
+
+		close(interruptCh)
+		assert.NoError(t, wait(t, done))
+	})
+
+	t.Run("準異常系: ワーカーの起動に失敗した時、エラーが返却されreadyzは503のままになる", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+		runner := newFakeRunner(errors.New("worker already started"))
+
+		// when
+		err := s.Run(runner, make(chan interface{}))
+
+		// then
+		assert.EqualError(t, err, "worker already started")
+		assert.False(t, runner.stopped)
+		code, _ := call(s, http.MethodGet, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	t.Run("異常系: ワーカーが致命的なエラーで停止した時、エラーが返却されreadyzは503になる", func(t *testing.T) {
+		// given
+		s := NewServer(&fakeHealthChecker{})
+		runner := newFakeRunner(nil)
+		done := runAsync(s, runner, make(chan interface{}))
+		<-runner.started
+
+		// when
+		s.Fail(errors.New("namespace not found"))
+
+		// then
+		assert.EqualError(t, wait(t, done), "worker failed: namespace not found")
+		assert.True(t, runner.stopped)
+		code, body := call(s, http.MethodGet, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.JSONEq(t, `{"status":"unavailable","reason":"worker failed: namespace not found"}`, body)
+	})
+}
+
+// call 管理用HTTPサーバーにリクエストを送り、ステータスコードとボディを返す
+func call(s *Server, method, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder.Code, recorder.Body.String()
+}
+
+// runAsync ワーカーの実行を別のゴルーチンで開始する
+func runAsync(s *Server, runner Runner, interruptCh <-chan interface{}) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- s.Run(runner, interruptCh) }()
+	return done
+}
+
+// wait ワーカーの停止を待つ
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop")
+		return nil
+	}
+}
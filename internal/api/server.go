@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+
+	"temporal-hotel-sample/internal/bookingclient"
+	"temporal-hotel-sample/internal/workflows"
+)
+
+// maxBodyBytes リクエストボディの上限
+const maxBodyBytes = 1 << 20
+
+// BookingService HTTP APIが使う予約の操作（bookingclient.Clientが実装する）
+type BookingService interface {
+	Start(ctx context.Context, request workflows.BookingRequest) (*bookingclient.Submission, error)
+	Describe(ctx context.Context, bookingID string) (*bookingclient.BookingSummary, error)
+	Status(ctx context.Context, bookingID string) (*workflows.BookingStatus, error)
+	Result(ctx context.Context, bookingID string) (*workflows.BookingResult, error)
+	Cancel(ctx context.Context, bookingID string, request workflows.CancellationRequest) error
+	Modify(ctx context.Context, bookingID string, request workflows.ModificationRequest) (*workflows.BookingResult, error)
+}
+
+var _ BookingService = (*bookingclient.Client)(nil)
+
+// Server 予約SagaのHTTP API
+//   - POST /bookings: 予約Sagaを開始する（予約IDをワークフローIDとする）。同じ内容の再送信は既存の予約を返す
+//   - GET /bookings/{id}: 実行中は予約状況、終了後は予約結果を返す
+//   - POST /bookings/{id}/cancel: 予約のキャンセルを要求する
+//   - PATCH /bookings/{id}: 予約を変更し、変更後の予約結果を返す
+type Server struct {
+	service BookingService
+	mux     *http.ServeMux
+}
+
+// NewServer HTTP APIを作成する
+func NewServer(service BookingService) *Server {
+	s := &Server{service: service, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /bookings", s.startBooking)
+	s.mux.HandleFunc("GET /bookings/{id}", s.getBooking)
+	s.mux.HandleFunc("POST /bookings/{id}/cancel", s.cancelBooking)
+	s.mux.HandleFunc("PATCH /bookings/{id}", s.modifyBooking)
+	return s
+}
+
+// ServeHTTP http.Handlerの実装
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// BookingResponse 予約の照会結果
+// 実行中の予約はStatus、終了した予約はResultを返す
+type BookingResponse struct {
+	BookingID      string                   `json:"booking_id"`
+	WorkflowStatus string                   `json:"workflow_status"` // ワークフローの実行状態（Running, Completedなど）
+	Status         *workflows.BookingStatus `json:"status,omitempty"`
+	Result         *workflows.BookingResult `json:"result,omitempty"`
+}
+
+// CancelResponse 予約キャンセルの受付結果
+type CancelResponse struct {
+	BookingID string `json:"booking_id"`
+	Message   string `json:"message"`
+}
+
+// ErrorResponse エラーの内容
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// startBooking 予約Sagaを開始する
+// 新しく開始した場合は202、同じ内容の予約が既に開始されていた場合は200で現在の予約を返す
+func (s *Server) startBooking(w http.ResponseWriter, r *http.Request) {
+	var request workflows.BookingRequest
+	if err := decodeBody(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := request.Validate(); err != nil {
+		writeError(w, fmt.Errorf("%w: %s", bookingclient.ErrInvalidRequest, err.Error()))
+		return
+	}
+	submission, err := s.service.Start(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/bookings/"+submission.BookingID)
+	if !submission.Duplicate {
+		writeJSON(w, http.StatusAccepted, submission)
+		return
+	}
+	response, err := s.booking(r.Context(), submission.BookingID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// getBooking 予約を照会する
+func (s *Server) getBooking(w http.ResponseWriter, r *http.Request) {
+	response, err := s.booking(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// booking 実行中は予約状況を問い合わせ、終了後は予約結果を返す
+func (s *Server) booking(ctx context.Context, bookingID string) (*BookingResponse, error) {
+	summary, err := s.service.Describe(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	response := &BookingResponse{BookingID: bookingID, WorkflowStatus: summary.Status}
+	switch {
+	case summary.Running():
+		response.Status, err = s.service.Status(ctx, bookingID)
+	case summary.Completed():
+		response.Result, err = s.service.Result(ctx, bookingID)
+	default:
+		// 失敗・タイムアウト・強制終了した予約Sagaは予約結果を返さないため、終了理由をエラーとして返す
+		if _, err = s.service.Result(ctx, bookingID); err == nil {
+			err = fmt.Errorf("booking %s closed with status %s", bookingID, summary.Status)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// cancelBooking 予約のキャンセルを要求する
+// キャンセルはSagaの安全なタイミングで処理されるため、受け付けた時点で202を返す
+func (s *Server) cancelBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID := r.PathValue("id")
+	var request workflows.CancellationRequest
+	if r.ContentLength != 0 {
+		if err := decodeBody(w, r, &request); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if err := s.service.Cancel(r.Context(), bookingID, request); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, CancelResponse{BookingID: bookingID, Message: "キャンセルを受け付けました"})
+}
+
+// modifyBooking 予約を変更し、変更後の予約結果を返す
+func (s *Server) modifyBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID := r.PathValue("id")
+	var request workflows.ModificationRequest
+	if err := decodeBody(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.service.Modify(r.Context(), bookingID, request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// decodeBody JSONのリクエストボディを読み込む（未知のフィールドは受け付けない）
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid JSON body: %s", bookingclient.ErrInvalidRequest, err.Error())
+	}
+	return nil
+}
+
+// writeError エラーをHTTPステータスに対応付けて返す
+//   - 400: リクエストのバリデーションエラー
+//   - 404: 予約が存在しない、または終了している
+//   - 409: 同じ予約IDで異なる内容の予約Sagaが既に存在する、または予約の状態により変更を受け付けられない
+//   - 410: 予約Sagaが強制終了・キャンセルされ、予約結果がない
+//   - 422: 予約変更の内容が不正、または予約し直しに失敗して元の予約を維持した
+//   - 500: 予約Sagaが失敗・タイムアウトし、予約結果がない
+func writeError(w http.ResponseWriter, err error) {
+	var (
+		alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+		notFound       *serviceerror.NotFound
+		workflowErr    *temporal.WorkflowExecutionError
+		terminatedErr  *temporal.TerminatedError
+		canceledErr    *temporal.CanceledError
+		applicationErr *temporal.ApplicationError
+	)
+	switch {
+	case errors.Is(err, bookingclient.ErrInvalidRequest):
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Code: "invalid_request", Message: err.Error()})
+	case errors.Is(err, bookingclient.ErrBookingConflict), errors.As(err, &alreadyStarted):
+		writeJSON(w, http.StatusConflict, ErrorResponse{Code: "booking_conflict", Message: err.Error()})
+	case errors.As(err, &notFound):
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Code: "booking_not_found", Message: err.Error()})
+	case errors.As(err, &workflowErr) && (errors.As(err, &terminatedErr) || errors.As(err, &canceledErr)):
+		writeJSON(w, http.StatusGone, ErrorResponse{Code: "booking_terminated", Message: err.Error()})
+	case errors.As(err, &workflowErr):
+		log.Println("Booking workflow failed", err)
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Code: "booking_failed", Message: err.Error()})
+	case errors.As(err, &applicationErr) && applicationErr.Type() == workflows.ModificationConflictErrorType:
+		writeJSON(w, http.StatusConflict, ErrorResponse{Code: "modification_conflict", Message: applicationErr.Error()})
+	case errors.As(err, &applicationErr) && applicationErr.Type() == workflows.InvalidModificationErrorType:
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Code: "modification_rejected", Message: applicationErr.Error()})
+	case errors.As(err, &applicationErr) && applicationErr.Type() == workflows.ModificationFailedErrorType:
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Code: "modification_failed", Message: applicationErr.Error()})
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, ErrorResponse{Code: "timeout", Message: err.Error()})
+	default:
+		log.Println("Unexpected API error", err)
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Code: "internal_error", Message: "internal server error"})
+	}
+}
+
+// writeJSON JSONのレスポンスを返す
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Unable to write response", err)
+	}
+}
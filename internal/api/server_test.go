@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-hotel-sample/internal/bookingclient"
+	"temporal-hotel-sample/internal/workflows"
+)
+
+// testBookingJSON テスト用の予約リクエスト
+func testBookingJSON(bookingID string) string {
+	return `{"booking_id":"` + bookingID + `","user_id":"user-001","hotel":{"hotel_id":"hotel-001","check_in":"2026-11-01T15:00:00Z","check_out":"2026-11-03T10:00:00Z"}}`
+}
+
+// apiCall 順に呼び出すAPIと期待するレスポンス
+type apiCall struct {
+	method string
+	path   string
+	body   string
+
+	expectedStatus int
+	expectedCode   string                          // エラーのコード
+	expectedBody   func(t *testing.T, body []byte) // レスポンスボディの検証
+}
+
+// testケース
+// 正常系:
+//   - 予約を開始した時、202と照会先を返し、照会すると終了した予約Sagaの結果を返す
+//   - キャンセルを要求した時、202を返し、照会するとお客様のキャンセルにより取り消された結果を返す
+//   - 予約を変更した時、変更後の予約結果を返す
+//
+// 準異常系:
+//   - 必須項目のない予約の時、予約Sagaを開始せずに400を返す
+//   - 未知のフィールドを含む予約の時、400を返す
+//   - 同じ内容の予約を再送信した時、予約Sagaを開始せずに既存の予約を返す
+//   - 同じ予約IDで異なる内容の予約を送信した時、409を返す
+//   - 変更内容のない予約変更の時、ワークフローに拒否されて422を返す
+//
+// 異常系:
+//   - 存在しない予約を照会・キャンセルした時、404を返す
+func TestServer(t *testing.T) {
+	tests := map[string]struct {
+		calls []apiCall
+	}{
+		"正常系 - 予約を開始して結果を照会する": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-001"), expectedStatus: http.StatusAccepted,
+					expectedBody: func(t *testing.T, body []byte) {
+						var response bookingclient.Submission
+						assert.NoError(t, json.Unmarshal(body, &response))
+						assert.Equal(t, "booking-001", response.BookingID)
+						assert.False(t, response.Duplicate)
+					}},
+				{method: http.MethodGet, path: "/bookings/booking-001", expectedStatus: http.StatusOK,
+					expectedBody: func(t *testing.T, body []byte) {
+						var response BookingResponse
+						assert.NoError(t, json.Unmarshal(body, &response))
+						assert.Equal(t, "Completed", response.WorkflowStatus)
+						if assert.NotNil(t, response.Result) {
+							assert.True(t, response.Result.Success)
+							assert.NotNil(t, response.Result.HotelResult)
+						}
+					}},
+			},
+		},
+		"正常系 - キャンセルを要求する": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-002"), expectedStatus: http.StatusAccepted},
+				{method: http.MethodPost, path: "/bookings/booking-002/cancel", body: `{"reason":"予定変更"}`, expectedStatus: http.StatusAccepted},
+				{method: http.MethodGet, path: "/bookings/booking-002", expectedStatus: http.StatusOK,
+					expectedBody: func(t *testing.T, body []byte) {
+						var response BookingResponse
+						assert.NoError(t, json.Unmarshal(body, &response))
+						if assert.NotNil(t, response.Result) {
+							assert.True(t, response.Result.CancelledByCustomer)
+							assert.Equal(t, "予定変更", response.Result.CancellationReason)
+						}
+					}},
+			},
+		},
+		"正常系 - 予約を変更する": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-003"), expectedStatus: http.StatusAccepted},
+				{method: http.MethodPatch, path: "/bookings/booking-003",
+					body:           `{"hotel":{"hotel_id":"hotel-001","check_in":"2026-11-01T15:00:00Z","check_out":"2026-11-03T10:00:00Z","room_type":"deluxe"},"reason":"アップグレード"}`,
+					expectedStatus: http.StatusOK,
+					expectedBody: func(t *testing.T, body []byte) {
+						var result workflows.BookingResult
+						assert.NoError(t, json.Unmarshal(body, &result))
+						assert.Equal(t, 1, result.Revision)
+						if assert.NotNil(t, result.HotelResult) {
+							assert.Equal(t, "deluxe", result.HotelResult.RoomType)
+						}
+					}},
+			},
+		},
+		"準異常系 - 必須項目のない予約": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: `{"booking_id":"booking-004","hotel":{"hotel_id":"hotel-001"}}`,
+					expectedStatus: http.StatusBadRequest, expectedCode: "invalid_request"},
+				{method: http.MethodGet, path: "/bookings/booking-004", expectedStatus: http.StatusNotFound, expectedCode: "booking_not_found"},
+			},
+		},
+		"準異常系 - 未知のフィールドを含む予約": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: `{"booking_id":"booking-005","guests":2}`,
+					expectedStatus: http.StatusBadRequest, expectedCode: "invalid_request"},
+			},
+		},
+		"準異常系 - 同じ内容の予約を再送信する": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-006"), expectedStatus: http.StatusAccepted},
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-006"), expectedStatus: http.StatusOK,
+					expectedBody: func(t *testing.T, body []byte) {
+						var response BookingResponse
+						assert.NoError(t, json.Unmarshal(body, &response))
+						assert.Equal(t, "booking-006", response.BookingID)
+						if assert.NotNil(t, response.Result) {
+							assert.True(t, response.Result.Success)
+						}
+					}},
+			},
+		},
+		"準異常系 - 同じ予約IDで異なる内容の予約を送信する": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-008"), expectedStatus: http.StatusAccepted},
+				{method: http.MethodPost, path: "/bookings", body: strings.Replace(testBookingJSON("booking-008"), "user-001", "user-002", 1),
+					expectedStatus: http.StatusConflict, expectedCode: "booking_conflict"},
+			},
+		},
+		"準異常系 - 変更内容のない予約変更": {
+			calls: []apiCall{
+				{method: http.MethodPost, path: "/bookings", body: testBookingJSON("booking-007"), expectedStatus: http.StatusAccepted},
+				{method: http.MethodPatch, path: "/bookings/booking-007", body: `{"reason":"理由のみ"}`,
+					expectedStatus: http.StatusUnprocessableEntity, expectedCode: "modification_rejected"},
+			},
+		},
+		"異常系 - 存在しない予約": {
+			calls: []apiCall{
+				{method: http.MethodGet, path: "/bookings/booking-999", expectedStatus: http.StatusNotFound, expectedCode: "booking_not_found"},
+				{method: http.MethodPost, path: "/bookings/booking-999/cancel", expectedStatus: http.StatusNotFound, expectedCode: "booking_not_found"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			server := NewServer(newTestEnvService())
+
+			for _, call := range tt.calls {
+				// when
+				request := httptest.NewRequest(call.method, call.path, strings.NewReader(call.body))
+				recorder := httptest.NewRecorder()
+				server.ServeHTTP(recorder, request)
+
+				// then
+				body := recorder.Body.Bytes()
+				if !assert.Equal(t, call.expectedStatus, recorder.Code, "%s %s: %s", call.method, call.path, string(body)) {
+					return
+				}
+				assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+				if call.expectedCode != "" {
+					var response ErrorResponse
+					assert.NoError(t, json.Unmarshal(body, &response))
+					assert.Equal(t, call.expectedCode, response.Code)
+				}
+				if call.expectedBody != nil {
+					call.expectedBody(t, body)
+				}
+			}
+		})
+	}
+}
+
+// closedBookingService 予約結果を返さずに終了した予約Sagaを返すBookingService
+type closedBookingService struct {
+	BookingService
+	status string
+	err    error
+}
+
+func (s *closedBookingService) Describe(_ context.Context, bookingID string) (*bookingclient.BookingSummary, error) {
+	return &bookingclient.BookingSummary{BookingID: bookingID, Status: s.status}, nil
+}
+
+func (s *closedBookingService) Result(context.Context, string) (*workflows.BookingResult, error) {
+	return nil, s.err
+}
+
+// closedWorkflowError テスト用ワークフロー環境でワークフローを実行し、クライアントが受け取るエラーを返す
+func closedWorkflowError(workflowFunc interface{}, cancel bool) error {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(workflowFunc)
+	if cancel {
+		env.RegisterDelayedCallback(env.CancelWorkflow, 0)
+	}
+	env.ExecuteWorkflow(workflowFunc)
+	return env.GetWorkflowError()
+}
+
+func failingWorkflow(ctx workflow.Context) error {
+	return temporal.NewApplicationError("unexpected failure", "UnexpectedError")
+}
+
+func waitingWorkflow(ctx workflow.Context) error {
+	return workflow.Sleep(ctx, time.Hour)
+}
+
+// testケース
+// 準異常系:
+//   - キャンセルされた予約Sagaを照会した時、410を返す
+//
+// 異常系:
+//   - 失敗した予約Sagaを照会した時、予約結果の代わりに500と失敗の理由を返す
+func TestServer_ClosedWorkflow(t *testing.T) {
+	tests := map[string]struct {
+		status string
+		err    error
+
+		expectedStatus int
+		expectedCode   string
+	}{
+		"準異常系 - キャンセルされた予約Saga": {
+			status:         "Canceled",
+			err:            closedWorkflowError(waitingWorkflow, true),
+			expectedStatus: http.StatusGone,
+			expectedCode:   "booking_terminated",
+		},
+		"異常系 - 失敗した予約Saga": {
+			status:         "Failed",
+			err:            closedWorkflowError(failingWorkflow, false),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "booking_failed",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			server := NewServer(&closedBookingService{status: tt.status, err: tt.err})
+
+			// when
+			request := httptest.NewRequest(http.MethodGet, "/bookings/booking-closed", nil)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, request)
+
+			// then
+			assert.Equal(t, tt.expectedStatus, recorder.Code, recorder.Body.String())
+			var response ErrorResponse
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+// testケース
+// 準異常系:
+//   - 予約の状態により変更を受け付けられない時、409を返す
+//   - 変更内容が不正な時、422を返す
+//   - 予約し直しに失敗した時、422を返す
+//
+// 異常系:
+//   - 予約変更以外のApplicationErrorの時、500を返す
+func TestWriteError_ApplicationError(t *testing.T) {
+	tests := map[string]struct {
+		err error
+
+		expectedStatus int
+		expectedCode   string
+	}{
+		"準異常系 - 予約の状態により変更を受け付けられない": {
+			err:            temporal.NewApplicationError("another modification is in progress", workflows.ModificationConflictErrorType),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "modification_conflict",
+		},
+		"準異常系 - 変更内容が不正": {
+			err:            temporal.NewApplicationError("Dinner.DateTime must be within the stay", workflows.InvalidModificationErrorType),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "modification_rejected",
+		},
+		"準異常系 - 予約し直しに失敗": {
+			err:            temporal.NewApplicationError("駐車場予約に失敗", workflows.ModificationFailedErrorType),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "modification_failed",
+		},
+		"異常系 - 予約変更以外のApplicationError": {
+			err:            errors.Join(errors.New("modify booking booking-001"), temporal.NewApplicationError("unexpected", "UnexpectedError")),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			recorder := httptest.NewRecorder()
+			writeError(recorder, tt.err)
+
+			// then
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			var response ErrorResponse
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
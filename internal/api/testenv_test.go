@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/bookingclient"
+	"temporal-hotel-sample/internal/inventory"
+	"temporal-hotel-sample/internal/workflows"
+)
+
+// testEnvService テスト用ワークフロー環境で予約Sagaを実行するBookingService
+// 予約Sagaは照会・変更されるまで実行せず、それまでに受け付けたキャンセルは開始直後のシグナルとして届ける
+// 実行した予約Sagaはチェックアウトまでの待機を含めて終了する
+type testEnvService struct {
+	suite    testsuite.WorkflowTestSuite
+	bookings map[string]*testBooking
+}
+
+// testBooking テスト用ワークフロー環境の予約Saga
+type testBooking struct {
+	env      *testsuite.TestWorkflowEnvironment
+	request  workflows.BookingRequest
+	executed bool
+}
+
+// newTestEnvService 空の客室在庫でBookingServiceを作成する
+func newTestEnvService() *testEnvService {
+	activities.SetHotelInventory(inventory.NewHotelInventory(inventory.DefaultHotels()))
+	return &testEnvService{bookings: map[string]*testBooking{}}
+}
+
+// Start 予約Sagaを登録する
+// bookingclient.Clientと同じく、同じ予約IDの再送信は内容が同じなら既存の予約を返し、異なればErrBookingConflictを返す
+func (s *testEnvService) Start(_ context.Context, request workflows.BookingRequest) (*bookingclient.Submission, error) {
+	if existing, ok := s.bookings[request.BookingID]; ok {
+		if !reflect.DeepEqual(existing.request, request) {
+			return nil, fmt.Errorf("%w: %s", bookingclient.ErrBookingConflict, request.BookingID)
+		}
+		return &bookingclient.Submission{BookingID: request.BookingID, RunID: "run-" + request.BookingID, Duplicate: true}, nil
+	}
+	env := s.suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(workflows.HotelBookingSaga)
+	env.RegisterActivity(activities.HotelRoomBookingActivity)
+	env.RegisterActivity(activities.DinnerFoodBookingActivity)
+	env.RegisterActivity(activities.ParkingBookingActivity)
+	env.RegisterActivity(activities.CompensateHotelRoomActivity)
+	env.RegisterActivity(activities.CompensateDinnerFoodActivity)
+	env.RegisterActivity(activities.CompensateParkingActivity)
+	env.RegisterActivity(activities.RecordStuckCompensationActivity)
+	env.RegisterActivity(activities.UpdateStuckCompensationActivity)
+	env.RegisterActivity(activities.SendCheckInReminderActivity)
+	env.SetStartTime(request.Hotel.CheckIn.AddDate(0, 0, -7))
+	s.bookings[request.BookingID] = &testBooking{env: env, request: request}
+	return &bookingclient.Submission{BookingID: request.BookingID, RunID: "run-" + request.BookingID}, nil
+}
+
+// Describe 予約Sagaを実行し、終了した状態を返す
+func (s *testEnvService) Describe(_ context.Context, bookingID string) (*bookingclient.BookingSummary, error) {
+	booking, err := s.executed(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	status := "Completed"
+	if booking.env.GetWorkflowError() != nil {
+		status = "Failed"
+	}
+	return &bookingclient.BookingSummary{BookingID: bookingID, RunID: "run-" + bookingID, Status: status}, nil
+}
+
+// Status 予約Sagaを実行し、予約状況を問い合わせる
+func (s *testEnvService) Status(_ context.Context, bookingID string) (*workflows.BookingStatus, error) {
+	booking, err := s.executed(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := booking.env.QueryWorkflow(workflows.BookingStatusQuery)
+	if err != nil {
+		return nil, err
+	}
+	var status workflows.BookingStatus
+	if err := value.Get(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Result 予約Sagaを実行し、予約結果を返す
+func (s *testEnvService) Result(_ context.Context, bookingID string) (*workflows.BookingResult, error) {
+	booking, err := s.executed(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	var result workflows.BookingResult
+	if err := booking.env.GetWorkflowResult(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Cancel 予約Sagaの開始直後にキャンセルのシグナルを届ける
+func (s *testEnvService) Cancel(_ context.Context, bookingID string, request workflows.CancellationRequest) error {
+	booking, err := s.pending(bookingID)
+	if err != nil {
+		return err
+	}
+	booking.env.RegisterDelayedCallback(func() {
+		booking.env.SignalWorkflow(workflows.CancelBookingSignal, request)
+	}, 0)
+	return nil
+}
+
+// Modify 予約の確定後に予約変更のアップデートを送り、予約Sagaを実行して変更の結果を返す
+// 拒否または失敗した変更は、Temporalクライアントと同じくワークフローが返したApplicationErrorとして返す
+func (s *testEnvService) Modify(_ context.Context, bookingID string, request workflows.ModificationRequest) (*workflows.BookingResult, error) {
+	booking, err := s.pending(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	callback := &updateCallback{}
+	booking.env.RegisterDelayedCallback(func() {
+		booking.env.UpdateWorkflow(workflows.ModifyBookingUpdate, "modify-"+bookingID, callback, request)
+	}, time.Hour)
+	if _, err := s.executed(bookingID); err != nil {
+		return nil, err
+	}
+	if callback.err != nil {
+		var applicationErr *temporal.ApplicationError
+		if errors.As(callback.err, &applicationErr) {
+			return nil, applicationErr
+		}
+		return nil, temporal.NewApplicationError(callback.err.Error(), "")
+	}
+	result, ok := callback.result.(*workflows.BookingResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected update result %T", callback.result)
+	}
+	return result, nil
+}
+
+// pending 実行前の予約Sagaを返す（実行済みの予約Sagaにはシグナルを送れない）
+func (s *testEnvService) pending(bookingID string) (*testBooking, error) {
+	booking, ok := s.bookings[bookingID]
+	if !ok {
+		return nil, serviceerror.NewNotFound(fmt.Sprintf("workflow not found for ID: %s", bookingID))
+	}
+	if booking.executed {
+		return nil, serviceerror.NewNotFound("workflow execution already completed")
+	}
+	return booking, nil
+}
+
+// executed 予約Sagaを実行していなければ実行する
+func (s *testEnvService) executed(bookingID string) (*testBooking, error) {
+	booking, ok := s.bookings[bookingID]
+	if !ok {
+		return nil, serviceerror.NewNotFound(fmt.Sprintf("workflow not found for ID: %s", bookingID))
+	}
+	if !booking.executed {
+		booking.executed = true
+		booking.env.ExecuteWorkflow(workflows.HotelBookingSaga, booking.request)
+	}
+	return booking, nil
+}
+
+// updateCallback 予約変更のアップデートの結果（拒否された場合はerrに拒否理由を記録する）
+type updateCallback struct {
+	result interface{}
+	err    error
+}
+
+func (c *updateCallback) Accept()          {}
+func (c *updateCallback) Reject(err error) { c.err = err }
+func (c *updateCallback) Complete(success interface{}, err error) {
+	c.result, c.err = success, err
+}
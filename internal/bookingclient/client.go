@@ -0,0 +1,244 @@
+package bookingclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+
+	"temporal-hotel-sample/internal/workflows"
+)
+
+var (
+	// ErrInvalidRequest 予約リクエストのバリデーションエラー
+	ErrInvalidRequest = errors.New("invalid booking request")
+	// ErrBookingConflict 同じ予約IDで異なる内容の予約Sagaが既に開始されている
+	ErrBookingConflict = errors.New("booking ID is already used by a different request")
+)
+
+// DefaultListLimit 予約一覧の既定の取得件数
+const DefaultListLimit = 20
+
+// bookingWorkflowType 予約一覧で検索するワークフローの種類
+const bookingWorkflowType = "HotelBookingSaga"
+
+// Client ホテル予約Sagaの開始・問い合わせ・シグナル送信を行うクライアント
+// 予約IDをワークフローIDとして扱う
+type Client struct {
+	temporal  client.Client
+	taskQueue string
+}
+
+// New Temporalクライアントから予約クライアントを作成する
+// taskQueueにはワーカーがポーリングするタスクキュー（worker.task_queue）を指定する
+func New(c client.Client, taskQueue string) *Client {
+	return &Client{temporal: c, taskQueue: taskQueue}
+}
+
+// Submission 予約Sagaの開始結果
+type Submission struct {
+	BookingID string `json:"booking_id"`
+	RunID     string `json:"run_id"`
+	Duplicate bool   `json:"duplicate,omitempty"` // 同じ内容の予約が既に開始されていた
+}
+
+// Start 予約IDをワークフローIDとして予約Sagaを開始する
+// 同じ予約IDは終了後も再利用しない。同じ内容の再送信は既存の予約Sagaを返し、異なる内容の場合はErrBookingConflictを返す
+// ワーカーで失敗させるより早く気付けるよう、開始前にリクエストを検証する
+func (c *Client) Start(ctx context.Context, request workflows.BookingRequest) (*Submission, error) {
+	if err := request.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRequest, err.Error())
+	}
+	hash, err := workflows.RequestHash(request)
+	if err != nil {
+		return nil, err
+	}
+	options := client.StartWorkflowOptions{
+		ID:                       request.BookingID,
+		TaskQueue:                c.taskQueue,
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+		Memo:                     map[string]interface{}{workflows.MemoRequestHash: hash},
+		// 既存の予約Sagaを黙って返さず、内容を照合する
+		WorkflowExecutionErrorWhenAlreadyStarted: true,
+	}
+	run, err := c.temporal.ExecuteWorkflow(ctx, options, workflows.HotelBookingSaga, request)
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if errors.As(err, &alreadyStarted) {
+		return c.existingSubmission(ctx, request.BookingID, hash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start booking %s: %w", request.BookingID, err)
+	}
+	return &Submission{BookingID: run.GetID(), RunID: run.GetRunID()}, nil
+}
+
+// existingSubmission 既に開始されている予約Sagaが同じ内容のリクエストで開始されたかを照合する
+func (c *Client) existingSubmission(ctx context.Context, bookingID, hash string) (*Submission, error) {
+	response, err := c.temporal.DescribeWorkflowExecution(ctx, bookingID, "")
+	if err != nil {
+		return nil, fmt.Errorf("describe existing booking %s: %w", bookingID, err)
+	}
+	info := response.GetWorkflowExecutionInfo()
+	existing, err := memoRequestHashOf(info)
+	if err != nil {
+		return nil, fmt.Errorf("describe existing booking %s: %w", bookingID, err)
+	}
+	if existing != hash {
+		return nil, fmt.Errorf("%w: %s", ErrBookingConflict, bookingID)
+	}
+	return &Submission{BookingID: bookingID, RunID: info.GetExecution().GetRunId(), Duplicate: true}, nil
+}
+
+// Result 予約Sagaの終了を待って結果を返す
+// 予約が確定した場合はチェックアウトまで終了しないため、確定を待つ場合はWatchを使う
+func (c *Client) Result(ctx context.Context, bookingID string) (*workflows.BookingResult, error) {
+	var result workflows.BookingResult
+	if err := c.temporal.GetWorkflow(ctx, bookingID, "").Get(ctx, &result); err != nil {
+		return nil, fmt.Errorf("get result of booking %s: %w", bookingID, err)
+	}
+	return &result, nil
+}
+
+// Status 予約Sagaの現在の状況を問い合わせる
+func (c *Client) Status(ctx context.Context, bookingID string) (*workflows.BookingStatus, error) {
+	value, err := c.temporal.QueryWorkflow(ctx, bookingID, "", workflows.BookingStatusQuery)
+	if err != nil {
+		return nil, fmt.Errorf("query status of booking %s: %w", bookingID, err)
+	}
+	var status workflows.BookingStatus
+	if err := value.Get(&status); err != nil {
+		return nil, fmt.Errorf("decode status of booking %s: %w", bookingID, err)
+	}
+	return &status, nil
+}
+
+// Watch 予約Sagaの状況を一定間隔で問い合わせ、フェーズが変わるたびにonChangeを呼び出す
+// untilのいずれかのフェーズに達するか、ワークフローが終了するまで待つ（untilが空の場合は終了まで待つ）
+func (c *Client) Watch(ctx context.Context, bookingID string, interval time.Duration, until []workflows.BookingPhase, onChange func(workflows.BookingStatus)) (*workflows.BookingStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last workflows.BookingPhase
+	for {
+		status, err := c.Status(ctx, bookingID)
+		if err != nil {
+			return nil, err
+		}
+		if status.Phase != last {
+			last = status.Phase
+			onChange(*status)
+		}
+		if status.Phase == workflows.PhaseDone || slices.Contains(until, status.Phase) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel お客様による予約キャンセルのシグナルを送る
+// キャンセルはSagaの安全なタイミングで処理されるため、結果はStatusかResultで確認する
+func (c *Client) Cancel(ctx context.Context, bookingID string, request workflows.CancellationRequest) error {
+	if err := c.temporal.SignalWorkflow(ctx, bookingID, "", workflows.CancelBookingSignal, request); err != nil {
+		return fmt.Errorf("cancel booking %s: %w", bookingID, err)
+	}
+	return nil
+}
+
+// Modify 予約変更のアップデートを送り、変更の完了を待って変更後の予約結果を返す
+func (c *Client) Modify(ctx context.Context, bookingID string, request workflows.ModificationRequest) (*workflows.BookingResult, error) {
+	handle, err := c.temporal.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   bookingID,
+		UpdateName:   workflows.ModifyBookingUpdate,
+		Args:         []interface{}{request},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("modify booking %s: %w", bookingID, err)
+	}
+	var result workflows.BookingResult
+	if err := handle.Get(ctx, &result); err != nil {
+		return nil, fmt.Errorf("modify booking %s: %w", bookingID, err)
+	}
+	return &result, nil
+}
+
+// BookingSummary 予約一覧の1件
+type BookingSummary struct {
+	BookingID string    `json:"booking_id"`
+	RunID     string    `json:"run_id"`
+	Status    string    `json:"status"` // ワークフローの実行状態（Running, Completedなど）
+	StartTime time.Time `json:"start_time"`
+	CloseTime time.Time `json:"close_time,omitempty"`
+}
+
+// Running 予約Sagaが実行中かどうか
+func (s BookingSummary) Running() bool {
+	return s.Status == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING.String()
+}
+
+// Completed 予約Sagaが結果を返して終了したかどうか
+func (s BookingSummary) Completed() bool {
+	return s.Status == enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED.String()
+}
+
+// Describe 予約Sagaの実行状態を返す（終了を待たない）
+func (c *Client) Describe(ctx context.Context, bookingID string) (*BookingSummary, error) {
+	response, err := c.temporal.DescribeWorkflowExecution(ctx, bookingID, "")
+	if err != nil {
+		return nil, fmt.Errorf("describe booking %s: %w", bookingID, err)
+	}
+	summary := newBookingSummary(response.GetWorkflowExecutionInfo())
+	return &summary, nil
+}
+
+// List 最近開始した予約の一覧を新しい順に返す
+func (c *Client) List(ctx context.Context, limit int) ([]BookingSummary, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	response, err := c.temporal.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		PageSize: int32(limit),
+		Query:    fmt.Sprintf("WorkflowType = '%s' ORDER BY StartTime DESC", bookingWorkflowType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list bookings: %w", err)
+	}
+
+	summaries := []BookingSummary{}
+	for _, execution := range response.GetExecutions() {
+		if len(summaries) == limit {
+			break
+		}
+		summaries = append(summaries, newBookingSummary(execution))
+	}
+	return summaries, nil
+}
+
+// newBookingSummary ワークフローの実行情報から予約一覧の1件を作成する
+func newBookingSummary(execution *workflowpb.WorkflowExecutionInfo) BookingSummary {
+	summary := BookingSummary{
+		BookingID: execution.GetExecution().GetWorkflowId(),
+		RunID:     execution.GetExecution().GetRunId(),
+		Status:    execution.GetStatus().String(),
+	}
+	if execution.GetStartTime() != nil {
+		summary.StartTime = execution.GetStartTime().AsTime()
+	}
+	if execution.GetCloseTime() != nil {
+		summary.CloseTime = execution.GetCloseTime().AsTime()
+	}
+	return summary
+}
@@ -0,0 +1,284 @@
+package bookingclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+
+	"temporal-hotel-sample/internal/workflows"
+)
+
+// testTaskQueue ワーカー設定で指定したタスクキュー（既定とは異なる値で、指定したキューに開始されることを確認する）
+const testTaskQueue = "HOTEL_BOOKING_CANARY"
+
+// テスト用の予約リクエスト
+func testBookingRequest() workflows.BookingRequest {
+	return workflows.BookingRequest{
+		BookingID: "booking-001",
+		UserID:    "user-001",
+		Hotel: workflows.HotelRequest{
+			HotelID:  "hotel-001",
+			CheckIn:  time.Date(2026, 11, 1, 15, 0, 0, 0, time.UTC),
+			CheckOut: time.Date(2026, 11, 3, 10, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+// statusValue 問い合わせ結果として予約状況を返す
+func statusValue(t *testing.T, status workflows.BookingStatus) *mocks.Value {
+	value := mocks.NewEncodedValue(t)
+	value.On("Get", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*workflows.BookingStatus) = status
+	}).Return(nil).Once()
+	return value
+}
+
+// describeResponse 既存の予約Sagaの実行情報（hashが空の場合はメモを記録していない）
+func describeResponse(t *testing.T, bookingID, hash string) *workflowservice.DescribeWorkflowExecutionResponse {
+	info := &workflowpb.WorkflowExecutionInfo{
+		Execution: &commonpb.WorkflowExecution{WorkflowId: bookingID, RunId: "run-existing"},
+		Status:    enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING,
+	}
+	if hash != "" {
+		payload, err := converter.GetDefaultDataConverter().ToPayload(hash)
+		assert.NoError(t, err)
+		info.Memo = &commonpb.Memo{Fields: map[string]*commonpb.Payload{workflows.MemoRequestHash: payload}}
+	}
+	return &workflowservice.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: info}
+}
+
+// testケース
+// 正常系:
+//   - 予約IDをワークフローIDとして、予約IDを再利用しないポリシーとリクエストのハッシュのメモを付けて開始する
+//   - 同じ内容の予約が既に開始されている時、既存の予約Sagaを返す（日時のタイムゾーンの違いは同じ内容として扱う）
+//
+// 準異常系:
+//   - 同じ予約IDで異なる内容の予約が既に開始されている時、ErrBookingConflictを返す
+//   - 既存の予約Sagaにハッシュが記録されていない時、照合できないためErrBookingConflictを返す
+//
+// 異常系:
+//   - リクエストが不正な時、予約Sagaを開始せずにErrInvalidRequestを返す
+//   - 予約Sagaの開始に失敗した時、エラーを返す
+func TestClient_Start(t *testing.T) {
+	invalid := testBookingRequest()
+	invalid.UserID = ""
+	otherUser := testBookingRequest()
+	otherUser.UserID = "user-002"
+	otherZone := testBookingRequest()
+	jst := time.FixedZone("JST", 9*60*60)
+	otherZone.Hotel.CheckIn = otherZone.Hotel.CheckIn.In(jst)
+	otherZone.Hotel.CheckOut = otherZone.Hotel.CheckOut.In(jst)
+	hash, err := workflows.RequestHash(testBookingRequest())
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		request      workflows.BookingRequest
+		startErr     error
+		existingHash string // 既に開始されている予約Sagaのハッシュ（startErrがAlreadyStartedの場合）
+
+		expectedStart      bool
+		expectedSubmission *Submission
+		expectedErr        error
+	}{
+		"正常系 - 予約IDをワークフローIDとして開始する": {
+			request:            testBookingRequest(),
+			expectedStart:      true,
+			expectedSubmission: &Submission{BookingID: "booking-001", RunID: "run-new"},
+		},
+		"正常系 - 同じ内容の予約を再送信する": {
+			request:            otherZone,
+			startErr:           serviceerror.NewWorkflowExecutionAlreadyStarted("already started", "", "run-existing"),
+			existingHash:       hash,
+			expectedStart:      true,
+			expectedSubmission: &Submission{BookingID: "booking-001", RunID: "run-existing", Duplicate: true},
+		},
+		"準異常系 - 同じ予約IDで異なる内容の予約を送信する": {
+			request:       otherUser,
+			startErr:      serviceerror.NewWorkflowExecutionAlreadyStarted("already started", "", "run-existing"),
+			existingHash:  hash,
+			expectedStart: true,
+			expectedErr:   ErrBookingConflict,
+		},
+		"準異常系 - 既存の予約Sagaにハッシュが記録されていない": {
+			request:       testBookingRequest(),
+			startErr:      serviceerror.NewWorkflowExecutionAlreadyStarted("already started", "", "run-existing"),
+			expectedStart: true,
+			expectedErr:   ErrBookingConflict,
+		},
+		"異常系 - リクエストが不正": {
+			request:     invalid,
+			expectedErr: ErrInvalidRequest,
+		},
+		"異常系 - 予約Sagaの開始に失敗する": {
+			request:       testBookingRequest(),
+			startErr:      errors.New("connection refused"),
+			expectedStart: true,
+			expectedErr:   errors.New("start booking booking-001: connection refused"),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			temporal := mocks.NewClient(t)
+			if tt.expectedStart {
+				expectedHash, err := workflows.RequestHash(tt.request)
+				assert.NoError(t, err)
+				options := mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+					return o.ID == tt.request.BookingID && o.TaskQueue == testTaskQueue &&
+						o.WorkflowIDReusePolicy == enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE &&
+						o.WorkflowIDConflictPolicy == enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL &&
+						o.WorkflowExecutionErrorWhenAlreadyStarted &&
+						o.Memo[workflows.MemoRequestHash] == expectedHash
+				})
+				if tt.startErr != nil {
+					temporal.On("ExecuteWorkflow", mock.Anything, options, mock.Anything, tt.request).Return(nil, tt.startErr).Once()
+				} else {
+					run := mocks.NewWorkflowRun(t)
+					run.On("GetID").Return(tt.request.BookingID)
+					run.On("GetRunID").Return("run-new")
+					temporal.On("ExecuteWorkflow", mock.Anything, options, mock.Anything, tt.request).Return(run, nil).Once()
+				}
+			}
+			var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+			if errors.As(tt.startErr, &alreadyStarted) {
+				temporal.On("DescribeWorkflowExecution", mock.Anything, tt.request.BookingID, "").Return(
+					describeResponse(t, tt.request.BookingID, tt.existingHash), nil).Once()
+			}
+
+			// when
+			got, err := New(temporal, testTaskQueue).Start(context.Background(), tt.request)
+
+			// then
+			switch {
+			case tt.expectedErr == nil:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedSubmission, got)
+			case errors.Is(tt.expectedErr, ErrInvalidRequest), errors.Is(tt.expectedErr, ErrBookingConflict):
+				assert.ErrorIs(t, err, tt.expectedErr)
+			default:
+				assert.EqualError(t, err, tt.expectedErr.Error())
+			}
+		})
+	}
+}
+
+// testケース
+// 正常系:
+//   - フェーズが変わった時だけ通知し、終了フェーズに達したら予約状況を返す
+//   - 終了フェーズの指定がない時、予約Sagaが終了するまで待つ
+//
+// 異常系:
+//   - 問い合わせに失敗した時、エラーを返す
+func TestClient_Watch(t *testing.T) {
+	tests := map[string]struct {
+		phases   []workflows.BookingPhase // 問い合わせごとのフェーズ
+		queryErr error                    // 最後の問い合わせのエラー
+		until    []workflows.BookingPhase
+
+		expectedNotified []workflows.BookingPhase
+		expectedPhase    workflows.BookingPhase
+		expectedErr      string
+	}{
+		"正常系 - 予約が確定するまで表示する": {
+			phases:           []workflows.BookingPhase{workflows.PhaseBookingHotel, workflows.PhaseBookingHotel, workflows.PhaseBookingDinner, workflows.PhaseAwaitingCheckIn},
+			until:            []workflows.BookingPhase{workflows.PhaseAwaitingCheckIn},
+			expectedNotified: []workflows.BookingPhase{workflows.PhaseBookingHotel, workflows.PhaseBookingDinner, workflows.PhaseAwaitingCheckIn},
+			expectedPhase:    workflows.PhaseAwaitingCheckIn,
+		},
+		"正常系 - 予約Sagaが終了するまで表示する": {
+			phases:           []workflows.BookingPhase{workflows.PhaseAwaitingCheckIn, workflows.PhaseCheckedIn, workflows.PhaseDone},
+			expectedNotified: []workflows.BookingPhase{workflows.PhaseAwaitingCheckIn, workflows.PhaseCheckedIn, workflows.PhaseDone},
+			expectedPhase:    workflows.PhaseDone,
+		},
+		"異常系 - 問い合わせに失敗する": {
+			phases:           []workflows.BookingPhase{workflows.PhaseBookingHotel},
+			queryErr:         errors.New("workflow not found"),
+			expectedNotified: []workflows.BookingPhase{workflows.PhaseBookingHotel},
+			expectedErr:      "query status of booking booking-001: workflow not found",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			temporal := mocks.NewClient(t)
+			for _, phase := range tt.phases {
+				status := workflows.BookingStatus{BookingID: "booking-001", Phase: phase}
+				temporal.On("QueryWorkflow", mock.Anything, "booking-001", "", workflows.BookingStatusQuery).Return(statusValue(t, status), nil).Once()
+			}
+			if tt.queryErr != nil {
+				temporal.On("QueryWorkflow", mock.Anything, "booking-001", "", workflows.BookingStatusQuery).Return(nil, tt.queryErr).Once()
+			}
+
+			// when
+			var notified []workflows.BookingPhase
+			status, err := New(temporal, testTaskQueue).Watch(context.Background(), "booking-001", time.Millisecond, tt.until, func(s workflows.BookingStatus) {
+				notified = append(notified, s.Phase)
+			})
+
+			// then
+			assert.Equal(t, tt.expectedNotified, notified)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPhase, status.Phase)
+		})
+	}
+}
+
+// testケース
+// 正常系:
+//   - キャンセルのシグナルを予約IDのワークフローに送る
+//   - 予約変更のアップデートの完了を待って変更後の予約結果を返す
+func TestClient_Signals(t *testing.T) {
+	t.Run("正常系 - キャンセルのシグナルを送る", func(t *testing.T) {
+		// given
+		temporal := mocks.NewClient(t)
+		request := workflows.CancellationRequest{Reason: "予定変更", RequestedBy: "bookingctl"}
+		temporal.On("SignalWorkflow", mock.Anything, "booking-001", "", workflows.CancelBookingSignal, request).Return(nil).Once()
+
+		// when
+		err := New(temporal, testTaskQueue).Cancel(context.Background(), "booking-001", request)
+
+		// then
+		assert.NoError(t, err)
+	})
+
+	t.Run("正常系 - 予約変更の完了を待つ", func(t *testing.T) {
+		// given
+		temporal := mocks.NewClient(t)
+		handle := mocks.NewWorkflowUpdateHandle(t)
+		request := workflows.ModificationRequest{Reason: "人数変更", Dinner: &workflows.DinnerRequest{MenuType: "kaiseki", Guests: 3}}
+		temporal.On("UpdateWorkflow", mock.Anything, client.UpdateWorkflowOptions{
+			WorkflowID:   "booking-001",
+			UpdateName:   workflows.ModifyBookingUpdate,
+			Args:         []interface{}{request},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		}).Return(handle, nil).Once()
+		handle.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*workflows.BookingResult) = workflows.BookingResult{Success: true, BookingID: "booking-001", Revision: 1}
+		}).Return(nil).Once()
+
+		// when
+		result, err := New(temporal, testTaskQueue).Modify(context.Background(), "booking-001", request)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Revision)
+	})
+}
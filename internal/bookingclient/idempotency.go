@@ -0,0 +1,24 @@
+package bookingclient
+
+import (
+	"fmt"
+
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/sdk/converter"
+
+	"temporal-hotel-sample/internal/workflows"
+)
+
+// memoRequestHashOf 予約Sagaのメモに記録したリクエストのハッシュ
+// 記録していない予約Saga（ハッシュの記録を導入する前に開始したもの）は照合できないため、空文字を返して異なる内容として扱う
+func memoRequestHashOf(info *workflowpb.WorkflowExecutionInfo) (string, error) {
+	payload, ok := info.GetMemo().GetFields()[workflows.MemoRequestHash]
+	if !ok {
+		return "", nil
+	}
+	var hash string
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &hash); err != nil {
+		return "", fmt.Errorf("decode request hash: %w", err)
+	}
+	return hash, nil
+}
@@ -0,0 +1,397 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// Step リトライポリシーを定義する単位となるステップ名
+type Step string
+
+const (
+	// StepHotel ホテルルーム予約
+	StepHotel Step = "hotel"
+	// StepDinner ディナー食材予約
+	StepDinner Step = "dinner"
+	// StepParking 駐車場予約
+	StepParking Step = "parking"
+	// StepHotelCompensation ホテルルーム補償
+	StepHotelCompensation Step = "hotel_compensation"
+	// StepDinnerCompensation ディナー食材補償
+	StepDinnerCompensation Step = "dinner_compensation"
+	// StepParkingCompensation 駐車場補償
+	StepParkingCompensation Step = "parking_compensation"
+	// StepCompensation 個別の定義を持たない補償処理
+	StepCompensation Step = "compensation"
+	// StepStuckCompensation 手動対応待ち補償処理の記録
+	StepStuckCompensation Step = "stuck_compensation"
+	// StepCheckInReminder チェックイン前のリマインダー送信
+	StepCheckInReminder Step = "check_in_reminder"
+)
+
+// envRetryPolicyPrefix ステップごとの環境変数上書きのプレフィックス
+// 例: HOTEL_BOOKING_RETRY_HOTEL_MAXIMUM_ATTEMPTS=5
+const envRetryPolicyPrefix = "HOTEL_BOOKING_RETRY_"
+
+// Duration "1s"や"5m"の形式で設定ファイルに記述できる時間
+type Duration time.Duration
+
+// UnmarshalJSON JSONの文字列またはナノ秒の数値から変換
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return d.parse(s)
+	}
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("invalid duration: %s", string(b))
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// MarshalJSON "1s"形式の文字列に変換
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalYAML YAMLの文字列から変換
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.parse(node.Value)
+}
+
+// MarshalYAML "1s"形式の文字列に変換
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// StepPolicy ステップごとのアクティビティタイムアウトとリトライポリシー
+type StepPolicy struct {
+	StartToCloseTimeout    Duration `json:"start_to_close_timeout" yaml:"start_to_close_timeout"`
+	InitialInterval        Duration `json:"initial_interval" yaml:"initial_interval"`
+	BackoffCoefficient     float64  `json:"backoff_coefficient" yaml:"backoff_coefficient"`
+	MaximumInterval        Duration `json:"maximum_interval" yaml:"maximum_interval"`
+	MaximumAttempts        int32    `json:"maximum_attempts" yaml:"maximum_attempts"` // 0の場合は無制限
+	NonRetryableErrorTypes []string `json:"non_retryable_error_types" yaml:"non_retryable_error_types"`
+}
+
+// Validate ポリシーの妥当性チェック
+func (p StepPolicy) Validate() error {
+	if p.StartToCloseTimeout <= 0 {
+		return errors.New("start_to_close_timeout must be positive")
+	}
+	if p.InitialInterval <= 0 {
+		return errors.New("initial_interval must be positive")
+	}
+	if p.BackoffCoefficient < 1 {
+		return errors.New("backoff_coefficient must be greater than or equal to 1")
+	}
+	if p.MaximumInterval != 0 && p.MaximumInterval < p.InitialInterval {
+		return errors.New("maximum_interval must be greater than or equal to initial_interval")
+	}
+	if p.MaximumAttempts < 0 {
+		return errors.New("maximum_attempts must not be negative")
+	}
+	for _, t := range p.NonRetryableErrorTypes {
+		if strings.TrimSpace(t) == "" {
+			return errors.New("non_retryable_error_types must not contain empty type")
+		}
+	}
+	return nil
+}
+
+// RetryPolicy TemporalのRetryPolicyに変換
+func (p StepPolicy) RetryPolicy() *temporal.RetryPolicy {
+	return &temporal.RetryPolicy{
+		InitialInterval:        time.Duration(p.InitialInterval),
+		BackoffCoefficient:     p.BackoffCoefficient,
+		MaximumInterval:        time.Duration(p.MaximumInterval),
+		MaximumAttempts:        p.MaximumAttempts,
+		NonRetryableErrorTypes: append([]string(nil), p.NonRetryableErrorTypes...),
+	}
+}
+
+// ActivityOptions TemporalのActivityOptionsに変換
+func (p StepPolicy) ActivityOptions() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		StartToCloseTimeout: time.Duration(p.StartToCloseTimeout),
+		RetryPolicy:         p.RetryPolicy(),
+	}
+}
+
+// PolicyRegistry ステップごとのリトライポリシーの登録簿
+type PolicyRegistry struct {
+	Steps map[Step]StepPolicy `json:"steps" yaml:"steps"`
+}
+
+// DefaultPolicyRegistry デフォルトのリトライポリシー登録簿を作成
+func DefaultPolicyRegistry() *PolicyRegistry {
+	// 予約ステップ: ビジネスエラー（activities.BusinessErrorType）はリトライしない
+	booking := StepPolicy{
+		StartToCloseTimeout:    Duration(30 * time.Second),
+		InitialInterval:        Duration(time.Second),
+		BackoffCoefficient:     2.0,
+		MaximumInterval:        Duration(time.Minute),
+		MaximumAttempts:        3,
+		NonRetryableErrorTypes: []string{"BusinessError"},
+	}
+	// 補償ステップ: 整合性を保つため全てのエラーをリトライ対象とする
+	compensation := StepPolicy{
+		StartToCloseTimeout: Duration(5 * time.Minute),
+		InitialInterval:     Duration(time.Second),
+		BackoffCoefficient:  2.0,
+		MaximumInterval:     Duration(time.Minute),
+		MaximumAttempts:     3,
+	}
+	// 手動対応待ち記録: 記録漏れを防ぐため多めにリトライする
+	stuck := StepPolicy{
+		StartToCloseTimeout:    Duration(30 * time.Second),
+		InitialInterval:        Duration(time.Second),
+		BackoffCoefficient:     2.0,
+		MaximumInterval:        Duration(time.Minute),
+		MaximumAttempts:        5,
+		NonRetryableErrorTypes: []string{"BusinessError"},
+	}
+
+	// リマインダー送信: 送信漏れを防ぐため多めにリトライする
+	reminder := StepPolicy{
+		StartToCloseTimeout:    Duration(30 * time.Second),
+		InitialInterval:        Duration(time.Second),
+		BackoffCoefficient:     2.0,
+		MaximumInterval:        Duration(time.Minute),
+		MaximumAttempts:        5,
+		NonRetryableErrorTypes: []string{"BusinessError"},
+	}
+
+	return &PolicyRegistry{
+		Steps: map[Step]StepPolicy{
+			StepHotel:               booking,
+			StepDinner:              booking,
+			StepParking:             booking,
+			StepHotelCompensation:   compensation,
+			StepDinnerCompensation:  compensation,
+			StepParkingCompensation: compensation,
+			StepCompensation:        compensation,
+			StepStuckCompensation:   stuck,
+			StepCheckInReminder:     reminder,
+		},
+	}
+}
+
+// Policy ステップのポリシーを取得（未定義のステップはStepCompensationにフォールバック）
+func (r *PolicyRegistry) Policy(step Step) StepPolicy {
+	if p, ok := r.Steps[step]; ok {
+		return p
+	}
+	return r.Steps[StepCompensation]
+}
+
+// RetryPolicy ステップのリトライポリシーを取得
+func (r *PolicyRegistry) RetryPolicy(step Step) *temporal.RetryPolicy {
+	return r.Policy(step).RetryPolicy()
+}
+
+// ActivityOptions ステップのアクティビティオプションを取得
+func (r *PolicyRegistry) ActivityOptions(step Step) workflow.ActivityOptions {
+	return r.Policy(step).ActivityOptions()
+}
+
+// Validate 全ステップのポリシーの妥当性チェック
+func (r *PolicyRegistry) Validate() error {
+	if _, ok := r.Steps[StepCompensation]; !ok {
+		return fmt.Errorf("retry policy for step %q is required", StepCompensation)
+	}
+	steps := make([]string, 0, len(r.Steps))
+	for step := range r.Steps {
+		steps = append(steps, string(step))
+	}
+	sort.Strings(steps)
+	for _, step := range steps {
+		if err := r.Steps[Step(step)].Validate(); err != nil {
+			return fmt.Errorf("invalid retry policy for step %q: %w", step, err)
+		}
+	}
+	return nil
+}
+
+// stepPolicyOverride 設定ファイルで指定されたステップごとの上書き値（未指定の項目はデフォルトを維持）
+type stepPolicyOverride struct {
+	StartToCloseTimeout    *Duration `json:"start_to_close_timeout" yaml:"start_to_close_timeout"`
+	InitialInterval        *Duration `json:"initial_interval" yaml:"initial_interval"`
+	BackoffCoefficient     *float64  `json:"backoff_coefficient" yaml:"backoff_coefficient"`
+	MaximumInterval        *Duration `json:"maximum_interval" yaml:"maximum_interval"`
+	MaximumAttempts        *int32    `json:"maximum_attempts" yaml:"maximum_attempts"`
+	NonRetryableErrorTypes []string  `json:"non_retryable_error_types" yaml:"non_retryable_error_types"`
+}
+
+// policyFile リトライポリシー定義ファイルの形式
+type policyFile struct {
+	Steps map[Step]stepPolicyOverride `json:"steps" yaml:"steps"`
+}
+
+// LoadPolicyRegistry デフォルトに設定ファイル（YAML/JSON）と環境変数の上書きを適用し、検証済みの登録簿を返す
+// pathが空の場合は設定ファイルを読み込まない
+func LoadPolicyRegistry(path string) (*PolicyRegistry, error) {
+	registry := DefaultPolicyRegistry()
+	if path != "" {
+		if err := registry.mergeFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := registry.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := registry.Validate(); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// mergeFile 設定ファイルの内容を登録簿に適用
+func (r *PolicyRegistry) mergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read retry policy file: %w", err)
+	}
+
+	var file policyFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	default:
+		return fmt.Errorf("unsupported retry policy file extension: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parse retry policy file: %w", err)
+	}
+
+	for step, override := range file.Steps {
+		if _, ok := r.Steps[step]; !ok {
+			return fmt.Errorf("unknown step in retry policy file: %q", step)
+		}
+		r.Steps[step] = override.apply(r.Steps[step])
+	}
+	return nil
+}
+
+// apply 上書き値をポリシーに適用
+func (o stepPolicyOverride) apply(p StepPolicy) StepPolicy {
+	if o.StartToCloseTimeout != nil {
+		p.StartToCloseTimeout = *o.StartToCloseTimeout
+	}
+	if o.InitialInterval != nil {
+		p.InitialInterval = *o.InitialInterval
+	}
+	if o.BackoffCoefficient != nil {
+		p.BackoffCoefficient = *o.BackoffCoefficient
+	}
+	if o.MaximumInterval != nil {
+		p.MaximumInterval = *o.MaximumInterval
+	}
+	if o.MaximumAttempts != nil {
+		p.MaximumAttempts = *o.MaximumAttempts
+	}
+	if o.NonRetryableErrorTypes != nil {
+		p.NonRetryableErrorTypes = o.NonRetryableErrorTypes
+	}
+	return p
+}
+
+// applyEnv 環境変数による上書きを適用
+// HOTEL_BOOKING_RETRY_<STEP>_<FIELD> の形式（例: HOTEL_BOOKING_RETRY_PARKING_COMPENSATION_MAXIMUM_ATTEMPTS）
+func (r *PolicyRegistry) applyEnv(lookup func(string) (string, bool)) error {
+	for step, policy := range r.Steps {
+		prefix := envRetryPolicyPrefix + strings.ToUpper(string(step)) + "_"
+		var override stepPolicyOverride
+
+		durations := map[string]**Duration{
+			"START_TO_CLOSE_TIMEOUT": &override.StartToCloseTimeout,
+			"INITIAL_INTERVAL":       &override.InitialInterval,
+			"MAXIMUM_INTERVAL":       &override.MaximumInterval,
+		}
+		for name, field := range durations {
+			if v, ok := lookup(prefix + name); ok {
+				var d Duration
+				if err := d.parse(v); err != nil {
+					return fmt.Errorf("%s: %w", prefix+name, err)
+				}
+				*field = &d
+			}
+		}
+		if v, ok := lookup(prefix + "BACKOFF_COEFFICIENT"); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", prefix+"BACKOFF_COEFFICIENT", err)
+			}
+			override.BackoffCoefficient = &f
+		}
+		if v, ok := lookup(prefix + "MAXIMUM_ATTEMPTS"); ok {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return fmt.Errorf("%s: %w", prefix+"MAXIMUM_ATTEMPTS", err)
+			}
+			attempts := int32(n)
+			override.MaximumAttempts = &attempts
+		}
+		if v, ok := lookup(prefix + "NON_RETRYABLE_ERROR_TYPES"); ok {
+			override.NonRetryableErrorTypes = splitList(v)
+		}
+
+		r.Steps[step] = override.apply(policy)
+	}
+	return nil
+}
+
+// splitList カンマ区切りの文字列を分割
+func splitList(v string) []string {
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+var (
+	policiesMu sync.RWMutex
+	policies   = DefaultPolicyRegistry()
+)
+
+// SetPolicies ワークフローが使用するリトライポリシー登録簿を設定
+// ワーカー起動時に検証済みの登録簿を設定すること
+func SetPolicies(r *PolicyRegistry) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	policies = r
+}
+
+// Policies ワークフローが使用するリトライポリシー登録簿を取得
+func Policies() *PolicyRegistry {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	return policies
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - 設定ファイルがない時、デフォルトのポリシーが返却される
+//   - YAMLファイルで指定した項目のみ上書きされる
+//   - JSONファイルで指定した項目のみ上書きされる
+//   - 環境変数の指定が設定ファイルより優先される
+//
+// 異常系:
+//   - 未知のステップが指定された時、エラーが返却される
+//   - backoff_coefficientが1未満の時、エラーが返却される
+//   - maximum_intervalがinitial_intervalより短い時、エラーが返却される
+//   - maximum_attemptsが負の時、エラーが返却される
+func TestLoadPolicyRegistry(t *testing.T) {
+	testcases := map[string]struct {
+		fileName    string
+		fileContent string
+		env         map[string]string
+
+		expectedStep   Step
+		expectedPolicy func(p StepPolicy) StepPolicy
+		expectedErr    bool
+	}{
+		"正常系: 設定ファイルがない時、デフォルトのポリシーが返却される": {
+			expectedStep:   StepHotel,
+			expectedPolicy: func(p StepPolicy) StepPolicy { return p },
+		},
+		"正常系: YAMLファイルで指定した項目のみ上書きされる": {
+			fileName: "policy.yaml",
+			fileContent: `
+steps:
+  parking:
+    maximum_attempts: 5
+    initial_interval: 2s
+`,
+			expectedStep: StepParking,
+			expectedPolicy: func(p StepPolicy) StepPolicy {
+				p.MaximumAttempts = 5
+				p.InitialInterval = Duration(2 * time.Second)
+				return p
+			},
+		},
+		"正常系: JSONファイルで指定した項目のみ上書きされる": {
+			fileName:     "policy.json",
+			fileContent:  `{"steps": {"hotel_compensation": {"start_to_close_timeout": "10m", "non_retryable_error_types": ["FatalError"]}}}`,
+			expectedStep: StepHotelCompensation,
+			expectedPolicy: func(p StepPolicy) StepPolicy {
+				p.StartToCloseTimeout = Duration(10 * time.Minute)
+				p.NonRetryableErrorTypes = []string{"FatalError"}
+				return p
+			},
+		},
+		"正常系: 環境変数の指定が設定ファイルより優先される": {
+			fileName:    "policy.yaml",
+			fileContent: "steps:\n  dinner:\n    maximum_attempts: 5\n",
+			env: map[string]string{
+				"HOTEL_BOOKING_RETRY_DINNER_MAXIMUM_ATTEMPTS":    "7",
+				"HOTEL_BOOKING_RETRY_DINNER_BACKOFF_COEFFICIENT": "1.5",
+			},
+			expectedStep: StepDinner,
+			expectedPolicy: func(p StepPolicy) StepPolicy {
+				p.MaximumAttempts = 7
+				p.BackoffCoefficient = 1.5
+				return p
+			},
+		},
+		"異常系: 未知のステップが指定された時、エラーが返却される": {
+			fileName:    "policy.yaml",
+			fileContent: "steps:\n  spa:\n    maximum_attempts: 5\n",
+			expectedErr: true,
+		},
+		"異常系: backoff_coefficientが1未満の時、エラーが返却される": {
+			fileName:    "policy.yaml",
+			fileContent: "steps:\n  hotel:\n    backoff_coefficient: 0.5\n",
+			expectedErr: true,
+		},
+		"異常系: maximum_intervalがinitial_intervalより短い時、エラーが返却される": {
+			fileName:    "policy.json",
+			fileContent: `{"steps": {"hotel": {"initial_interval": "2m", "maximum_interval": "1m"}}}`,
+			expectedErr: true,
+		},
+		"異常系: maximum_attemptsが負の時、エラーが返却される": {
+			env:         map[string]string{"HOTEL_BOOKING_RETRY_PARKING_COMPENSATION_MAXIMUM_ATTEMPTS": "-1"},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			path := ""
+			if tc.fileName != "" {
+				path = filepath.Join(t.TempDir(), tc.fileName)
+				assert.NoError(t, os.WriteFile(path, []byte(tc.fileContent), 0o644))
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			// when
+			actual, err := LoadPolicyRegistry(path)
+
+			// then
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			expected := tc.expectedPolicy(DefaultPolicyRegistry().Policy(tc.expectedStep))
+			assert.Equal(t, expected, actual.Policy(tc.expectedStep))
+		})
+	}
+}
+
+func TestPolicyRegistry_RetryPolicy(t *testing.T) {
+	testcases := map[string]struct {
+		step                   Step
+		expectedNonRetryable   []string
+		expectedMaximumAttempt int32
+	}{
+		"正常系: 予約ステップではビジネスエラーがリトライ対象外になる": {
+			step:                   StepHotel,
+			expectedNonRetryable:   []string{"BusinessError"},
+			expectedMaximumAttempt: 3,
+		},
+		"正常系: 補償ステップでは全てのエラーがリトライ対象になる": {
+			step:                   StepParkingCompensation,
+			expectedNonRetryable:   nil,
+			expectedMaximumAttempt: 3,
+		},
+		"正常系: 未定義のステップは補償処理のポリシーにフォールバックする": {
+			step:                   Step("spa_compensation"),
+			expectedNonRetryable:   nil,
+			expectedMaximumAttempt: 3,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			sut := DefaultPolicyRegistry()
+
+			// when
+			actual := sut.RetryPolicy(tc.step)
+
+			// then
+			assert.Equal(t, tc.expectedNonRetryable, actual.NonRetryableErrorTypes)
+			assert.Equal(t, tc.expectedMaximumAttempt, actual.MaximumAttempts)
+		})
+	}
+}
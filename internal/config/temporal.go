@@ -1,8 +1,6 @@
 package config
 
 import (
-	"time"
-
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -12,23 +10,12 @@ const (
 	TaskQueue = "HOTEL_BOOKING_TASK_QUEUE"
 )
 
-// GetRetryPolicy リトライポリシーを取得
-func GetRetryPolicy() *temporal.RetryPolicy {
-	return &temporal.RetryPolicy{
-		InitialInterval:    time.Second,
-		BackoffCoefficient: 2.0,
-		MaximumInterval:    time.Minute,
-		MaximumAttempts:    3,
-		NonRetryableErrorTypes: []string{
-			"BusinessError",
-		},
-	}
+// GetRetryPolicy ステップのリトライポリシーを取得
+func GetRetryPolicy(step Step) *temporal.RetryPolicy {
+	return Policies().RetryPolicy(step)
 }
 
-// GetActivityOptions アクティビティオプションを取得
-func GetActivityOptions() workflow.ActivityOptions {
-	return workflow.ActivityOptions{
-		StartToCloseTimeout: time.Minute * 5,
-		RetryPolicy:         GetRetryPolicy(),
-	}
+// GetActivityOptions ステップのアクティビティオプションを取得
+func GetActivityOptions(step Step) workflow.ActivityOptions {
+	return Policies().ActivityOptions(step)
 }
@@ -0,0 +1,405 @@
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvWorkerConfigFile ワーカー設定ファイルのパスを指定する環境変数
+const EnvWorkerConfigFile = "HOTEL_BOOKING_WORKER_CONFIG_FILE"
+
+// ワーカー設定を上書きする環境変数
+// Temporalへの接続はtemporal CLIと同じ名前の環境変数で指定する
+// ワーカーが読み書きするファイルは、設定ファイルを導入する前からの環境変数名を引き継ぐ
+const (
+	envTemporalAddress       = "TEMPORAL_ADDRESS"
+	envTemporalNamespace     = "TEMPORAL_NAMESPACE"
+	envTemporalTLSCert       = "TEMPORAL_TLS_CERT"
+	envTemporalTLSKey        = "TEMPORAL_TLS_KEY"
+	envTemporalTLSCA         = "TEMPORAL_TLS_CA"
+	envTemporalTLSServerName = "TEMPORAL_TLS_SERVER_NAME"
+	envWorkerPrefix          = "HOTEL_BOOKING_WORKER_"
+	envRetryPolicyFile       = "HOTEL_BOOKING_RETRY_POLICY_FILE"
+	envStuckCompensationFile = "STUCK_COMPENSATION_FILE"
+	envIdempotencyStoreFile  = "IDEMPOTENCY_STORE_FILE"
+)
+
+// TemporalConfig Temporalサーバーへの接続設定
+type TemporalConfig struct {
+	Address   string    `json:"address" yaml:"address"`
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	Identity  string    `json:"identity,omitempty" yaml:"identity,omitempty"` // 空の場合はSDKの既定（pid@host）
+	TLS       TLSConfig `json:"tls" yaml:"tls"`
+}
+
+// TLSConfig Temporalサーバーへの接続のTLS設定
+// いずれかの項目を指定するとTLSで接続する。クライアント証明書（mTLS）はcert_fileとkey_fileを両方指定する
+type TLSConfig struct {
+	Enabled    bool   `json:"enabled" yaml:"enabled"`
+	CertFile   string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile    string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	CAFile     string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"` // 空の場合はシステムのルート証明書
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+}
+
+// WorkerOptionsConfig ワーカーのタスクキューと同時実行数の設定
+// 同時実行数とポーラー数は0の場合にSDKの既定値を使う
+type WorkerOptionsConfig struct {
+	TaskQueue                  string   `json:"task_queue" yaml:"task_queue"`
+	MaxConcurrentActivities    int      `json:"max_concurrent_activities" yaml:"max_concurrent_activities"`
+	MaxConcurrentWorkflowTasks int      `json:"max_concurrent_workflow_tasks" yaml:"max_concurrent_workflow_tasks"`
+	ActivityPollers            int      `json:"activity_pollers" yaml:"activity_pollers"`
+	WorkflowPollers            int      `json:"workflow_pollers" yaml:"workflow_pollers"`
+	ShutdownTimeout            Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"` // 停止時に実行中のアクティビティの完了を待つ時間
+	AdminAddress               string   `json:"admin_address" yaml:"admin_address"`       // 管理用HTTPサーバー（healthz, readyz, drain）の待ち受けアドレス。空の場合は起動しない
+}
+
+// FilesConfig ワーカーが読み書きするファイルの設定
+// 記録先を指定しない場合はインメモリで保持するため、ワーカーを再起動すると失われる
+type FilesConfig struct {
+	RetryPolicy       string `json:"retry_policy" yaml:"retry_policy"`             // リトライポリシー定義ファイル（YAML/JSON）。空の場合は既定のポリシー
+	StuckCompensation string `json:"stuck_compensation" yaml:"stuck_compensation"` // 手動対応待ち補償処理の記録先
+	IdempotencyStore  string `json:"idempotency_store" yaml:"idempotency_store"`   // 処理結果と在庫の状態の保存先（BoltDB）
+}
+
+// WorkerConfig ワーカープロセスの設定
+type WorkerConfig struct {
+	Temporal TemporalConfig      `json:"temporal" yaml:"temporal"`
+	Worker   WorkerOptionsConfig `json:"worker" yaml:"worker"`
+	Files    FilesConfig         `json:"files" yaml:"files"`
+}
+
+// enabled TLSで接続するかどうか
+func (c TLSConfig) enabled() bool {
+	return c.Enabled || c.CertFile != "" || c.KeyFile != "" || c.CAFile != "" || c.ServerName != ""
+}
+
+// DefaultWorkerConfig デフォルトのワーカー設定を作成（ローカルのTemporalサーバーに接続する）
+func DefaultWorkerConfig() *WorkerConfig {
+	return &WorkerConfig{
+		Temporal: TemporalConfig{
+			Address:   client.DefaultHostPort,
+			Namespace: client.DefaultNamespace,
+		},
+		Worker: WorkerOptionsConfig{
+			TaskQueue:       TaskQueue,
+			ShutdownTimeout: Duration(30 * time.Second),
+			AdminAddress:    ":8090",
+		},
+	}
+}
+
+// LoadWorkerConfig デフォルトに設定ファイル（YAML/JSON）と環境変数の上書きを適用し、検証済みの設定を返す
+// pathが空の場合は設定ファイルを読み込まない
+func LoadWorkerConfig(path string) (*WorkerConfig, error) {
+	cfg := DefaultWorkerConfig()
+	if path != "" {
+		if err := cfg.mergeFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ClientConfig APIサーバーやbookingctlがワーカーと同じ接続先・タスクキューで予約Sagaを開始するための設定
+type ClientConfig struct {
+	Temporal  TemporalConfig
+	TaskQueue string // 予約Sagaを開始するタスクキュー（ワーカーのworker.task_queue）
+}
+
+// LoadClientConfig ワーカー設定ファイルと環境変数から接続設定とワーカーのタスクキューだけを読み込む
+// ワーカー固有の項目は検証しない。identityはワーカーのものなので引き継がず、SDKの既定（pid@host）を使う
+func LoadClientConfig(path string) (*ClientConfig, error) {
+	cfg := DefaultWorkerConfig()
+	if path != "" {
+		if err := cfg.mergeFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Temporal.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if v, ok := os.LookupEnv(envWorkerPrefix + "TASK_QUEUE"); ok {
+		cfg.Worker.TaskQueue = v
+	}
+	cfg.Temporal.Identity = ""
+	clientConfig := &ClientConfig{Temporal: cfg.Temporal, TaskQueue: cfg.Worker.TaskQueue}
+	if err := clientConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return clientConfig, nil
+}
+
+// mergeFile 設定ファイルの内容を設定に適用（指定のない項目は現在の値を維持する）
+// 項目名の誤りに気付けるよう、未知の項目はエラーとする
+func (c *WorkerConfig) mergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read worker config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("unsupported worker config file extension: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parse worker config file: %w", err)
+	}
+	return nil
+}
+
+// applyEnv 環境変数による上書きを適用
+// ワーカーの設定は HOTEL_BOOKING_WORKER_<FIELD> の形式（例: HOTEL_BOOKING_WORKER_MAX_CONCURRENT_ACTIVITIES）
+func (c *WorkerConfig) applyEnv(lookup func(string) (string, bool)) error {
+	if err := c.Temporal.applyEnv(lookup); err != nil {
+		return err
+	}
+
+	strs := map[string]*string{
+		envWorkerPrefix + "IDENTITY":      &c.Temporal.Identity,
+		envWorkerPrefix + "TASK_QUEUE":    &c.Worker.TaskQueue,
+		envWorkerPrefix + "ADMIN_ADDRESS": &c.Worker.AdminAddress,
+		envRetryPolicyFile:                &c.Files.RetryPolicy,
+		envStuckCompensationFile:          &c.Files.StuckCompensation,
+		envIdempotencyStoreFile:           &c.Files.IdempotencyStore,
+	}
+	for name, field := range strs {
+		if v, ok := lookup(name); ok {
+			*field = v
+		}
+	}
+
+	ints := map[string]*int{
+		envWorkerPrefix + "MAX_CONCURRENT_ACTIVITIES":     &c.Worker.MaxConcurrentActivities,
+		envWorkerPrefix + "MAX_CONCURRENT_WORKFLOW_TASKS": &c.Worker.MaxConcurrentWorkflowTasks,
+		envWorkerPrefix + "ACTIVITY_POLLERS":              &c.Worker.ActivityPollers,
+		envWorkerPrefix + "WORKFLOW_POLLERS":              &c.Worker.WorkflowPollers,
+	}
+	for name, field := range ints {
+		if v, ok := lookup(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field = n
+		}
+	}
+	if v, ok := lookup(envWorkerPrefix + "SHUTDOWN_TIMEOUT"); ok {
+		if err := c.Worker.ShutdownTimeout.parse(v); err != nil {
+			return fmt.Errorf("%s: %w", envWorkerPrefix+"SHUTDOWN_TIMEOUT", err)
+		}
+	}
+	return nil
+}
+
+// applyEnv Temporalサーバーへの接続設定の環境変数による上書きを適用
+func (c *TemporalConfig) applyEnv(lookup func(string) (string, bool)) error {
+	strs := map[string]*string{
+		envTemporalAddress:       &c.Address,
+		envTemporalNamespace:     &c.Namespace,
+		envTemporalTLSCert:       &c.TLS.CertFile,
+		envTemporalTLSKey:        &c.TLS.KeyFile,
+		envTemporalTLSCA:         &c.TLS.CAFile,
+		envTemporalTLSServerName: &c.TLS.ServerName,
+	}
+	for name, field := range strs {
+		if v, ok := lookup(name); ok {
+			*field = v
+		}
+	}
+	if v, ok := lookup(envWorkerPrefix + "TLS_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", envWorkerPrefix+"TLS_ENABLED", err)
+		}
+		c.TLS.Enabled = enabled
+	}
+	return nil
+}
+
+// Validate 設定の妥当性チェック
+func (c *WorkerConfig) Validate() error {
+	if err := c.Temporal.Validate(); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(c.Worker.TaskQueue) == "" {
+		return errors.New("worker.task_queue is required")
+	}
+	limits := map[string]int{
+		"worker.max_concurrent_activities":     c.Worker.MaxConcurrentActivities,
+		"worker.max_concurrent_workflow_tasks": c.Worker.MaxConcurrentWorkflowTasks,
+		"worker.activity_pollers":              c.Worker.ActivityPollers,
+		"worker.workflow_pollers":              c.Worker.WorkflowPollers,
+	}
+	for name, n := range limits {
+		if n < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if c.Worker.ShutdownTimeout < 0 {
+		return errors.New("worker.shutdown_timeout must not be negative")
+	}
+	if c.Worker.AdminAddress != "" {
+		if _, _, err := net.SplitHostPort(c.Worker.AdminAddress); err != nil {
+			return fmt.Errorf("worker.admin_address must be host:port: %w", err)
+		}
+	}
+
+	if c.Files.RetryPolicy != "" {
+		if _, err := os.Stat(c.Files.RetryPolicy); err != nil {
+			return fmt.Errorf("files.retry_policy: %w", err)
+		}
+	}
+	// 記録先のファイルは初回の書き込みで作成されるため、書き込み先のディレクトリだけを確認する
+	outputs := map[string]string{
+		"files.stuck_compensation": c.Files.StuckCompensation,
+		"files.idempotency_store":  c.Files.IdempotencyStore,
+	}
+	for name, path := range outputs {
+		if path == "" {
+			continue
+		}
+		dir, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if !dir.IsDir() {
+			return fmt.Errorf("%s: %s is not a directory", name, filepath.Dir(path))
+		}
+	}
+	return nil
+}
+
+// Validate 接続設定とタスクキューの妥当性チェック
+func (c *ClientConfig) Validate() error {
+	if err := c.Temporal.Validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.TaskQueue) == "" {
+		return errors.New("worker.task_queue is required")
+	}
+	return nil
+}
+
+// Validate Temporalサーバーへの接続設定の妥当性チェック
+func (c *TemporalConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("temporal.address must be host:port: %w", err)
+	}
+	if strings.TrimSpace(c.Namespace) == "" {
+		return errors.New("temporal.namespace is required")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("temporal.tls.cert_file and temporal.tls.key_file must be specified together")
+	}
+	files := map[string]string{
+		"temporal.tls.cert_file": c.TLS.CertFile,
+		"temporal.tls.key_file":  c.TLS.KeyFile,
+		"temporal.tls.ca_file":   c.TLS.CAFile,
+	}
+	for name, path := range files {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ClientOptions TemporalのClient Optionsに変換（TLSの証明書はここで読み込む）
+func (c *WorkerConfig) ClientOptions() (client.Options, error) {
+	return c.Temporal.ClientOptions()
+}
+
+// ClientOptions TemporalのClient Optionsに変換（TLSの証明書はここで読み込む）
+func (c *TemporalConfig) ClientOptions() (client.Options, error) {
+	options := client.Options{
+		HostPort:  c.Address,
+		Namespace: c.Namespace,
+		Identity:  c.Identity,
+	}
+	if !c.TLS.enabled() {
+		return options, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.TLS.ServerName,
+	}
+	if c.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return client.Options{}, fmt.Errorf("load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if c.TLS.CAFile != "" {
+		pem, err := os.ReadFile(c.TLS.CAFile)
+		if err != nil {
+			return client.Options{}, fmt.Errorf("read TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return client.Options{}, fmt.Errorf("no certificates found in TLS CA file %s", c.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	options.ConnectionOptions.TLS = tlsConfig
+	return options, nil
+}
+
+// WorkerOptions TemporalのWorker Optionsに変換
+func (c *WorkerConfig) WorkerOptions() worker.Options {
+	return worker.Options{
+		MaxConcurrentActivityExecutionSize:     c.Worker.MaxConcurrentActivities,
+		MaxConcurrentWorkflowTaskExecutionSize: c.Worker.MaxConcurrentWorkflowTasks,
+		MaxConcurrentActivityTaskPollers:       c.Worker.ActivityPollers,
+		MaxConcurrentWorkflowTaskPollers:       c.Worker.WorkflowPollers,
+		WorkerStopTimeout:                      time.Duration(c.Worker.ShutdownTimeout),
+	}
+}
+
+// YAML 設定ファイルと同じ形式のYAML（--print-configで実際に使う設定を確認するため）
+func (c *WorkerConfig) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - 設定ファイルがない時、ローカルのTemporalサーバーに接続するデフォルトの設定が返却される
+//   - YAMLファイルで指定した項目のみ上書きされる
+//   - JSONファイルで指定した項目のみ上書きされる
+//   - 環境変数の指定が設定ファイルより優先される
+//   - ワーカーが読み書きするファイルを設定ファイルと環境変数で指定できる
+//
+// 異常系:
+//   - 未知の項目が指定された時、エラーが返却される
+//   - アドレスにポートがない時、エラーが返却される
+//   - クライアント証明書だけで秘密鍵がない時、エラーが返却される
+//   - 存在しないCA証明書が指定された時、エラーが返却される
+//   - 同時実行数が負の時、エラーが返却される
+//   - 環境変数の停止待ち時間が不正な時、エラーが返却される
+//   - 管理用HTTPサーバーのアドレスにポートがない時、エラーが返却される
+//   - 存在しないリトライポリシー定義ファイルが指定された時、エラーが返却される
+//   - 処理結果ストアの保存先のディレクトリが存在しない時、エラーが返却される
+func TestLoadWorkerConfig(t *testing.T) {
+	testcases := map[string]struct {
+		fileName    string
+		fileContent string
+		env         map[string]string
+
+		expectedConfig func(c WorkerConfig) WorkerConfig
+		expectedErr    bool
+	}{
+		"正常系: 設定ファイルがない時、デフォルトの設定が返却される": {
+			expectedConfig: func(c WorkerConfig) WorkerConfig { return c },
+		},
+		"正常系: YAMLファイルで指定した項目のみ上書きされる": {
+			fileName: "worker.yaml",
+			fileContent: `
+temporal:
+  address: temporal.example.com:7233
+  namespace: hotel
+worker:
+  max_concurrent_activities: 50
+  shutdown_timeout: 2m
+`,
+			expectedConfig: func(c WorkerConfig) WorkerConfig {
+				c.Temporal.Address = "temporal.example.com:7233"
+				c.Temporal.Namespace = "hotel"
+				c.Worker.MaxConcurrentActivities = 50
+				c.Worker.ShutdownTimeout = Duration(2 * time.Minute)
+				return c
+			},
+		},
+		"正常系: JSONファイルで指定した項目のみ上書きされる": {
+			fileName:    "worker.json",
+			fileContent: `{"worker": {"task_queue": "HOTEL_BOOKING_CANARY", "workflow_pollers": 4}}`,
+			expectedConfig: func(c WorkerConfig) WorkerConfig {
+				c.Worker.TaskQueue = "HOTEL_BOOKING_CANARY"
+				c.Worker.WorkflowPollers = 4
+				return c
+			},
+		},
+		"正常系: 環境変数の指定が設定ファイルより優先される": {
+			fileName:    "worker.yaml",
+			fileContent: "temporal:\n  namespace: hotel\nworker:\n  activity_pollers: 2\n",
+			env: map[string]string{
+				"TEMPORAL_NAMESPACE":                    "hotel-staging",
+				"HOTEL_BOOKING_WORKER_ACTIVITY_POLLERS": "8",
+				"HOTEL_BOOKING_WORKER_IDENTITY":         "worker-a",
+				"HOTEL_BOOKING_WORKER_SHUTDOWN_TIMEOUT": "45s",
+			},
+			expectedConfig: func(c WorkerConfig) WorkerConfig {
+				c.Temporal.Namespace = "hotel-staging"
+				c.Temporal.Identity = "worker-a"
+				c.Worker.ActivityPollers = 8
+				c.Worker.ShutdownTimeout = Duration(45 * time.Second)
+				return c
+			},
+		},
+		"正常系: ワーカーが読み書きするファイルを設定ファイルと環境変数で指定できる": {
+			fileName:    "worker.yaml",
+			fileContent: "files:\n  stuck_compensation: stuck.jsonl\n  idempotency_store: idempotency.db\n",
+			env:         map[string]string{"IDEMPOTENCY_STORE_FILE": "results.db"},
+			expectedConfig: func(c WorkerConfig) WorkerConfig {
+				c.Files.StuckCompensation = "stuck.jsonl"
+				c.Files.IdempotencyStore = "results.db"
+				return c
+			},
+		},
+		"異常系: 未知の項目が指定された時、エラーが返却される": {
+			fileName:    "worker.yaml",
+			fileContent: "worker:\n  max_concurrent_activity: 50\n",
+			expectedErr: true,
+		},
+		"異常系: アドレスにポートがない時、エラーが返却される": {
+			env:         map[string]string{"TEMPORAL_ADDRESS": "temporal.example.com"},
+			expectedErr: true,
+		},
+		"異常系: クライアント証明書だけで秘密鍵がない時、エラーが返却される": {
+			fileName:    "worker.yaml",
+			fileContent: "temporal:\n  tls:\n    cert_file: client.pem\n",
+			expectedErr: true,
+		},
+		"異常系: 存在しないCA証明書が指定された時、エラーが返却される": {
+			env:         map[string]string{"TEMPORAL_TLS_CA": "/nonexistent/ca.pem"},
+			expectedErr: true,
+		},
+		"異常系: 同時実行数が負の時、エラーが返却される": {
+			env:         map[string]string{"HOTEL_BOOKING_WORKER_MAX_CONCURRENT_WORKFLOW_TASKS": "-1"},
+			expectedErr: true,
+		},
+		"異常系: 環境変数の停止待ち時間が不正な時、エラーが返却される": {
+			env:         map[string]string{"HOTEL_BOOKING_WORKER_SHUTDOWN_TIMEOUT": "30"},
+			expectedErr: true,
+		},
+		"異常系: 管理用HTTPサーバーのアドレスにポートがない時、エラーが返却される": {
+			fileName:    "worker.yaml",
+			fileContent: "worker:\n  admin_address: localhost\n",
+			expectedErr: true,
+		},
+		"異常系: 存在しないリトライポリシー定義ファイルが指定された時、エラーが返却される": {
+			env:         map[string]string{"HOTEL_BOOKING_RETRY_POLICY_FILE": "/nonexistent/retry.yaml"},
+			expectedErr: true,
+		},
+		"異常系: 処理結果ストアの保存先のディレクトリが存在しない時、エラーが返却される": {
+			fileName:    "worker.yaml",
+			fileContent: "files:\n  idempotency_store: /nonexistent/idempotency.db\n",
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			path := ""
+			if tc.fileName != "" {
+				path = filepath.Join(t.TempDir(), tc.fileName)
+				assert.NoError(t, os.WriteFile(path, []byte(tc.fileContent), 0o644))
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			// when
+			actual, err := LoadWorkerConfig(path)
+
+			// then
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConfig(*DefaultWorkerConfig()), *actual)
+		})
+	}
+}
+
+// テストケースについて
+// 正常系:
+//   - ワーカー設定ファイルのtemporalの項目と環境変数から接続設定が読み込まれる
+//   - ワーカーのタスクキューが設定ファイルと環境変数から読み込まれる
+//   - ワーカーのidentityは引き継がない
+//
+// 異常系:
+//   - 環境変数のアドレスにポートがない時、エラーが返却される
+//   - タスクキューが空の時、エラーが返却される
+func TestLoadClientConfig(t *testing.T) {
+	testcases := map[string]struct {
+		fileContent string
+		env         map[string]string
+
+		expectedConfig ClientConfig
+		expectedErr    bool
+	}{
+		"正常系: ワーカー設定ファイルのtemporalの項目と環境変数から接続設定が読み込まれる": {
+			fileContent: "temporal:\n  address: temporal.example.com:7233\n  namespace: hotel\nworker:\n  activity_pollers: 2\n",
+			env: map[string]string{
+				"TEMPORAL_NAMESPACE":       "hotel-staging",
+				"TEMPORAL_TLS_SERVER_NAME": "temporal.example.com",
+			},
+			expectedConfig: ClientConfig{
+				Temporal: TemporalConfig{
+					Address:   "temporal.example.com:7233",
+					Namespace: "hotel-staging",
+					TLS:       TLSConfig{ServerName: "temporal.example.com"},
+				},
+				TaskQueue: TaskQueue,
+			},
+		},
+		"正常系: ワーカーのタスクキューが設定ファイルと環境変数から読み込まれる": {
+			fileContent: "worker:\n  task_queue: HOTEL_BOOKING_CANARY\n",
+			env:         map[string]string{"HOTEL_BOOKING_WORKER_TASK_QUEUE": "HOTEL_BOOKING_STAGING"},
+			expectedConfig: ClientConfig{
+				Temporal:  DefaultWorkerConfig().Temporal,
+				TaskQueue: "HOTEL_BOOKING_STAGING",
+			},
+		},
+		"正常系: ワーカーのidentityは引き継がない": {
+			fileContent: "temporal:\n  identity: worker-a\n",
+			env:         map[string]string{"HOTEL_BOOKING_WORKER_IDENTITY": "worker-b"},
+			expectedConfig: ClientConfig{
+				Temporal:  DefaultWorkerConfig().Temporal,
+				TaskQueue: TaskQueue,
+			},
+		},
+		"異常系: 環境変数のアドレスにポートがない時、エラーが返却される": {
+			env:         map[string]string{"TEMPORAL_ADDRESS": "temporal.example.com"},
+			expectedErr: true,
+		},
+		"異常系: タスクキューが空の時、エラーが返却される": {
+			env:         map[string]string{"HOTEL_BOOKING_WORKER_TASK_QUEUE": ""},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			path := ""
+			if tc.fileContent != "" {
+				path = filepath.Join(t.TempDir(), "worker.yaml")
+				assert.NoError(t, os.WriteFile(path, []byte(tc.fileContent), 0o644))
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			// when
+			actual, err := LoadClientConfig(path)
+
+			// then
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConfig, *actual)
+		})
+	}
+}
+
+// テストケースについて
+// 正常系:
+//   - TLSの指定がない時、TLSなしで接続するクライアントオプションに変換される
+//   - TLSを有効にした時、システムのルート証明書で検証するクライアントオプションに変換される
+//   - ワーカーの同時実行数と停止待ち時間がワーカーオプションに変換される
+func TestWorkerConfig_Options(t *testing.T) {
+	t.Run("正常系: TLSの指定がない時、TLSなしで接続する", func(t *testing.T) {
+		// given
+		cfg := DefaultWorkerConfig()
+		cfg.Temporal.Identity = "worker-a"
+
+		// when
+		options, err := cfg.ClientOptions()
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost:7233", options.HostPort)
+		assert.Equal(t, "default", options.Namespace)
+		assert.Equal(t, "worker-a", options.Identity)
+		assert.Nil(t, options.ConnectionOptions.TLS)
+	})
+
+	t.Run("正常系: TLSを有効にした時、システムのルート証明書で検証する", func(t *testing.T) {
+		// given
+		cfg := DefaultWorkerConfig()
+		cfg.Temporal.TLS.ServerName = "temporal.example.com"
+
+		// when
+		options, err := cfg.ClientOptions()
+
+		// then
+		assert.NoError(t, err)
+		if assert.NotNil(t, options.ConnectionOptions.TLS) {
+			assert.Equal(t, "temporal.example.com", options.ConnectionOptions.TLS.ServerName)
+			assert.Nil(t, options.ConnectionOptions.TLS.RootCAs)
+			assert.Empty(t, options.ConnectionOptions.TLS.Certificates)
+		}
+	})
+
+	t.Run("正常系: ワーカーの同時実行数と停止待ち時間が変換される", func(t *testing.T) {
+		// given
+		cfg := DefaultWorkerConfig()
+		cfg.Worker.MaxConcurrentActivities = 50
+		cfg.Worker.MaxConcurrentWorkflowTasks = 20
+		cfg.Worker.ActivityPollers = 4
+		cfg.Worker.WorkflowPollers = 2
+
+		// when
+		options := cfg.WorkerOptions()
+
+		// then
+		assert.Equal(t, 50, options.MaxConcurrentActivityExecutionSize)
+		assert.Equal(t, 20, options.MaxConcurrentWorkflowTaskExecutionSize)
+		assert.Equal(t, 4, options.MaxConcurrentActivityTaskPollers)
+		assert.Equal(t, 2, options.MaxConcurrentWorkflowTaskPollers)
+		assert.Equal(t, 30*time.Second, options.WorkerStopTimeout)
+	})
+}
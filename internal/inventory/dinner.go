@@ -0,0 +1,342 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrMenuNotFound 指定されたメニューが存在しない
+	ErrMenuNotFound = errors.New("menu not found")
+	// ErrOutOfStock 食材の在庫が不足している
+	ErrOutOfStock = errors.New("out of stock")
+)
+
+// Menu ディナーメニューと1名あたりの食材構成
+type Menu struct {
+	Code        string
+	Name        string
+	Ingredients []IngredientQuantity
+}
+
+// IngredientQuantity 食材と数量
+type IngredientQuantity struct {
+	Ingredient string `json:"ingredient"`
+	Quantity   int    `json:"quantity"`
+}
+
+// MenuCatalog メニューコードからメニューを引くカタログ
+type MenuCatalog map[string]Menu
+
+// NewMenuCatalog メニュー一覧からカタログを作成
+func NewMenuCatalog(menus []Menu) MenuCatalog {
+	catalog := make(MenuCatalog, len(menus))
+	for _, m := range menus {
+		catalog[m.Code] = m
+	}
+	return catalog
+}
+
+// Lookup メニューを取得
+func (c MenuCatalog) Lookup(code string) (Menu, error) {
+	menu, exists := c[code]
+	if !exists {
+		return Menu{}, fmt.Errorf("%w: %s", ErrMenuNotFound, code)
+	}
+	return menu, nil
+}
+
+// Requirements 指定人数分の必要な食材を返す
+func (m Menu) Requirements(guests int) []IngredientQuantity {
+	required := make([]IngredientQuantity, 0, len(m.Ingredients))
+	for _, ing := range m.Ingredients {
+		required = append(required, IngredientQuantity{Ingredient: ing.Ingredient, Quantity: ing.Quantity * guests})
+	}
+	return required
+}
+
+// DefaultMenus サンプル用のメニュー一覧
+func DefaultMenus() []Menu {
+	return []Menu{
+		{
+			Code: "standard",
+			Name: "スタンダードディナー",
+			Ingredients: []IngredientQuantity{
+				{Ingredient: "rice", Quantity: 1},
+				{Ingredient: "vegetables", Quantity: 2},
+				{Ingredient: "sea_bream", Quantity: 1},
+				{Ingredient: "dessert", Quantity: 1},
+			},
+		},
+		{
+			Code: "course",
+			Name: "和牛コース",
+			Ingredients: []IngredientQuantity{
+				{Ingredient: "wagyu", Quantity: 1},
+				{Ingredient: "sea_bream", Quantity: 1},
+				{Ingredient: "vegetables", Quantity: 3},
+				{Ingredient: "rice", Quantity: 1},
+				{Ingredient: "dessert", Quantity: 1},
+			},
+		},
+		{
+			Code: "vegetarian",
+			Name: "ベジタリアンコース",
+			Ingredients: []IngredientQuantity{
+				{Ingredient: "vegetables", Quantity: 4},
+				{Ingredient: "tofu", Quantity: 2},
+				{Ingredient: "rice", Quantity: 1},
+				{Ingredient: "dessert", Quantity: 1},
+			},
+		},
+	}
+}
+
+// DefaultStock サンプル用の食材在庫
+func DefaultStock() map[string]int {
+	return map[string]int{
+		"rice":       100,
+		"vegetables": 200,
+		"sea_bream":  30,
+		"wagyu":      20,
+		"tofu":       40,
+		"dessert":    100,
+	}
+}
+
+// Shortage 不足している食材
+type Shortage struct {
+	Ingredient string `json:"ingredient"`
+	Required   int    `json:"required"`
+	Available  int    `json:"available"`
+}
+
+// OutOfStockError 在庫不足の食材一覧を持つエラー
+type OutOfStockError struct {
+	Shortages []Shortage
+}
+
+func (e *OutOfStockError) Error() string {
+	parts := make([]string, 0, len(e.Shortages))
+	for _, s := range e.Shortages {
+		parts = append(parts, fmt.Sprintf("%s(required %d, available %d)", s.Ingredient, s.Required, s.Available))
+	}
+	return fmt.Sprintf("%s: %s", ErrOutOfStock, strings.Join(parts, ", "))
+}
+
+// Is errors.Is(err, ErrOutOfStock)で判定できるようにする
+func (e *OutOfStockError) Is(target error) bool {
+	return target == ErrOutOfStock
+}
+
+// LedgerEntry 食材在庫の入出庫記録
+// 引当は負の数量、戻し入れは正の数量で記録する
+type LedgerEntry struct {
+	ID         string `json:"id"`
+	BookingID  string `json:"booking_id"`
+	Ingredient string `json:"ingredient"`
+	Delta      int    `json:"delta"`
+}
+
+// IngredientReservation 予約に引き当てた食材
+type IngredientReservation struct {
+	ID        string               `json:"id"`
+	BookingID string               `json:"booking_id"`
+	Owner     string               `json:"owner,omitempty"` // 予約変更の前後の引当に共通する予約ID
+	MenuCode  string               `json:"menu_code"`
+	Guests    int                  `json:"guests"`
+	Items     []IngredientQuantity `json:"items"`
+	EntryIDs  []string             `json:"entry_ids"` // 引当時の入出庫記録
+}
+
+// IngredientLedger 食材在庫の台帳
+// 在庫の増減は全て入出庫記録として残し、予約ごとの引当を保持する
+type IngredientLedger struct {
+	mu           sync.Mutex
+	stock        map[string]int
+	entries      []LedgerEntry
+	reservations map[string]IngredientReservation // 予約ID -> 引当
+	sequence     int
+}
+
+// NewIngredientLedger 初期在庫から食材台帳を作成
+func NewIngredientLedger(stock map[string]int) *IngredientLedger {
+	l := &IngredientLedger{
+		stock:        make(map[string]int, len(stock)),
+		reservations: make(map[string]IngredientReservation),
+	}
+	for ingredient, quantity := range stock {
+		l.stock[ingredient] = quantity
+	}
+	return l
+}
+
+// Reserve メニューと人数から必要な食材をまとめて引き当てる
+// 1つでも不足する食材があれば何も引き当てずにOutOfStockErrorを返す
+// 同じ予約IDで既に引き当て済みの場合は、その引当を返す
+func (l *IngredientLedger) Reserve(bookingID string, menu Menu, guests int) (IngredientReservation, error) {
+	return l.ReserveFor(bookingID, "", menu, guests)
+}
+
+// ReserveFor Ownerを指定して食材を引き当てる（ownerが空の場合はbookingID）
+// 同じOwnerの引当（予約変更前の食材）は戻し入れられる前提で在庫に含めて判定するため、
+// 変更前の引当を戻し入れるまでの間は在庫数が一時的に負になることがある
+func (l *IngredientLedger) ReserveFor(bookingID, owner string, menu Menu, guests int) (IngredientReservation, error) {
+	if guests <= 0 {
+		return IngredientReservation{}, fmt.Errorf("guests must be greater than 0: %d", guests)
+	}
+	required := menu.Requirements(guests)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if reservation, exists := l.reservations[bookingID]; exists {
+		return reservation, nil
+	}
+
+	held := l.heldBy(owner, bookingID)
+	var shortages []Shortage
+	for _, r := range required {
+		if available := l.stock[r.Ingredient] + held[r.Ingredient]; available < r.Quantity {
+			shortages = append(shortages, Shortage{Ingredient: r.Ingredient, Required: r.Quantity, Available: available})
+		}
+	}
+	if len(shortages) > 0 {
+		sort.Slice(shortages, func(i, j int) bool { return shortages[i].Ingredient < shortages[j].Ingredient })
+		return IngredientReservation{}, &OutOfStockError{Shortages: shortages}
+	}
+
+	l.sequence++
+	reservation := IngredientReservation{
+		ID:        fmt.Sprintf("food-%06d", l.sequence),
+		BookingID: bookingID,
+		Owner:     owner,
+		MenuCode:  menu.Code,
+		Guests:    guests,
+		Items:     required,
+	}
+	for _, r := range required {
+		l.stock[r.Ingredient] -= r.Quantity
+		reservation.EntryIDs = append(reservation.EntryIDs, l.record(bookingID, r.Ingredient, -r.Quantity))
+	}
+	l.reservations[bookingID] = reservation
+	return reservation, nil
+}
+
+// Restock 予約で引き当てた食材をそのまま在庫に戻す
+// 引当が存在しない場合は戻し入れ済みとして何もしない
+func (l *IngredientLedger) Restock(bookingID, reservationID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	reservation, exists := l.reservations[bookingID]
+	if !exists || reservation.ID != reservationID {
+		return
+	}
+	for _, item := range reservation.Items {
+		l.stock[item.Ingredient] += item.Quantity
+		l.record(bookingID, item.Ingredient, item.Quantity)
+	}
+	delete(l.reservations, bookingID)
+}
+
+// Stock 食材の現在の在庫数
+func (l *IngredientLedger) Stock(ingredient string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.stock[ingredient]
+}
+
+// Entries 入出庫記録の一覧を記録順に返す
+func (l *IngredientLedger) Entries() []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]LedgerEntry(nil), l.entries...)
+}
+
+// LedgerSnapshot 食材台帳の状態（保存・復元用）
+type LedgerSnapshot struct {
+	Stock        map[string]int          `json:"stock"`
+	Entries      []LedgerEntry           `json:"entries"`
+	Reservations []IngredientReservation `json:"reservations"`
+	Sequence     int                     `json:"sequence"`
+}
+
+// Snapshot 現在の在庫数・入出庫記録・引当を返す（引当は予約ID順）
+func (l *IngredientLedger) Snapshot() LedgerSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := LedgerSnapshot{
+		Stock:        make(map[string]int, len(l.stock)),
+		Entries:      append([]LedgerEntry(nil), l.entries...),
+		Reservations: make([]IngredientReservation, 0, len(l.reservations)),
+		Sequence:     l.sequence,
+	}
+	for ingredient, quantity := range l.stock {
+		snapshot.Stock[ingredient] = quantity
+	}
+	for _, reservation := range l.reservations {
+		snapshot.Reservations = append(snapshot.Reservations, reservation)
+	}
+	sort.Slice(snapshot.Reservations, func(i, j int) bool {
+		return snapshot.Reservations[i].BookingID < snapshot.Reservations[j].BookingID
+	})
+	return snapshot
+}
+
+// Restore Snapshotで保存しておいた状態から台帳を復元する
+// 呼び出し前の在庫数・入出庫記録・引当は破棄する
+func (l *IngredientLedger) Restore(snapshot LedgerSnapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stock = make(map[string]int, len(snapshot.Stock))
+	for ingredient, quantity := range snapshot.Stock {
+		l.stock[ingredient] = quantity
+	}
+	l.entries = append([]LedgerEntry(nil), snapshot.Entries...)
+	l.reservations = make(map[string]IngredientReservation, len(snapshot.Reservations))
+	for _, reservation := range snapshot.Reservations {
+		l.reservations[reservation.BookingID] = reservation
+	}
+	l.sequence = snapshot.Sequence
+}
+
+// heldBy ownerの引当が保持している食材の数量（呼び出し元でロックを取得済みであること）
+func (l *IngredientLedger) heldBy(owner, bookingID string) map[string]int {
+	if owner == "" {
+		owner = bookingID
+	}
+	held := make(map[string]int)
+	for _, reservation := range l.reservations {
+		reservationOwner := reservation.Owner
+		if reservationOwner == "" {
+			reservationOwner = reservation.BookingID
+		}
+		if reservationOwner != owner {
+			continue
+		}
+		for _, item := range reservation.Items {
+			held[item.Ingredient] += item.Quantity
+		}
+	}
+	return held
+}
+
+// record 入出庫記録を追加し、記録IDを返す（呼び出し元でロックを取得済みであること）
+func (l *IngredientLedger) record(bookingID, ingredient string, delta int) string {
+	entry := LedgerEntry{
+		ID:         fmt.Sprintf("ledger-%06d", len(l.entries)+1),
+		BookingID:  bookingID,
+		Ingredient: ingredient,
+		Delta:      delta,
+	}
+	l.entries = append(l.entries, entry)
+	return entry.ID
+}
@@ -0,0 +1,90 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - 人数分の食材が在庫から引き当てられ、入出庫記録が残る
+//   - 同じ予約IDで再度引き当てた場合、同じ引当が返却され在庫は減らない
+//   - 戻し入れで引き当てた分だけ在庫が戻る
+//
+// 異常系:
+//   - 1つでも食材が不足する場合、何も引き当てずに不足食材の一覧が返却される
+func TestIngredientLedger_ReserveRestock(t *testing.T) {
+	// given
+	menu := Menu{Code: "test", Ingredients: []IngredientQuantity{
+		{Ingredient: "beef", Quantity: 1},
+		{Ingredient: "rice", Quantity: 2},
+	}}
+	sut := NewIngredientLedger(map[string]int{"beef": 3, "rice": 10})
+
+	// when: 2名分を引き当てる
+	reservation, err := sut.Reserve("booking-1", menu, 2)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []IngredientQuantity{{Ingredient: "beef", Quantity: 2}, {Ingredient: "rice", Quantity: 4}}, reservation.Items)
+	assert.Equal(t, []string{"ledger-000001", "ledger-000002"}, reservation.EntryIDs)
+	assert.Equal(t, 1, sut.Stock("beef"))
+	assert.Equal(t, 6, sut.Stock("rice"))
+
+	// when: 同じ予約IDで再度引き当てる
+	again, err := sut.Reserve("booking-1", menu, 2)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, reservation, again)
+	assert.Equal(t, 1, sut.Stock("beef"))
+
+	// when: 牛肉が足りない予約
+	_, err = sut.Reserve("booking-2", menu, 2)
+
+	// then: 米も含めて何も引き当てられない
+	var outOfStock *OutOfStockError
+	assert.True(t, errors.As(err, &outOfStock))
+	assert.True(t, errors.Is(err, ErrOutOfStock))
+	assert.Equal(t, []Shortage{{Ingredient: "beef", Required: 2, Available: 1}}, outOfStock.Shortages)
+	assert.Equal(t, 6, sut.Stock("rice"))
+
+	// when: 戻し入れる
+	sut.Restock("booking-1", reservation.ID)
+	sut.Restock("booking-1", reservation.ID)
+
+	// then: 引き当てた分だけ1回だけ戻る
+	assert.Equal(t, 3, sut.Stock("beef"))
+	assert.Equal(t, 10, sut.Stock("rice"))
+	assert.Equal(t, []LedgerEntry{
+		{ID: "ledger-000001", BookingID: "booking-1", Ingredient: "beef", Delta: -2},
+		{ID: "ledger-000002", BookingID: "booking-1", Ingredient: "rice", Delta: -4},
+		{ID: "ledger-000003", BookingID: "booking-1", Ingredient: "beef", Delta: 2},
+		{ID: "ledger-000004", BookingID: "booking-1", Ingredient: "rice", Delta: 4},
+	}, sut.Entries())
+}
+
+func TestIngredientLedger_ReserveForSameOwner(t *testing.T) {
+	// given: 牛肉3つのうち2つを引き当て済み
+	menu := Menu{Code: "test", Ingredients: []IngredientQuantity{{Ingredient: "beef", Quantity: 1}}}
+	sut := NewIngredientLedger(map[string]int{"beef": 3})
+	original, err := sut.ReserveFor("booking-1", "booking-1", menu, 2)
+	assert.NoError(t, err)
+
+	// when: 別の予約が2名分を引き当てる
+	_, err = sut.ReserveFor("booking-2", "booking-2", menu, 2)
+
+	// then
+	assert.True(t, errors.Is(err, ErrOutOfStock))
+
+	// when: 同じ予約の変更として3名分を引き当て、変更前の引当を戻し入れる
+	revised, err := sut.ReserveFor("booking-1@r1", "booking-1", menu, 3)
+	assert.NoError(t, err)
+	sut.Restock(original.BookingID, original.ID)
+
+	// then: 変更後の3名分だけが引き当てられている
+	assert.Equal(t, []IngredientQuantity{{Ingredient: "beef", Quantity: 3}}, revised.Items)
+	assert.Equal(t, 0, sut.Stock("beef"))
+}
@@ -0,0 +1,364 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRoomType 客室タイプが指定されなかった場合の客室タイプ
+const DefaultRoomType = "standard"
+
+// MaxStayNights 1回の予約で宿泊できる最大泊数
+const MaxStayNights = 30
+
+const nightLayout = "2006-01-02"
+
+var (
+	// ErrHotelNotFound 指定されたホテルが存在しない
+	ErrHotelNotFound = errors.New("hotel not found")
+	// ErrRoomTypeNotFound 指定された客室タイプが存在しない
+	ErrRoomTypeNotFound = errors.New("room type not found")
+	// ErrInvalidStay 宿泊期間が不正
+	ErrInvalidStay = errors.New("invalid stay")
+	// ErrRoomFull 宿泊期間を通して空いている客室がない
+	ErrRoomFull = errors.New("room full")
+)
+
+// Hotel ホテル
+type Hotel struct {
+	ID        string
+	Name      string
+	RoomTypes []RoomType
+}
+
+// RoomType 客室タイプと、そのタイプに属する客室番号
+type RoomType struct {
+	Code  string
+	Rooms []string
+}
+
+// Stay 宿泊の予約内容
+type Stay struct {
+	BookingID string
+	Owner     string // 予約変更の前後の割り当てに共通する予約ID（空の場合はBookingID）
+	HotelID   string
+	RoomType  string
+	CheckIn   time.Time
+	CheckOut  time.Time
+}
+
+// Nights 宿泊する夜の一覧（チェックイン日からチェックアウト前日まで）
+func (s Stay) Nights() ([]string, error) {
+	checkIn := truncateToDate(s.CheckIn)
+	checkOut := truncateToDate(s.CheckOut)
+	if s.CheckIn.IsZero() || s.CheckOut.IsZero() || !checkOut.After(checkIn) {
+		return nil, fmt.Errorf("%w: check-out must be after check-in", ErrInvalidStay)
+	}
+
+	var nights []string
+	for d := checkIn; d.Before(checkOut); d = d.AddDate(0, 0, 1) {
+		nights = append(nights, d.Format(nightLayout))
+	}
+	if len(nights) > MaxStayNights {
+		return nil, fmt.Errorf("%w: stay exceeds %d nights", ErrInvalidStay, MaxStayNights)
+	}
+	return nights, nil
+}
+
+// owner 割り当てを共有できる予約ID
+func (s Stay) owner() string {
+	if s.Owner == "" {
+		return s.BookingID
+	}
+	return s.Owner
+}
+
+// RoomAllocation 予約に割り当てられた客室
+type RoomAllocation struct {
+	ID         string   `json:"id"`
+	BookingID  string   `json:"booking_id"`
+	Owner      string   `json:"owner,omitempty"` // 予約変更の前後の割り当てに共通する予約ID
+	HotelID    string   `json:"hotel_id"`
+	RoomType   string   `json:"room_type"`
+	RoomNumber string   `json:"room_number"`
+	Nights     []string `json:"nights"`
+}
+
+// owner 割り当てを共有できる予約ID
+func (a RoomAllocation) owner() string {
+	if a.Owner == "" {
+		return a.BookingID
+	}
+	return a.Owner
+}
+
+// hasNight 指定した宿泊日を含むかどうか
+func (a RoomAllocation) hasNight(night string) bool {
+	for _, n := range a.Nights {
+		if n == night {
+			return true
+		}
+	}
+	return false
+}
+
+// roomKey 客室を一意に識別するキー
+type roomKey struct {
+	hotelID    string
+	roomNumber string
+}
+
+// HotelInventory ホテルの客室在庫（客室ごとの宿泊日単位の空き状況）
+type HotelInventory struct {
+	mu          sync.Mutex
+	hotels      map[string]Hotel
+	occupied    map[roomKey]map[string]string // 客室 -> 宿泊日 -> 予約ID（同じ予約の変更前後で重なる宿泊日は新しい割り当て）
+	allocations map[string]RoomAllocation     // 予約ID -> 割り当て
+}
+
+// NewHotelInventory ホテル一覧から客室在庫を作成
+func NewHotelInventory(hotels []Hotel) *HotelInventory {
+	inv := &HotelInventory{
+		hotels:      make(map[string]Hotel, len(hotels)),
+		occupied:    make(map[roomKey]map[string]string),
+		allocations: make(map[string]RoomAllocation),
+	}
+	for _, h := range hotels {
+		inv.hotels[h.ID] = h
+	}
+	return inv
+}
+
+// DefaultHotels サンプル用のホテル一覧
+func DefaultHotels() []Hotel {
+	return []Hotel{
+		{
+			ID:   "hotel-001",
+			Name: "テンポラルホテル東京",
+			RoomTypes: []RoomType{
+				{Code: "standard", Rooms: []string{"101", "102", "103", "104", "105"}},
+				{Code: "deluxe", Rooms: []string{"201", "202", "203"}},
+				{Code: "suite", Rooms: []string{"301"}},
+			},
+		},
+		{
+			ID:   "hotel-002",
+			Name: "テンポラルホテル大阪",
+			RoomTypes: []RoomType{
+				{Code: "standard", Rooms: []string{"101", "102", "103"}},
+				{Code: "deluxe", Rooms: []string{"201"}},
+			},
+		},
+	}
+}
+
+// Reserve 宿泊期間を通して空いている客室を1室割り当てる
+// 同じ予約IDで既に割り当て済みの場合は、その割り当てを返す
+// 同じOwnerの割り当て（予約変更前の客室）が使っている宿泊日は空きとして扱い、変更前と同じ客室を優先する
+// 変更前の割り当てを解放するまでの間、重なる宿泊日は新しい割り当てのものとなる
+func (inv *HotelInventory) Reserve(stay Stay) (RoomAllocation, error) {
+	roomTypeCode := stay.RoomType
+	if roomTypeCode == "" {
+		roomTypeCode = DefaultRoomType
+	}
+
+	nights, err := stay.Nights()
+	if err != nil {
+		return RoomAllocation{}, err
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if allocation, exists := inv.allocations[stay.BookingID]; exists {
+		return allocation, nil
+	}
+
+	hotel, exists := inv.hotels[stay.HotelID]
+	if !exists {
+		return RoomAllocation{}, fmt.Errorf("%w: %s", ErrHotelNotFound, stay.HotelID)
+	}
+	roomType, exists := findRoomType(hotel, roomTypeCode)
+	if !exists {
+		return RoomAllocation{}, fmt.Errorf("%w: %s", ErrRoomTypeNotFound, roomTypeCode)
+	}
+
+	for _, roomNumber := range inv.preferredRooms(stay.owner(), hotel.ID, roomType.Rooms) {
+		key := roomKey{hotelID: hotel.ID, roomNumber: roomNumber}
+		if !inv.isAvailable(key, nights, stay.owner()) {
+			continue
+		}
+
+		if inv.occupied[key] == nil {
+			inv.occupied[key] = make(map[string]string)
+		}
+		for _, night := range nights {
+			inv.occupied[key][night] = stay.BookingID
+		}
+		// 予約変更の前後で同じ客室・同じ初日になっても区別できるよう、予約IDを含める
+		allocation := RoomAllocation{
+			ID:         fmt.Sprintf("room-%s-%s-%s-%s", hotel.ID, roomNumber, nights[0], stay.BookingID),
+			BookingID:  stay.BookingID,
+			Owner:      stay.Owner,
+			HotelID:    hotel.ID,
+			RoomType:   roomType.Code,
+			RoomNumber: roomNumber,
+			Nights:     nights,
+		}
+		inv.allocations[stay.BookingID] = allocation
+		return allocation, nil
+	}
+
+	return RoomAllocation{}, fmt.Errorf("%w: %s %s %s-%s", ErrRoomFull, hotel.ID, roomType.Code, nights[0], nights[len(nights)-1])
+}
+
+// Release 予約に割り当てた客室の宿泊日を解放する
+// 同じOwnerの別の割り当てが同じ宿泊日を含む場合は、解放せずにその割り当てに戻す
+// 割り当てが存在しない場合は解放済みとして何もしない
+func (inv *HotelInventory) Release(bookingID, allocationID string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	allocation, exists := inv.allocations[bookingID]
+	if !exists || allocation.ID != allocationID {
+		return
+	}
+
+	delete(inv.allocations, bookingID)
+	key := roomKey{hotelID: allocation.HotelID, roomNumber: allocation.RoomNumber}
+	for _, night := range allocation.Nights {
+		if inv.occupied[key][night] != bookingID {
+			continue
+		}
+		if sibling, exists := inv.siblingAllocation(allocation, night); exists {
+			inv.occupied[key][night] = sibling.BookingID
+			continue
+		}
+		delete(inv.occupied[key], night)
+	}
+}
+
+// Available 宿泊期間を通して空いている客室数を返す
+func (inv *HotelInventory) Available(hotelID, roomTypeCode string, checkIn, checkOut time.Time) (int, error) {
+	nights, err := Stay{CheckIn: checkIn, CheckOut: checkOut}.Nights()
+	if err != nil {
+		return 0, err
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	hotel, exists := inv.hotels[hotelID]
+	if !exists {
+		return 0, fmt.Errorf("%w: %s", ErrHotelNotFound, hotelID)
+	}
+	roomType, exists := findRoomType(hotel, roomTypeCode)
+	if !exists {
+		return 0, fmt.Errorf("%w: %s", ErrRoomTypeNotFound, roomTypeCode)
+	}
+
+	available := 0
+	for _, roomNumber := range roomType.Rooms {
+		if inv.isAvailable(roomKey{hotelID: hotelID, roomNumber: roomNumber}, nights, "") {
+			available++
+		}
+	}
+	return available, nil
+}
+
+// Allocations 現在の割り当て一覧を予約ID順に返す
+func (inv *HotelInventory) Allocations() []RoomAllocation {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	list := make([]RoomAllocation, 0, len(inv.allocations))
+	for _, allocation := range inv.allocations {
+		list = append(list, allocation)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BookingID < list[j].BookingID })
+	return list
+}
+
+// Restore 保存しておいた割り当て一覧から在庫の状態を復元する
+// 呼び出し前の割り当ては破棄する
+func (inv *HotelInventory) Restore(allocations []RoomAllocation) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.occupied = make(map[roomKey]map[string]string)
+	inv.allocations = make(map[string]RoomAllocation, len(allocations))
+	for _, allocation := range allocations {
+		key := roomKey{hotelID: allocation.HotelID, roomNumber: allocation.RoomNumber}
+		if inv.occupied[key] == nil {
+			inv.occupied[key] = make(map[string]string)
+		}
+		for _, night := range allocation.Nights {
+			inv.occupied[key][night] = allocation.BookingID
+		}
+		inv.allocations[allocation.BookingID] = allocation
+	}
+}
+
+// isAvailable 客室が全ての宿泊日で空いているか（呼び出し元でロックを取得済みであること）
+// ownerの割り当てが使っている宿泊日は空きとして扱う
+func (inv *HotelInventory) isAvailable(key roomKey, nights []string, owner string) bool {
+	for _, night := range nights {
+		bookingID, taken := inv.occupied[key][night]
+		if taken && (owner == "" || inv.allocations[bookingID].owner() != owner) {
+			return false
+		}
+	}
+	return true
+}
+
+// preferredRooms ownerが既に割り当てられている客室を先頭にした客室番号の一覧（呼び出し元でロックを取得済みであること）
+func (inv *HotelInventory) preferredRooms(owner, hotelID string, rooms []string) []string {
+	held := make(map[string]bool)
+	for _, allocation := range inv.allocations {
+		if allocation.owner() == owner && allocation.HotelID == hotelID {
+			held[allocation.RoomNumber] = true
+		}
+	}
+	ordered := make([]string, 0, len(rooms))
+	for _, roomNumber := range rooms {
+		if held[roomNumber] {
+			ordered = append(ordered, roomNumber)
+		}
+	}
+	for _, roomNumber := range rooms {
+		if !held[roomNumber] {
+			ordered = append(ordered, roomNumber)
+		}
+	}
+	return ordered
+}
+
+// siblingAllocation 同じ客室の同じ宿泊日を含む、同じOwnerの別の割り当てを探す（呼び出し元でロックを取得済みであること）
+func (inv *HotelInventory) siblingAllocation(released RoomAllocation, night string) (RoomAllocation, bool) {
+	for _, allocation := range inv.allocations {
+		if allocation.BookingID != released.BookingID && allocation.owner() == released.owner() &&
+			allocation.HotelID == released.HotelID && allocation.RoomNumber == released.RoomNumber && allocation.hasNight(night) {
+			return allocation, true
+		}
+	}
+	return RoomAllocation{}, false
+}
+
+// findRoomType ホテルから客室タイプを探す
+func findRoomType(hotel Hotel, code string) (RoomType, bool) {
+	for _, rt := range hotel.RoomTypes {
+		if rt.Code == code {
+			return rt, true
+		}
+	}
+	return RoomType{}, false
+}
+
+// truncateToDate 日付部分のみを残す（タイムゾーンはUTCに揃える）
+func truncateToDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
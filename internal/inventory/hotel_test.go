@@ -0,0 +1,182 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - チェックイン日からチェックアウト前日までの宿泊日が返却される
+//   - 時刻やタイムゾーンに関わらず日付単位で数えられる
+//
+// 異常系:
+//   - チェックアウトがチェックイン以前の時、ErrInvalidStayが返却される
+//   - 宿泊日が上限を超える時、ErrInvalidStayが返却される
+func TestStay_Nights(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	testcases := map[string]struct {
+		stay           Stay
+		expectedNights []string
+		expectedErr    error
+	}{
+		"正常系: チェックイン日からチェックアウト前日までの宿泊日が返却される": {
+			stay: Stay{
+				CheckIn:  time.Date(2026, 11, 30, 15, 0, 0, 0, time.UTC),
+				CheckOut: time.Date(2026, 12, 2, 10, 0, 0, 0, time.UTC),
+			},
+			expectedNights: []string{"2026-11-30", "2026-12-01"},
+		},
+		"正常系: 時刻やタイムゾーンに関わらずUTCの日付単位で数えられる": {
+			stay: Stay{
+				CheckIn:  time.Date(2026, 11, 2, 8, 0, 0, 0, jst), // 2026-11-01 23:00 UTC
+				CheckOut: time.Date(2026, 11, 2, 12, 0, 0, 0, jst),
+			},
+			expectedNights: []string{"2026-11-01"},
+		},
+		"異常系: チェックアウトがチェックイン以前の時、ErrInvalidStayが返却される": {
+			stay: Stay{
+				CheckIn:  time.Date(2026, 11, 2, 15, 0, 0, 0, time.UTC),
+				CheckOut: time.Date(2026, 11, 2, 20, 0, 0, 0, time.UTC),
+			},
+			expectedErr: ErrInvalidStay,
+		},
+		"異常系: 宿泊日が上限を超える時、ErrInvalidStayが返却される": {
+			stay: Stay{
+				CheckIn:  time.Date(2026, 11, 1, 15, 0, 0, 0, time.UTC),
+				CheckOut: time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC).AddDate(0, 0, MaxStayNights+1),
+			},
+			expectedErr: ErrInvalidStay,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given - テストケースで設定済み
+
+			// when
+			actualNights, actualErr := tc.stay.Nights()
+
+			// then
+			assert.Equal(t, tc.expectedNights, actualNights)
+			assert.True(t, errors.Is(actualErr, tc.expectedErr), "expected %v, got %v", tc.expectedErr, actualErr)
+		})
+	}
+}
+
+// テストケースについて
+// 正常系:
+//   - 客室ごとに宿泊日単位で空きが管理され、重ならない予約は同じ客室に入る
+//   - 解放した宿泊日は再び予約できる
+//
+// 異常系:
+//   - 全ての客室が埋まっている時、ErrRoomFullが返却される
+func TestHotelInventory_ReserveRelease(t *testing.T) {
+	// given: 2室だけのホテル
+	sut := NewHotelInventory([]Hotel{
+		{ID: "hotel-test", RoomTypes: []RoomType{{Code: "standard", Rooms: []string{"101", "102"}}}},
+	})
+	day := func(d int) time.Time { return time.Date(2026, 11, d, 0, 0, 0, 0, time.UTC) }
+	stay := func(bookingID string, from, to int) Stay {
+		return Stay{BookingID: bookingID, HotelID: "hotel-test", CheckIn: day(from), CheckOut: day(to)}
+	}
+
+	// when: 1日〜3日を2件予約し、3日〜4日を1件予約する
+	first, err := sut.Reserve(stay("booking-1", 1, 3))
+	assert.NoError(t, err)
+	second, err := sut.Reserve(stay("booking-2", 1, 3))
+	assert.NoError(t, err)
+	third, err := sut.Reserve(stay("booking-3", 3, 4))
+	assert.NoError(t, err)
+
+	// then
+	assert.Equal(t, "101", first.RoomNumber)
+	assert.Equal(t, "102", second.RoomNumber)
+	assert.Equal(t, "101", third.RoomNumber)
+
+	// when: 2日〜3日は満室
+	_, err = sut.Reserve(stay("booking-4", 2, 3))
+
+	// then
+	assert.True(t, errors.Is(err, ErrRoomFull))
+	available, err := sut.Available("hotel-test", "standard", day(2), day(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, available)
+
+	// when: 1件目を解放する
+	sut.Release(first.BookingID, first.ID)
+
+	// then: 解放した客室が再び予約できる
+	available, err = sut.Available("hotel-test", "standard", day(2), day(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, available)
+	fourth, err := sut.Reserve(stay("booking-4", 2, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, "101", fourth.RoomNumber)
+}
+
+// テストケースについて
+// 正常系:
+//   - 同じOwnerの割り当てと重なる宿泊日は空きとして扱い、同じ客室を割り当てる
+//   - 変更後の割り当てを解放した場合、重なる宿泊日は変更前の割り当てに戻る
+//   - 変更前の割り当てを解放した場合、重なる宿泊日は変更後の割り当てに残る
+//   - 同じ客室・同じ初日で予約し直した場合も、変更ごとに別の割り当てIDになる
+//
+// 異常系:
+//   - Ownerが異なる予約は重なる宿泊日を共有できない
+func TestHotelInventory_ReserveSameOwner(t *testing.T) {
+	// given: 1室だけのホテルで1日〜3日を予約済み
+	sut := NewHotelInventory([]Hotel{
+		{ID: "hotel-test", RoomTypes: []RoomType{{Code: "standard", Rooms: []string{"101"}}}},
+	})
+	day := func(d int) time.Time { return time.Date(2026, 11, d, 0, 0, 0, 0, time.UTC) }
+	stay := func(bookingID, owner string, from, to int) Stay {
+		return Stay{BookingID: bookingID, Owner: owner, HotelID: "hotel-test", CheckIn: day(from), CheckOut: day(to)}
+	}
+	original, err := sut.Reserve(stay("booking-1", "booking-1", 1, 3))
+	assert.NoError(t, err)
+
+	// when: 別の予約が2日〜4日を予約する
+	_, err = sut.Reserve(stay("booking-2", "booking-2", 2, 4))
+
+	// then
+	assert.True(t, errors.Is(err, ErrRoomFull))
+
+	// when: 同じ予約の変更として2日〜4日を予約する
+	revised, err := sut.Reserve(stay("booking-1@r1", "booking-1", 2, 4))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "101", revised.RoomNumber)
+
+	// when: 変更後の割り当てを解放する（ロールバック）
+	sut.Release(revised.BookingID, revised.ID)
+
+	// then: 変更前の1日〜3日は確保されたまま、3日〜4日だけが空く
+	available, err := sut.Available("hotel-test", "standard", day(2), day(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, available)
+	available, err = sut.Available("hotel-test", "standard", day(3), day(4))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, available)
+
+	// when: もう一度変更し、変更前の割り当てを解放する
+	releasedID := revised.ID
+	revised, err = sut.Reserve(stay("booking-1@r2", "booking-1", 2, 4))
+	assert.NoError(t, err)
+	assert.NotEqual(t, releasedID, revised.ID, "同じ客室・同じ初日でも変更ごとに別の割り当てIDになる")
+	sut.Release(original.BookingID, original.ID)
+
+	// then: 1日〜2日だけが空き、2日〜4日は変更後の割り当てに残る
+	available, err = sut.Available("hotel-test", "standard", day(1), day(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, available)
+	available, err = sut.Available("hotel-test", "standard", day(2), day(4))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, available)
+	assert.Equal(t, []RoomAllocation{revised}, sut.Allocations())
+}
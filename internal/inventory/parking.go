@@ -0,0 +1,269 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 駐車スペースの種類
+const (
+	SpaceTypeStandard   = "standard"
+	SpaceTypeEV         = "ev"
+	SpaceTypeAccessible = "accessible"
+	SpaceTypeOversize   = "oversize"
+)
+
+var (
+	// ErrSpaceTypeNotFound 指定された駐車スペースの種類が存在しない
+	ErrSpaceTypeNotFound = errors.New("space type not found")
+	// ErrInvalidWindow 駐車場の利用時間が不正
+	ErrInvalidWindow = errors.New("invalid parking window")
+	// ErrParkingFull 利用時間を通して空いている駐車スペースがない
+	ErrParkingFull = errors.New("parking full")
+)
+
+// compatibleSpaceTypes 要求された種類に対して割り当て可能な駐車スペースの種類（優先順）
+// 普通車は大型スペースにも駐車できるが、EV・車椅子用・大型は専用スペースのみ
+var compatibleSpaceTypes = map[string][]string{
+	SpaceTypeStandard:   {SpaceTypeStandard, SpaceTypeOversize},
+	SpaceTypeEV:         {SpaceTypeEV},
+	SpaceTypeAccessible: {SpaceTypeAccessible},
+	SpaceTypeOversize:   {SpaceTypeOversize},
+}
+
+// ParkingLot 駐車場
+type ParkingLot struct {
+	ID     string
+	Name   string
+	Spaces []ParkingSpace
+}
+
+// ParkingSpace 駐車スペース
+type ParkingSpace struct {
+	ID   string
+	Type string
+}
+
+// ParkingWindow 駐車場の利用予約内容
+type ParkingWindow struct {
+	BookingID string
+	Owner     string // 予約変更の前後の割り当てに共通する予約ID（空の場合はBookingID）
+	SpaceType string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// overlaps 利用時間が重なるかどうか（終了時刻と開始時刻が同じ場合は重ならない）
+func (w ParkingWindow) overlaps(start, end time.Time) bool {
+	return w.StartTime.Before(end) && w.EndTime.After(start)
+}
+
+// owner 駐車スペースを共有できる予約ID
+func (w ParkingWindow) owner() string {
+	if w.Owner == "" {
+		return w.BookingID
+	}
+	return w.Owner
+}
+
+// SpaceAllocation 予約に割り当てられた駐車スペース
+type SpaceAllocation struct {
+	ID        string    `json:"id"`
+	BookingID string    `json:"booking_id"`
+	Owner     string    `json:"owner,omitempty"` // 予約変更の前後の割り当てに共通する予約ID
+	LotID     string    `json:"lot_id"`
+	SpaceID   string    `json:"space_id"`
+	SpaceType string    `json:"space_type"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// spaceKey 駐車スペースを一意に識別するキー
+type spaceKey struct {
+	lotID   string
+	spaceID string
+}
+
+// ParkingInventory 駐車場の空き状況（駐車スペースごとの利用時間帯）
+type ParkingInventory struct {
+	mu          sync.Mutex
+	lots        []ParkingLot
+	windows     map[spaceKey][]ParkingWindow
+	allocations map[string]SpaceAllocation // 予約ID -> 割り当て
+}
+
+// NewParkingInventory 駐車場一覧から空き状況を作成
+func NewParkingInventory(lots []ParkingLot) *ParkingInventory {
+	return &ParkingInventory{
+		lots:        lots,
+		windows:     make(map[spaceKey][]ParkingWindow),
+		allocations: make(map[string]SpaceAllocation),
+	}
+}
+
+// DefaultParkingLots サンプル用の駐車場一覧
+func DefaultParkingLots() []ParkingLot {
+	return []ParkingLot{
+		{
+			ID:   "lot-001",
+			Name: "ホテル本館駐車場",
+			Spaces: []ParkingSpace{
+				{ID: "S-01", Type: SpaceTypeStandard},
+				{ID: "S-02", Type: SpaceTypeStandard},
+				{ID: "S-03", Type: SpaceTypeStandard},
+				{ID: "S-04", Type: SpaceTypeStandard},
+				{ID: "EV-01", Type: SpaceTypeEV},
+				{ID: "EV-02", Type: SpaceTypeEV},
+				{ID: "A-01", Type: SpaceTypeAccessible},
+				{ID: "O-01", Type: SpaceTypeOversize},
+			},
+		},
+		{
+			ID:   "lot-002",
+			Name: "第2駐車場",
+			Spaces: []ParkingSpace{
+				{ID: "S-01", Type: SpaceTypeStandard},
+				{ID: "S-02", Type: SpaceTypeStandard},
+				{ID: "O-01", Type: SpaceTypeOversize},
+			},
+		},
+	}
+}
+
+// Reserve 利用時間を通して空いている駐車スペースを1つ割り当てる
+// 要求された種類のスペースを優先し、なければ互換性のある種類のスペースを割り当てる
+// 同じ予約IDで既に割り当て済みの場合は、その割り当てを返す
+// 同じOwnerの割り当て（予約変更前の駐車スペース）が使っている時間帯は空きとして扱う
+func (inv *ParkingInventory) Reserve(window ParkingWindow) (SpaceAllocation, error) {
+	candidates, exists := compatibleSpaceTypes[window.SpaceType]
+	if !exists {
+		return SpaceAllocation{}, fmt.Errorf("%w: %s", ErrSpaceTypeNotFound, window.SpaceType)
+	}
+	if window.StartTime.IsZero() || !window.EndTime.After(window.StartTime) {
+		return SpaceAllocation{}, fmt.Errorf("%w: end time must be after start time", ErrInvalidWindow)
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if allocation, exists := inv.allocations[window.BookingID]; exists {
+		return allocation, nil
+	}
+
+	for _, spaceType := range candidates {
+		for _, lot := range inv.lots {
+			for _, space := range lot.Spaces {
+				if space.Type != spaceType {
+					continue
+				}
+				key := spaceKey{lotID: lot.ID, spaceID: space.ID}
+				if !inv.isFree(key, window.StartTime, window.EndTime, window.owner()) {
+					continue
+				}
+
+				inv.windows[key] = append(inv.windows[key], window)
+				allocation := SpaceAllocation{
+					ID:        fmt.Sprintf("parking-%s-%s-%s", lot.ID, space.ID, window.StartTime.UTC().Format("20060102T1504")),
+					BookingID: window.BookingID,
+					Owner:     window.Owner,
+					LotID:     lot.ID,
+					SpaceID:   space.ID,
+					SpaceType: space.Type,
+					StartTime: window.StartTime,
+					EndTime:   window.EndTime,
+				}
+				inv.allocations[window.BookingID] = allocation
+				return allocation, nil
+			}
+		}
+	}
+
+	return SpaceAllocation{}, fmt.Errorf("%w: %s %s-%s", ErrParkingFull, window.SpaceType,
+		window.StartTime.UTC().Format(time.RFC3339), window.EndTime.UTC().Format(time.RFC3339))
+}
+
+// Release 予約に割り当てた駐車スペースを解放する
+// 割り当てが存在しない場合は解放済みとして何もしない
+func (inv *ParkingInventory) Release(bookingID, allocationID string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	allocation, exists := inv.allocations[bookingID]
+	if !exists || allocation.ID != allocationID {
+		return
+	}
+
+	key := spaceKey{lotID: allocation.LotID, spaceID: allocation.SpaceID}
+	windows := inv.windows[key][:0]
+	for _, w := range inv.windows[key] {
+		if w.BookingID != bookingID {
+			windows = append(windows, w)
+		}
+	}
+	inv.windows[key] = windows
+	delete(inv.allocations, bookingID)
+}
+
+// Available 利用時間を通して空いている、指定された種類の駐車スペース数を返す
+func (inv *ParkingInventory) Available(spaceType string, start, end time.Time) int {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	available := 0
+	for _, lot := range inv.lots {
+		for _, space := range lot.Spaces {
+			if space.Type == spaceType && inv.isFree(spaceKey{lotID: lot.ID, spaceID: space.ID}, start, end, "") {
+				available++
+			}
+		}
+	}
+	return available
+}
+
+// Allocations 現在の割り当て一覧を予約ID順に返す
+func (inv *ParkingInventory) Allocations() []SpaceAllocation {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	list := make([]SpaceAllocation, 0, len(inv.allocations))
+	for _, allocation := range inv.allocations {
+		list = append(list, allocation)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BookingID < list[j].BookingID })
+	return list
+}
+
+// Restore 保存しておいた割り当て一覧から空き状況を復元する
+// 呼び出し前の割り当ては破棄する
+func (inv *ParkingInventory) Restore(allocations []SpaceAllocation) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.windows = make(map[spaceKey][]ParkingWindow)
+	inv.allocations = make(map[string]SpaceAllocation, len(allocations))
+	for _, allocation := range allocations {
+		key := spaceKey{lotID: allocation.LotID, spaceID: allocation.SpaceID}
+		inv.windows[key] = append(inv.windows[key], ParkingWindow{
+			BookingID: allocation.BookingID,
+			Owner:     allocation.Owner,
+			SpaceType: allocation.SpaceType,
+			StartTime: allocation.StartTime,
+			EndTime:   allocation.EndTime,
+		})
+		inv.allocations[allocation.BookingID] = allocation
+	}
+}
+
+// isFree 駐車スペースが利用時間を通して空いているか（呼び出し元でロックを取得済みであること）
+// ownerの割り当てが使っている時間帯は空きとして扱う
+func (inv *ParkingInventory) isFree(key spaceKey, start, end time.Time, owner string) bool {
+	for _, w := range inv.windows[key] {
+		if w.overlaps(start, end) && (owner == "" || w.owner() != owner) {
+			return false
+		}
+	}
+	return true
+}
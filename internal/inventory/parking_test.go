@@ -0,0 +1,89 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - 利用時間が重なる予約は同じ駐車スペースを共有しない
+//   - 終了時刻と開始時刻が接する予約は同じ駐車スペースを使える
+//   - 解放した駐車スペースは再び予約できる
+//
+// 異常系:
+//   - 空いている駐車スペースがない時、ErrParkingFullが返却される
+//   - 存在しない種類の時、ErrSpaceTypeNotFoundが返却される
+func TestParkingInventory_ReserveRelease(t *testing.T) {
+	// given: 車椅子用スペースが1つだけの駐車場
+	sut := NewParkingInventory([]ParkingLot{
+		{ID: "lot-test", Spaces: []ParkingSpace{{ID: "A-01", Type: SpaceTypeAccessible}}},
+	})
+	at := func(h int) time.Time { return time.Date(2026, 11, 1, h, 0, 0, 0, time.UTC) }
+	window := func(bookingID string, from, to int) ParkingWindow {
+		return ParkingWindow{BookingID: bookingID, SpaceType: SpaceTypeAccessible, StartTime: at(from), EndTime: at(to)}
+	}
+
+	// when: 10時〜12時を予約
+	first, err := sut.Reserve(window("booking-1", 10, 12))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "A-01", first.SpaceID)
+
+	// when: 11時〜13時は重なるため満車
+	_, err = sut.Reserve(window("booking-2", 11, 13))
+
+	// then
+	assert.True(t, errors.Is(err, ErrParkingFull))
+
+	// when: 12時〜14時は接するだけなので予約できる
+	second, err := sut.Reserve(window("booking-3", 12, 14))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "A-01", second.SpaceID)
+
+	// when: 1件目を解放する
+	sut.Release(first.BookingID, first.ID)
+
+	// then: 11時〜12時が空く
+	assert.Equal(t, 1, sut.Available(SpaceTypeAccessible, at(11), at(12)))
+	assert.Equal(t, 0, sut.Available(SpaceTypeAccessible, at(11), at(13)))
+
+	// when: 存在しない種類
+	_, err = sut.Reserve(ParkingWindow{BookingID: "booking-4", SpaceType: "helipad", StartTime: at(10), EndTime: at(11)})
+
+	// then
+	assert.True(t, errors.Is(err, ErrSpaceTypeNotFound))
+}
+
+func TestParkingInventory_ReserveSameOwner(t *testing.T) {
+	// given: 車椅子用スペースが1つだけの駐車場で10時〜12時を予約済み
+	sut := NewParkingInventory([]ParkingLot{
+		{ID: "lot-test", Spaces: []ParkingSpace{{ID: "A-01", Type: SpaceTypeAccessible}}},
+	})
+	at := func(h int) time.Time { return time.Date(2026, 11, 1, h, 0, 0, 0, time.UTC) }
+	window := func(bookingID, owner string, from, to int) ParkingWindow {
+		return ParkingWindow{BookingID: bookingID, Owner: owner, SpaceType: SpaceTypeAccessible, StartTime: at(from), EndTime: at(to)}
+	}
+	original, err := sut.Reserve(window("booking-1", "booking-1", 10, 12))
+	assert.NoError(t, err)
+
+	// when: 同じ予約の変更として11時〜13時を予約する
+	revised, err := sut.Reserve(window("booking-1@r1", "booking-1", 11, 13))
+
+	// then: 重なる時間帯も含めて同じスペースが確保される
+	assert.NoError(t, err)
+	assert.Equal(t, "A-01", revised.SpaceID)
+
+	// when: 変更前の割り当てを解放する
+	sut.Release(original.BookingID, original.ID)
+
+	// then: 変更後の時間帯は確保されたまま残る
+	assert.Equal(t, 1, sut.Available(SpaceTypeAccessible, at(10), at(11)))
+	assert.Equal(t, 0, sut.Available(SpaceTypeAccessible, at(11), at(13)))
+}
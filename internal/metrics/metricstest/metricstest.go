@@ -0,0 +1,37 @@
+// Package metricstest テストでtallyのスコープに記録されたメトリクスを確認するためのヘルパー
+package metricstest
+
+import "github.com/uber-go/tally/v4"
+
+// Value 名前が一致し、指定したラベルを全て持つ系列の値の合計を返す
+// カウンターとゲージは値、タイマーは記録回数を返す
+func Value(scope tally.TestScope, name string, labels map[string]string) float64 {
+	snapshot := scope.Snapshot()
+	var total float64
+	for _, s := range snapshot.Counters() {
+		if s.Name() == name && hasLabels(s.Tags(), labels) {
+			total += float64(s.Value())
+		}
+	}
+	for _, s := range snapshot.Gauges() {
+		if s.Name() == name && hasLabels(s.Tags(), labels) {
+			total += s.Value()
+		}
+	}
+	for _, s := range snapshot.Timers() {
+		if s.Name() == name && hasLabels(s.Tags(), labels) {
+			total += float64(len(s.Values()))
+		}
+	}
+	return total
+}
+
+// hasLabels 系列が指定したラベルを全て持つかどうか
+func hasLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package metricstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally/v4"
+	"temporal-hotel-sample/internal/metrics"
+)
+
+// テストケースについて
+// 正常系:
+//   - 指定したラベルを全て持つ系列の値の合計が返却される
+//   - タイマーは記録回数が返却される
+//   - 記録されていない名前の時、0が返却される
+func TestValue(t *testing.T) {
+	// given
+	scope := tally.NewTestScope("", nil)
+	h := metrics.NewHandler(scope).WithTags(map[string]string{"namespace": "default"})
+	h.WithTags(map[string]string{"error_code": "NETWORK_ERROR", "activity_type": "A"}).Counter("retry_total").Inc(2)
+	h.WithTags(map[string]string{"error_code": "NETWORK_ERROR", "activity_type": "B"}).Counter("retry_total").Inc(1)
+	h.WithTags(map[string]string{"error_code": "TIMEOUT", "activity_type": "A"}).Counter("retry_total").Inc(5)
+	h.Timer("step_latency_seconds").Record(time.Second)
+	h.Timer("step_latency_seconds").Record(time.Second)
+
+	t.Run("正常系: 指定したラベルを全て持つ系列の値の合計が返却される", func(t *testing.T) {
+		assert.Equal(t, 3.0, Value(scope, "retry_total", map[string]string{"error_code": "NETWORK_ERROR"}))
+		assert.Equal(t, 8.0, Value(scope, "retry_total", nil))
+	})
+	t.Run("正常系: タイマーは記録回数が返却される", func(t *testing.T) {
+		assert.Equal(t, 2.0, Value(scope, "step_latency_seconds", map[string]string{"namespace": "default"}))
+	})
+	t.Run("正常系: 記録されていない名前の時、0が返却される", func(t *testing.T) {
+		assert.Equal(t, 0.0, Value(scope, "unknown_total", nil))
+	})
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/uber-go/tally/v4"
+	tallyprom "github.com/uber-go/tally/v4/prometheus"
+	"go.temporal.io/sdk/client"
+	sdktally "go.temporal.io/sdk/contrib/tally"
+)
+
+// DefaultTimerBuckets タイマーのヒストグラムのバケット（秒）
+// リトライを含むステップの所要時間も数えられるよう、5分まで用意する
+var DefaultTimerBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// reportInterval tallyの集計値をPrometheusのメトリクスに反映する間隔
+const reportInterval = time.Second
+
+// Registry Temporal SDKとワーカーが記録するメトリクスをtallyで集計し、Prometheusの形式で公開する
+// タイマーは秒単位のヒストグラムとして公開する
+type Registry struct {
+	scope    tally.Scope
+	closer   io.Closer
+	reporter tallyprom.Reporter
+}
+
+// NewRegistry メトリクスの集計先を作成する（Goランタイムとプロセスのメトリクスを含む）
+func NewRegistry() *Registry {
+	gatherer := prometheus.NewRegistry()
+	gatherer.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	reporter := tallyprom.NewReporter(tallyprom.Options{
+		Registerer:              gatherer,
+		DefaultTimerType:        tallyprom.HistogramTimerType,
+		DefaultHistogramBuckets: DefaultTimerBuckets,
+		// 同じ名前をタグの組み合わせを変えて記録した場合などは、ワーカーを止めずに記録を諦める
+		OnRegisterError: func(err error) {
+			log.Println("Unable to register metric", err)
+		},
+	})
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		CachedReporter:  reporter,
+		Separator:       tallyprom.DefaultSeparator,
+		SanitizeOptions: &sdktally.PrometheusSanitizeOptions,
+	}, reportInterval)
+	return &Registry{scope: scope, closer: closer, reporter: reporter}
+}
+
+// Handler Temporalクライアントに設定するメトリクスハンドラー
+func (r *Registry) Handler() client.MetricsHandler {
+	return NewHandler(r.scope)
+}
+
+// HTTPHandler /metricsで公開するHTTPハンドラー
+func (r *Registry) HTTPHandler() http.Handler {
+	return r.reporter.HTTPHandler()
+}
+
+// Close 集計中の値をPrometheusのメトリクスに反映して集計を終了する
+func (r *Registry) Close() error {
+	return r.closer.Close()
+}
+
+// NewHandler tallyのスコープに記録するメトリクスハンドラー
+// Prometheusの命名規則に合わせ、カウンターには _total、タイマーには _seconds を付ける（付いている場合はそのまま）
+func NewHandler(scope tally.Scope) client.MetricsHandler {
+	return sdktally.NewMetricsHandler(sdktally.NewPrometheusNamingScope(scope))
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// テストケースについて
+// 正常系:
+//   - カウンターはタグの組み合わせごとに加算され、_totalを付けて公開される
+//   - 名前が _total で終わるカウンターは、そのままの名前で公開される
+//   - ゲージは最後に記録した値になる
+//   - タイマーは _seconds を付けた秒単位のヒストグラムとして公開される
+//   - Prometheusで使えない文字を含む名前は、アンダースコアに置き換えて公開される
+func TestRegistry(t *testing.T) {
+	testcases := map[string]struct {
+		record func(r *Registry)
+
+		expectedLines []string
+	}{
+		"正常系: カウンターはタグの組み合わせごとに加算され、_totalを付けて公開される": {
+			record: func(r *Registry) {
+				h := r.Handler().WithTags(map[string]string{"step": "hotel"})
+				h.Counter("booking").Inc(1)
+				h.Counter("booking").Inc(2)
+				r.Handler().WithTags(map[string]string{"step": "dinner"}).Counter("booking").Inc(1)
+			},
+			expectedLines: []string{
+				"# TYPE booking_total counter",
+				`booking_total{step="hotel"} 3`,
+				`booking_total{step="dinner"} 1`,
+			},
+		},
+		"正常系: 名前が _total で終わるカウンターは、そのままの名前で公開される": {
+			record: func(r *Registry) {
+				r.Handler().WithTags(map[string]string{"execution": "sequential"}).Counter("hotel_booking_started_total").Inc(1)
+			},
+			expectedLines: []string{`hotel_booking_started_total{execution="sequential"} 1`},
+		},
+		"正常系: ゲージは最後に記録した値になる": {
+			record: func(r *Registry) {
+				g := r.Handler().Gauge("worker_slots")
+				g.Update(5)
+				g.Update(3)
+			},
+			expectedLines: []string{
+				"# TYPE worker_slots gauge",
+				"worker_slots 3",
+			},
+		},
+		"正常系: タイマーは _seconds を付けた秒単位のヒストグラムとして公開される": {
+			record: func(r *Registry) {
+				timer := r.Handler().WithTags(map[string]string{"step": "hotel"}).Timer("step_latency")
+				timer.Record(200 * time.Millisecond)
+				timer.Record(3 * time.Second)
+			},
+			expectedLines: []string{
+				"# TYPE step_latency_seconds histogram",
+				`step_latency_seconds_bucket{step="hotel",le="0.25"} 1`,
+				`step_latency_seconds_bucket{step="hotel",le="5"} 2`,
+				`step_latency_seconds_bucket{step="hotel",le="+Inf"} 2`,
+				`step_latency_seconds_sum{step="hotel"} 3.2`,
+				`step_latency_seconds_count{step="hotel"} 2`,
+			},
+		},
+		"正常系: Prometheusで使えない文字を含む名前は、アンダースコアに置き換えて公開される": {
+			record: func(r *Registry) {
+				r.Handler().WithTags(map[string]string{"task-queue": "HOTEL"}).Counter("worker.task").Inc(1)
+			},
+			expectedLines: []string{`worker_task_total{task_queue="HOTEL"} 1`},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			registry := NewRegistry()
+			tc.record(registry)
+			assert.NoError(t, registry.Close())
+
+			// when
+			recorder := httptest.NewRecorder()
+			registry.HTTPHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+			// then
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			body, err := io.ReadAll(recorder.Body)
+			assert.NoError(t, err)
+			for _, line := range tc.expectedLines {
+				assert.Contains(t, string(body), line+"\n")
+			}
+			assert.Contains(t, string(body), "go_goroutines")
+		})
+	}
+}
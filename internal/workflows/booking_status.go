@@ -0,0 +1,110 @@
+package workflows
+
+import (
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+)
+
+// BookingStatusQuery 予約の進行状況を問い合わせるクエリ名
+const BookingStatusQuery = "booking-status"
+
+// BookingPhase 予約Sagaの進行フェーズ
+type BookingPhase string
+
+const (
+	// PhaseValidating リクエストのバリデーション中
+	PhaseValidating BookingPhase = "validating"
+	// PhaseBookingHotel ホテルルーム予約中
+	PhaseBookingHotel BookingPhase = "booking_hotel"
+	// PhaseBookingDinner ディナー食材予約中
+	PhaseBookingDinner BookingPhase = "booking_dinner"
+	// PhaseBookingParking 駐車場予約中
+	PhaseBookingParking BookingPhase = "booking_parking"
+	// PhaseBookingInParallel 複数のステップを並行して予約中
+	PhaseBookingInParallel BookingPhase = "booking_in_parallel"
+	// PhaseAwaitingCheckIn 全ての予約が完了し、チェックインを待機中（キャンセル可能）
+	PhaseAwaitingCheckIn BookingPhase = "awaiting_check_in"
+	// PhaseModifying 予約変更中（完了後はチェックイン待機に戻る）
+	PhaseModifying BookingPhase = "modifying"
+	// PhaseCheckedIn チェックイン済みで、チェックアウトを待機中
+	PhaseCheckedIn BookingPhase = "checked_in"
+	// PhaseCompensating 補償処理中
+	PhaseCompensating BookingPhase = "compensating"
+	// PhaseAwaitingManualResolution 補償処理の手動対応待ち
+	PhaseAwaitingManualResolution BookingPhase = "awaiting_manual_resolution"
+	// PhaseDone 完了（成功・失敗を問わない）
+	PhaseDone BookingPhase = "done"
+)
+
+// BookingStatus 予約Sagaの現在の状況
+type BookingStatus struct {
+	BookingID     string                           `json:"booking_id"`
+	Phase         BookingPhase                     `json:"phase"`
+	HotelResult   *activities.HotelBookingResult   `json:"hotel_result,omitempty"`
+	DinnerResult  *activities.DinnerBookingResult  `json:"dinner_result,omitempty"`
+	ParkingResult *activities.ParkingBookingResult `json:"parking_result,omitempty"`
+	Attempts      map[config.Step]int32            `json:"attempts"`      // 完了したステップの試行回数
+	Compensations []string                         `json:"compensations"` // 登録済みの補償処理
+
+	CompensationReport  *CompensationReport `json:"compensation_report,omitempty"`
+	Success             bool                `json:"success"`
+	CancelledByCustomer bool                `json:"cancelled_by_customer,omitempty"`
+	Lifecycle           LifecycleState      `json:"lifecycle,omitempty"`
+	Message             string              `json:"message,omitempty"`
+}
+
+// newBookingStatus 予約状況を初期化し、クエリハンドラーを登録する
+func newBookingStatus(ctx workflow.Context, bookingID string) *BookingStatus {
+	status := &BookingStatus{
+		BookingID:     bookingID,
+		Phase:         PhaseValidating,
+		Attempts:      map[config.Step]int32{},
+		Compensations: []string{},
+	}
+	err := workflow.SetQueryHandler(ctx, BookingStatusQuery, func() (BookingStatus, error) {
+		return *status, nil
+	})
+	if err != nil {
+		workflow.GetLogger(ctx).Error("予約状況クエリハンドラーの登録に失敗", "Error", err)
+	}
+	return status
+}
+
+// enter 次のフェーズに進む
+func (s *BookingStatus) enter(phase BookingPhase) {
+	s.Phase = phase
+}
+
+// recordAttempts ステップの試行回数を記録する
+func (s *BookingStatus) recordAttempts(step config.Step, reported int32, err error, policy *temporal.RetryPolicy) {
+	s.Attempts[step] = stepAttempts(reported, err, policy)
+}
+
+// registerCompensation 登録された補償処理を記録する
+func (s *BookingStatus) registerCompensation(step CompensationStep) {
+	s.Compensations = append(s.Compensations, step.String())
+}
+
+// finish 予約結果を反映して完了にする
+func (s *BookingStatus) finish(result *BookingResult) {
+	s.Phase = PhaseDone
+	s.Success = result.Success
+	s.CancelledByCustomer = result.CancelledByCustomer
+	s.Lifecycle = result.Lifecycle
+	s.Message = result.Message
+	s.CompensationReport = result.CompensationReport
+}
+
+// stepAttempts アクティビティの試行回数を求める
+// 成功時はアクティビティが報告した試行回数、リトライ上限で失敗した場合は最大試行回数とする
+func stepAttempts(reported int32, err error, policy *temporal.RetryPolicy) int32 {
+	if err != nil && exhaustedRetries(err, policy) {
+		return policy.MaximumAttempts
+	}
+	if reported > 0 {
+		return reported
+	}
+	return 1
+}
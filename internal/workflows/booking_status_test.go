@@ -0,0 +1,118 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+)
+
+// testケース
+// 正常系:
+//   - ディナー食材予約の実行中に問い合わせると、予約中のフェーズとホテルの結果、登録済みの補償処理が返却される
+//   - ノーショーで完了した後に問い合わせると、全ての結果と完了フェーズ、ディナーと駐車場の解放の補償処理のレポートが返却される
+//
+// 異常系:
+//   - 駐車場予約がリトライ上限で失敗した後に問い合わせると、試行回数と補償処理のレポートが返却される
+func TestHotelBookingSaga_BookingStatusQuery(t *testing.T) {
+	hotelResult := &activities.HotelBookingResult{Success: true, ResourceID: "room-001"}
+	dinnerResult := &activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}
+	parkingResult := &activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}
+
+	tests := map[string]struct {
+		parkingErr error
+		queryAfter time.Duration // 0の場合は完了後に問い合わせる
+
+		expectedPhase         BookingPhase
+		expectedHotel         *activities.HotelBookingResult
+		expectedDinner        *activities.DinnerBookingResult
+		expectedParking       *activities.ParkingBookingResult
+		expectedAttempts      map[config.Step]int32
+		expectedCompensations []string
+		expectedReport        bool
+	}{
+		"正常系 - ディナー食材予約の実行中に問い合わせる": {
+			queryAfter:            30 * time.Minute,
+			expectedPhase:         PhaseBookingDinner,
+			expectedHotel:         hotelResult,
+			expectedAttempts:      map[config.Step]int32{config.StepHotel: 1},
+			expectedCompensations: []string{"ホテルルーム補償(room-001)"},
+		},
+		"正常系 - ノーショーで完了した後に問い合わせる": {
+			expectedPhase:         PhaseDone,
+			expectedHotel:         hotelResult,
+			expectedDinner:        dinnerResult,
+			expectedParking:       parkingResult,
+			expectedAttempts:      map[config.Step]int32{config.StepHotel: 1, config.StepDinner: 1, config.StepParking: 1},
+			expectedCompensations: []string{"ホテルルーム補償(room-001)", "ディナー食材補償(food-001)", "駐車場補償(parking-001)"},
+			expectedReport:        true,
+		},
+		"異常系 - 駐車場予約がリトライ上限で失敗した後に問い合わせる": {
+			parkingErr:            activities.NewServerError("駐車場管理システムへの接続に失敗しました", "CONNECTION_ERROR"),
+			expectedPhase:         PhaseDone,
+			expectedHotel:         hotelResult,
+			expectedDinner:        dinnerResult,
+			expectedAttempts:      map[config.Step]int32{config.StepHotel: 1, config.StepDinner: 1, config.StepParking: 3},
+			expectedCompensations: []string{"ホテルルーム補償(room-001)", "ディナー食材補償(food-001)"},
+			expectedReport:        true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(hotelResult, nil)
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, mock.Anything).After(time.Hour).Return(dinnerResult, nil)
+			if tt.parkingErr != nil {
+				testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, mock.Anything).Return(nil, activities.ToApplicationError(tt.parkingErr))
+			} else {
+				testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, mock.Anything).Return(parkingResult, nil)
+			}
+			testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, nil).Maybe()
+			testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, nil).Maybe()
+			testEnv.OnActivity(activities.CompensateParkingActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, nil).Maybe()
+
+			var status BookingStatus
+			query := func() {
+				value, err := testEnv.QueryWorkflow(BookingStatusQuery)
+				if assert.NoError(t, err) {
+					assert.NoError(t, value.Get(&status))
+				}
+			}
+			if tt.queryAfter > 0 {
+				testEnv.RegisterDelayedCallback(query, tt.queryAfter)
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, BookingRequest{
+				BookingID: "booking-status-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			})
+			if tt.queryAfter == 0 {
+				query()
+			}
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			assert.Equal(t, "booking-status-001", status.BookingID)
+			assert.Equal(t, tt.expectedPhase, status.Phase)
+			assert.Equal(t, tt.expectedHotel, status.HotelResult)
+			assert.Equal(t, tt.expectedDinner, status.DinnerResult)
+			assert.Equal(t, tt.expectedParking, status.ParkingResult)
+			assert.Equal(t, tt.expectedAttempts, status.Attempts)
+			assert.Equal(t, tt.expectedCompensations, status.Compensations)
+			assert.Equal(t, tt.expectedReport, status.CompensationReport != nil)
+		})
+	}
+}
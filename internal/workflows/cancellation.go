@@ -0,0 +1,58 @@
+package workflows
+
+import "go.temporal.io/sdk/workflow"
+
+// CancelBookingSignal お客様による予約キャンセルのシグナル名
+const CancelBookingSignal = "cancel-booking"
+
+// CancellationRequest 予約キャンセルの要求内容
+type CancellationRequest struct {
+	Reason      string `json:"reason,omitempty"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// cancellationListener 予約キャンセルシグナルの受信状況
+// シグナルは受信しておき、Sagaの安全なタイミング（ステップの前後）でのみ確認する
+type cancellationListener struct {
+	ch      workflow.ReceiveChannel
+	request *CancellationRequest
+}
+
+// newCancellationListener 予約キャンセルシグナルの受信を開始
+func newCancellationListener(ctx workflow.Context) *cancellationListener {
+	return &cancellationListener{ch: workflow.GetSignalChannel(ctx, CancelBookingSignal)}
+}
+
+// requested 既にキャンセルが要求されているかどうか（待機しない）
+func (l *cancellationListener) requested() bool {
+	if l.request != nil {
+		return true
+	}
+	var request CancellationRequest
+	if l.ch.ReceiveAsync(&request) {
+		l.request = &request
+	}
+	return l.request != nil
+}
+
+// receive セレクターで受信可能になったキャンセル要求を受け取る（既に受け取っている場合は最初の要求を維持する）
+func (l *cancellationListener) receive(ctx workflow.Context, c workflow.ReceiveChannel) {
+	var request CancellationRequest
+	c.Receive(ctx, &request)
+	if l.request == nil {
+		l.request = &request
+	}
+}
+
+// cancelBooking お客様のキャンセルにより、完了済みの全ステップを補償して予約を取り消す
+func cancelBooking(ctx workflow.Context, bookingID string, compensations Compensations, result *BookingResult, status *BookingStatus, request CancellationRequest) {
+	workflow.GetLogger(ctx).Info("お客様のキャンセル要求により予約を取り消します",
+		"BookingID", bookingID, "Reason", request.Reason, "Compensations", len(compensations))
+
+	result.Success = false
+	result.CancelledByCustomer = true
+	result.Lifecycle = LifecycleCancelled
+	result.CancellationReason = request.Reason
+	result.Message = "お客様のキャンセルにより予約を取り消しました"
+	compensate(ctx, bookingID, compensations, result, status)
+}
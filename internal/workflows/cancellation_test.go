@@ -0,0 +1,98 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"temporal-hotel-sample/internal/activities"
+)
+
+// testケース
+// 準異常系:
+//   - ディナー食材予約の実行中にキャンセルされた時、ディナー完了後にホテルとディナーを補償して取り消す
+//   - 全ての予約完了後、チェックイン前にキャンセルされた時、全てのリソースを補償して取り消す
+func TestHotelBookingSaga_CancelBooking(t *testing.T) {
+	tests := map[string]struct {
+		cancelAfter time.Duration
+
+		expectedSuccess       bool
+		expectedCancelled     bool
+		expectedParkingCalls  int
+		expectedReleased      []string
+		expectedRollback      CompensationStatus
+		expectedMessagePrefix string
+	}{
+		"準異常系 - ディナー食材予約の実行中にキャンセルされる": {
+			cancelAfter:           30 * time.Minute,
+			expectedCancelled:     true,
+			expectedParkingCalls:  0,
+			expectedReleased:      []string{"food-001", "room-001"},
+			expectedRollback:      CompensationStatusRolledBack,
+			expectedMessagePrefix: "お客様のキャンセルにより予約を取り消しました",
+		},
+		"準異常系 - 全ての予約完了後、チェックイン前にキャンセルされる": {
+			cancelAfter:           24 * time.Hour,
+			expectedCancelled:     true,
+			expectedParkingCalls:  1,
+			expectedReleased:      []string{"parking-001", "food-001", "room-001"},
+			expectedRollback:      CompensationStatusRolledBack,
+			expectedMessagePrefix: "お客様のキャンセルにより予約を取り消しました",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.SetStartTime(testCheckIn.AddDate(0, 0, -7))
+
+			request := BookingRequest{
+				BookingID: "booking-cancel-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, mock.Anything).After(time.Hour).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}, nil).Once()
+			if tt.expectedParkingCalls > 0 {
+				testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, mock.Anything).Return(
+					&activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}, nil).Times(tt.expectedParkingCalls)
+			}
+			for _, released := range tt.expectedReleased {
+				compensation := map[string]interface{}{
+					"room-001":    activities.CompensateHotelRoomActivity,
+					"food-001":    activities.CompensateDinnerFoodActivity,
+					"parking-001": activities.CompensateParkingActivity,
+				}[released]
+				testEnv.OnActivity(compensation, mock.Anything, request.BookingID, released).Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+
+			testEnv.RegisterDelayedCallback(func() {
+				testEnv.SignalWorkflow(CancelBookingSignal, CancellationRequest{Reason: "予定変更", RequestedBy: "user-001"})
+			}, tt.cancelAfter)
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.Equal(t, tt.expectedSuccess, result.Success)
+			assert.Equal(t, tt.expectedCancelled, result.CancelledByCustomer)
+			assert.Equal(t, tt.expectedReleased, result.ReleasedResources)
+			assert.Equal(t, tt.expectedRollback, result.RollbackStatus)
+			assert.Contains(t, result.Message, tt.expectedMessagePrefix)
+			assert.Equal(t, "予定変更", result.CancellationReason)
+			assert.Equal(t, LifecycleCancelled, result.Lifecycle)
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package workflows
+
+import (
+	"fmt"
+	"strings"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+)
+
+// BookingConfirmedSignal 子ワークフローの予約が確定したことを親ワークフローに知らせるシグナル名
+// 予約Sagaはチェックアウトまで終了しないため、確定した時点でこのシグナルを送る
+const BookingConfirmedSignal = "booking-confirmed"
+
+// GroupBookingPolicy グループ予約の一部が失敗した場合の扱い
+type GroupBookingPolicy string
+
+const (
+	// GroupAllOrNothing 1件でも失敗した場合は、確定した予約も含めて全て取り消す
+	GroupAllOrNothing GroupBookingPolicy = "all_or_nothing"
+	// GroupBestEffort 失敗した予約を除いて、確定した予約を維持する
+	GroupBestEffort GroupBookingPolicy = "best_effort"
+)
+
+// GroupBookingRequest 結婚式や会議などのグループ予約リクエスト
+// 1件の予約（客室）ごとに子ワークフローとしてホテル予約Sagaを実行する
+type GroupBookingRequest struct {
+	GroupID  string             `json:"group_id"`
+	Policy   GroupBookingPolicy `json:"policy,omitempty"` // 省略時はall_or_nothing
+	Bookings []BookingRequest   `json:"bookings"`
+}
+
+// GroupMemberResult グループ内の1件の予約の結果
+type GroupMemberResult struct {
+	BookingID  string         `json:"booking_id"`
+	WorkflowID string         `json:"workflow_id"`
+	Confirmed  bool           `json:"confirmed"`           // 予約が確定し、維持されているか
+	Cancelled  bool           `json:"cancelled,omitempty"` // グループの取り消しにより取り消されたか
+	Message    string         `json:"message"`
+	Result     *BookingResult `json:"result,omitempty"`
+}
+
+// GroupBookingResult グループ予約の集計結果
+type GroupBookingResult struct {
+	Success    bool                `json:"success"`
+	GroupID    string              `json:"group_id"`
+	Policy     GroupBookingPolicy  `json:"policy"`
+	Message    string              `json:"message"`
+	Bookings   []GroupMemberResult `json:"bookings"`
+	Confirmed  int                 `json:"confirmed"` // 確定した予約の件数
+	Failed     int                 `json:"failed"`    // 失敗した予約の件数
+	RolledBack bool                `json:"rolled_back,omitempty"`
+}
+
+// policy 省略時のポリシーを補ったポリシー
+func (r *GroupBookingRequest) policy() GroupBookingPolicy {
+	if r.Policy == "" {
+		return GroupAllOrNothing
+	}
+	return r.Policy
+}
+
+// Validate グループ予約リクエストのバリデーション
+// 予約IDは子ワークフローのIDとして使うため、グループ内で重複してはならない
+func (r *GroupBookingRequest) Validate() error {
+	if strings.TrimSpace(r.GroupID) == "" {
+		return fmt.Errorf("GroupID is required")
+	}
+	switch r.policy() {
+	case GroupAllOrNothing, GroupBestEffort:
+	default:
+		return fmt.Errorf("Policy must be %q or %q", GroupAllOrNothing, GroupBestEffort)
+	}
+	if len(r.Bookings) == 0 {
+		return fmt.Errorf("Bookings must not be empty")
+	}
+	seen := map[string]bool{}
+	for i, booking := range r.Bookings {
+		if err := booking.Validate(); err != nil {
+			return fmt.Errorf("Bookings[%d]: %w", i, err)
+		}
+		if seen[booking.BookingID] {
+			return fmt.Errorf("Bookings[%d]: duplicate BookingID %q", i, booking.BookingID)
+		}
+		seen[booking.BookingID] = true
+	}
+	return nil
+}
+
+// notifyParent 親ワークフローから起動された場合、予約が確定したことを知らせる
+func notifyParent(ctx workflow.Context, result *BookingResult) {
+	parent := workflow.GetInfo(ctx).ParentWorkflowExecution
+	if parent == nil {
+		return
+	}
+	err := workflow.SignalExternalWorkflow(ctx, parent.ID, "", BookingConfirmedSignal, *result).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Error("親ワークフローへの予約確定の通知に失敗", "BookingID", result.BookingID, "Error", err.Error())
+	}
+}
+
+// groupMember 子ワークフローの実行状況
+type groupMember struct {
+	result  GroupMemberResult
+	future  workflow.ChildWorkflowFuture
+	settled bool // 確定または終了した
+	done    bool // 子ワークフローが終了した
+}
+
+// GroupBookingWorkflow グループ予約ワークフロー
+// 予約ごとに子ワークフローとしてホテル予約Sagaを並行して実行し、全ての予約が確定または失敗するまで待って結果を集計する
+//   - all_or_nothing: 1件でも失敗した場合は、全ての子ワークフローにキャンセルを送り、確定した予約を補償させる
+//   - best_effort: 失敗した予約を除いて、確定した予約を維持する
+//
+// 確定した子ワークフローはグループ予約の完了後もチェックアウトまで予約を管理する
+func GroupBookingWorkflow(ctx workflow.Context, request GroupBookingRequest) (*GroupBookingResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("グループ予約ワークフローを開始", "GroupID", request.GroupID, "Bookings", len(request.Bookings))
+
+	result := &GroupBookingResult{
+		GroupID:  request.GroupID,
+		Policy:   request.policy(),
+		Bookings: []GroupMemberResult{},
+	}
+	if err := request.Validate(); err != nil {
+		logger.Error("リクエストのバリデーションに失敗", "Error", err.Error())
+		result.Message = fmt.Sprintf("バリデーションエラー: %s", err.Error())
+		return result, nil // ワークフローとしては正常終了、結果でエラーを表現
+	}
+
+	// 予約ごとに子ワークフローを起動する（グループ予約の完了後も子ワークフローは継続する）
+	selector := workflow.NewSelector(ctx)
+	members := make([]*groupMember, len(request.Bookings))
+	byBookingID := map[string]*groupMember{}
+	for i, booking := range request.Bookings {
+		// 子の予約IDへの再送信を照合できるよう、予約Sagaを直接開始する場合と同じくリクエストのハッシュを記録する
+		hash, err := RequestHash(booking)
+		if err != nil {
+			return nil, err
+		}
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID:        booking.BookingID,
+			ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
+			Memo:              map[string]interface{}{MemoRequestHash: hash},
+		})
+		member := &groupMember{
+			result: GroupMemberResult{BookingID: booking.BookingID, WorkflowID: booking.BookingID},
+			future: workflow.ExecuteChildWorkflow(childCtx, HotelBookingSaga, booking),
+		}
+		members[i] = member
+		byBookingID[booking.BookingID] = member
+		selector.AddFuture(member.future, func(f workflow.Future) {
+			member.done = true
+			member.settled = true
+			var childResult BookingResult
+			if err := f.Get(ctx, &childResult); err != nil {
+				member.result.Message = fmt.Sprintf("予約ワークフローが失敗しました: %s", err.Error())
+				return
+			}
+			member.result.Result = &childResult
+			member.result.Message = childResult.Message
+			member.result.Confirmed = childResult.Success
+			member.result.Cancelled = childResult.CancelledByCustomer
+		})
+	}
+
+	// 予約の確定は子ワークフローからのシグナルで受け取る
+	selector.AddReceive(workflow.GetSignalChannel(ctx, BookingConfirmedSignal), func(c workflow.ReceiveChannel, more bool) {
+		var childResult BookingResult
+		c.Receive(ctx, &childResult)
+		member, ok := byBookingID[childResult.BookingID]
+		if !ok || member.settled {
+			return
+		}
+		logger.Info("グループ内の予約が確定", "GroupID", request.GroupID, "BookingID", childResult.BookingID)
+		member.settled = true
+		member.result.Result = &childResult
+		member.result.Message = childResult.Message
+		member.result.Confirmed = true
+	})
+
+	rollback := false
+	for !rollback && !allSettled(members) {
+		selector.Select(ctx)
+		rollback = result.Policy == GroupAllOrNothing && anyFailed(members)
+	}
+
+	// all_or_nothingで失敗した場合は、終了していない全ての子ワークフローをキャンセルし、補償の完了を待つ
+	if rollback {
+		logger.Warn("グループ内の予約が失敗したため、全ての予約を取り消します", "GroupID", request.GroupID)
+		cancellation := CancellationRequest{
+			Reason:      "グループ予約の一部が失敗したため取り消しました",
+			RequestedBy: fmt.Sprintf("group:%s", request.GroupID),
+		}
+		for _, member := range members {
+			if member.done {
+				continue
+			}
+			if err := member.future.SignalChildWorkflow(ctx, CancelBookingSignal, cancellation).Get(ctx, nil); err != nil {
+				logger.Error("子ワークフローへのキャンセルの送信に失敗", "BookingID", member.result.BookingID, "Error", err.Error())
+			}
+		}
+		for !allDone(members) {
+			selector.Select(ctx)
+		}
+		result.RolledBack = true
+	}
+
+	for _, member := range members {
+		if member.result.Confirmed {
+			result.Confirmed++
+		} else if !member.result.Cancelled {
+			result.Failed++
+		}
+		result.Bookings = append(result.Bookings, member.result)
+	}
+
+	switch {
+	case result.RolledBack:
+		result.Message = fmt.Sprintf("%d件の予約が失敗したため、グループ予約を全て取り消しました", result.Failed)
+	case result.Failed == 0:
+		result.Success = true
+		result.Message = fmt.Sprintf("グループ予約が完了しました（%d件）", result.Confirmed)
+	case result.Confirmed > 0:
+		result.Success = true
+		result.Message = fmt.Sprintf("グループ予約が完了しました（%d件確定、%d件失敗）", result.Confirmed, result.Failed)
+	default:
+		result.Message = "グループ内の全ての予約が失敗しました"
+	}
+
+	logger.Info("グループ予約ワークフローが完了", "GroupID", request.GroupID, "Confirmed", result.Confirmed, "Failed", result.Failed)
+	return result, nil
+}
+
+// allSettled 全ての予約が確定または終了したかどうか
+func allSettled(members []*groupMember) bool {
+	for _, member := range members {
+		if !member.settled {
+			return false
+		}
+	}
+	return true
+}
+
+// allDone 全ての子ワークフローが終了したかどうか
+func allDone(members []*groupMember) bool {
+	for _, member := range members {
+		if !member.done {
+			return false
+		}
+	}
+	return true
+}
+
+// anyFailed 確定せずに終了した予約があるかどうか
+func anyFailed(members []*groupMember) bool {
+	for _, member := range members {
+		if member.done && !member.result.Confirmed {
+			return true
+		}
+	}
+	return false
+}
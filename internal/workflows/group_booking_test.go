@@ -0,0 +1,132 @@
+package workflows
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"temporal-hotel-sample/internal/activities"
+)
+
+// testケース
+// 正常系:
+//   - all_or_nothingで全ての予約が確定した時、グループ予約が完了する
+//
+// 準異常系:
+//   - best_effortで一部の予約が満室の時、確定した予約を維持して完了する
+//
+// 異常系:
+//   - all_or_nothingで一部の予約が満室の時、予約中の子ワークフローをキャンセルして客室を補償する
+//   - 予約IDが重複している時、子ワークフローを起動せずにバリデーションエラーを返す
+func TestGroupBookingWorkflow(t *testing.T) {
+	booking := func(bookingID string) BookingRequest {
+		return BookingRequest{
+			BookingID: bookingID,
+			UserID:    "user-001",
+			Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+		}
+	}
+
+	tests := map[string]struct {
+		policy   GroupBookingPolicy
+		bookings []BookingRequest
+		fullRoom string // 満室になる予約ID
+		invalid  bool   // バリデーションエラーで子ワークフローを起動しない
+
+		expectedSuccess    bool
+		expectedRolledBack bool
+		expectedConfirmed  int
+		expectedFailed     int
+		expectedReleased   []string // 補償される予約ID
+		expectedMessage    string
+	}{
+		"正常系 - 全ての予約が確定する": {
+			policy:            GroupAllOrNothing,
+			bookings:          []BookingRequest{booking("wedding-001"), booking("wedding-002")},
+			expectedSuccess:   true,
+			expectedConfirmed: 2,
+			expectedMessage:   "グループ予約が完了しました（2件）",
+		},
+		"準異常系 - best_effortで一部の予約が満室になる": {
+			policy:            GroupBestEffort,
+			bookings:          []BookingRequest{booking("wedding-001"), booking("wedding-002")},
+			fullRoom:          "wedding-002",
+			expectedSuccess:   true,
+			expectedConfirmed: 1,
+			expectedFailed:    1,
+			expectedMessage:   "グループ予約が完了しました（1件確定、1件失敗）",
+		},
+		"異常系 - all_or_nothingで一部の予約が満室になり、全て取り消す": {
+			policy:             GroupAllOrNothing,
+			bookings:           []BookingRequest{booking("wedding-001"), booking("wedding-002")},
+			fullRoom:           "wedding-002",
+			expectedRolledBack: true,
+			expectedFailed:     1,
+			expectedReleased:   []string{"wedding-001"},
+			expectedMessage:    "1件の予約が失敗したため、グループ予約を全て取り消しました",
+		},
+		"異常系 - 予約IDが重複する": {
+			bookings:        []BookingRequest{booking("wedding-001"), booking("wedding-001")},
+			invalid:         true,
+			expectedMessage: `バリデーションエラー: Bookings[1]: duplicate BookingID "wedding-001"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.RegisterWorkflow(HotelBookingSaga)
+			testEnv.SetStartTime(testCheckIn.AddDate(0, 0, -7))
+
+			for _, b := range tt.bookings {
+				if tt.invalid {
+					break
+				}
+				if b.BookingID == tt.fullRoom {
+					testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(b, 0)).Return(
+						nil, activities.ToApplicationError(activities.NewBusinessError("指定されたホテルは満室です", activities.CodeRoomFull))).Once()
+					continue
+				}
+				// 満室の予約が失敗するまで予約中のままにする
+				testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(b, 0)).Return(
+					&activities.HotelBookingResult{Success: true, ResourceID: "room-" + b.BookingID}, nil).After(time.Hour).Once()
+			}
+			for _, bookingID := range tt.expectedReleased {
+				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, bookingID, "room-"+bookingID).Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+			testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, mock.Anything).Return(
+				&activities.NotificationResult{Success: true}, nil).Maybe()
+
+			// when
+			testEnv.ExecuteWorkflow(GroupBookingWorkflow, GroupBookingRequest{
+				GroupID:  "group-001",
+				Policy:   tt.policy,
+				Bookings: tt.bookings,
+			})
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var result GroupBookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.Equal(t, tt.expectedSuccess, result.Success)
+			assert.Equal(t, tt.expectedRolledBack, result.RolledBack)
+			assert.Equal(t, tt.expectedConfirmed, result.Confirmed)
+			assert.Equal(t, tt.expectedFailed, result.Failed)
+			assert.Equal(t, tt.expectedMessage, result.Message)
+			for _, member := range result.Bookings {
+				if slices.Contains(tt.expectedReleased, member.BookingID) {
+					assert.True(t, member.Cancelled)
+					if assert.NotNil(t, member.Result) {
+						assert.Equal(t, []string{"room-" + member.BookingID}, member.Result.ReleasedResources)
+					}
+				}
+			}
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
@@ -5,20 +5,33 @@ import (
 	"strings"
 	"time"
 
-	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+	"temporal-hotel-sample/internal/inventory"
 )
 
 // BookingRequest ホテル予約Sagaの統合リクエスト
 type BookingRequest struct {
-	BookingID string         `json:"booking_id"`
-	UserID    string         `json:"user_id"`
-	Hotel     HotelRequest   `json:"hotel"`
-	Dinner    DinnerRequest  `json:"dinner"`
-	Parking   ParkingRequest `json:"parking"`
+	BookingID string          `json:"booking_id"`
+	UserID    string          `json:"user_id"`
+	Hotel     HotelRequest    `json:"hotel"`
+	Dinner    *DinnerRequest  `json:"dinner,omitempty"`  // 省略時はディナーを予約しない
+	Parking   *ParkingRequest `json:"parking,omitempty"` // 省略時は駐車場を予約しない
+
+	Execution ExecutionMode `json:"execution,omitempty"` // ステップの実行方式（省略時は順次実行）
 }
 
+// ExecutionMode 予約Sagaのステップの実行方式
+type ExecutionMode string
+
+const (
+	// ExecutionSequential ステップを定義順に1つずつ実行する
+	ExecutionSequential ExecutionMode = "sequential"
+	// ExecutionParallel 依存関係のないステップを並行して実行する
+	ExecutionParallel ExecutionMode = "parallel"
+)
+
 // HotelRequest ホテル予約サブリクエスト
 type HotelRequest struct {
 	HotelID  string    `json:"hotel_id"`
@@ -32,6 +45,8 @@ type DinnerRequest struct {
 	MenuType string    `json:"menu_type"`
 	DateTime time.Time `json:"date_time,omitempty"`
 	Guests   int       `json:"guests,omitempty"`
+
+	BestEffort bool `json:"best_effort,omitempty"` // 失敗してもホテル予約を取り消さない
 }
 
 // ParkingRequest 駐車場予約サブリクエスト
@@ -39,6 +54,8 @@ type ParkingRequest struct {
 	SpaceType string    `json:"space_type"`
 	StartTime time.Time `json:"start_time,omitempty"`
 	EndTime   time.Time `json:"end_time,omitempty"`
+
+	BestEffort bool `json:"best_effort,omitempty"` // 失敗してもホテル予約を取り消さない
 }
 
 // BookingResult ホテル予約Sagaの統合結果
@@ -50,9 +67,50 @@ type BookingResult struct {
 	DinnerResult  *activities.DinnerBookingResult  `json:"dinner_result,omitempty"`
 	ParkingResult *activities.ParkingBookingResult `json:"parking_result,omitempty"`
 	Compensations []string                         `json:"compensations,omitempty"` // 実行された補償処理
+
+	FailedStep config.Step          `json:"failed_step,omitempty"` // 失敗したステップ
+	ErrorCode  string               `json:"error_code,omitempty"`  // 失敗したアクティビティのエラーコード
+	Shortages  []inventory.Shortage `json:"shortages,omitempty"`   // 在庫不足の食材（OUT_OF_STOCKの場合のみ）
+
+	CompensationReport   *CompensationReport `json:"compensation_report,omitempty"`    // 補償処理の実行レポート
+	RollbackStatus       CompensationStatus  `json:"rollback_status,omitempty"`        // ロールバックの状態
+	ManualActionRequired bool                `json:"manual_action_required,omitempty"` // 補償失敗により手動対応が必要か
+	ReleasedResources    []string            `json:"released_resources,omitempty"`     // 補償により解放されたリソースID
+
+	CancelledByCustomer bool   `json:"cancelled_by_customer,omitempty"` // お客様のキャンセルにより取り消されたか
+	CancellationReason  string `json:"cancellation_reason,omitempty"`
+
+	Lifecycle                LifecycleState `json:"lifecycle,omitempty"`                  // 予約完了後の予約の状態
+	FreeCancellationDeadline time.Time      `json:"free_cancellation_deadline,omitempty"` // 無料キャンセルの期限
+	CancellationFeeApplies   bool           `json:"cancellation_fee_applies,omitempty"`   // 無料キャンセル期限後のキャンセルか
+	ReminderSent             bool           `json:"reminder_sent,omitempty"`              // チェックインのリマインダーを送信したか
+	CheckedInAt              time.Time      `json:"checked_in_at,omitempty"`
+	Revision                 int            `json:"revision,omitempty"` // 予約変更の版（変更がなければ0）
+
+	Degraded      bool          `json:"degraded,omitempty"`       // 必須ではないステップが失敗し、一部の予約なしで完了したか
+	DegradedSteps []config.Step `json:"degraded_steps,omitempty"` // 失敗した必須ではないステップ
+	Warnings      []string      `json:"warnings,omitempty"`       // 失敗した必須ではないステップの警告
+}
+
+// applyCompensationReport 補償処理のレポートを予約結果に反映
+func (r *BookingResult) applyCompensationReport(report CompensationReport) {
+	r.CompensationReport = &report
+	r.Compensations = report.Compensated()
+	r.RollbackStatus = report.Status()
+	for _, o := range report.Outcomes {
+		if o.Succeeded() && o.ResourceID != "" {
+			r.ReleasedResources = append(r.ReleasedResources, o.ResourceID)
+		}
+	}
+	if r.RollbackStatus == CompensationStatusPartiallyFailed {
+		r.ManualActionRequired = true
+		r.Message = fmt.Sprintf("%s（補償処理が一部失敗しました。手動対応が必要です）", r.Message)
+	}
 }
 
 // Validate 統合リクエストのバリデーション
+// 各サブリクエストの必須項目に加えて、宿泊期間とディナー・駐車場の日時の整合性をチェックする
+// ディナーと駐車場は指定された場合のみチェックする
 func (r *BookingRequest) Validate() error {
 	if strings.TrimSpace(r.BookingID) == "" {
 		return fmt.Errorf("BookingID is required")
@@ -60,139 +118,317 @@ func (r *BookingRequest) Validate() error {
 	if strings.TrimSpace(r.UserID) == "" {
 		return fmt.Errorf("UserID is required")
 	}
+	switch r.Execution {
+	case "", ExecutionSequential, ExecutionParallel:
+	default:
+		return fmt.Errorf("Execution must be %q or %q", ExecutionSequential, ExecutionParallel)
+	}
 	if strings.TrimSpace(r.Hotel.HotelID) == "" {
 		return fmt.Errorf("Hotel.HotelID is required")
 	}
-	if strings.TrimSpace(r.Dinner.MenuType) == "" {
-		return fmt.Errorf("Dinner.MenuType is required")
+	if r.Hotel.CheckIn.IsZero() {
+		return fmt.Errorf("Hotel.CheckIn is required")
+	}
+	if !r.Hotel.CheckOut.After(r.Hotel.CheckIn) {
+		return fmt.Errorf("Hotel.CheckOut must be after Hotel.CheckIn")
+	}
+	// 客室は宿泊日（UTCの日付）単位で確保するため、同じ日付のチェックアウトや最大泊数を超える宿泊はここで弾く
+	if _, err := (inventory.Stay{CheckIn: r.Hotel.CheckIn, CheckOut: r.Hotel.CheckOut}).Nights(); err != nil {
+		return fmt.Errorf("Hotel stay is invalid: %w", err)
 	}
-	if strings.TrimSpace(r.Parking.SpaceType) == "" {
-		return fmt.Errorf("Parking.SpaceType is required")
+	if r.Dinner != nil {
+		if strings.TrimSpace(r.Dinner.MenuType) == "" {
+			return fmt.Errorf("Dinner.MenuType is required")
+		}
+		if r.Dinner.Guests <= 0 {
+			return fmt.Errorf("Dinner.Guests must be greater than 0")
+		}
+		if r.Dinner.DateTime.Before(r.Hotel.CheckIn) || !r.Dinner.DateTime.Before(r.Hotel.CheckOut) {
+			return fmt.Errorf("Dinner.DateTime must be within the stay")
+		}
+	}
+	if r.Parking != nil {
+		if strings.TrimSpace(r.Parking.SpaceType) == "" {
+			return fmt.Errorf("Parking.SpaceType is required")
+		}
+		if !r.Parking.EndTime.After(r.Parking.StartTime) {
+			return fmt.Errorf("Parking.EndTime must be after Parking.StartTime")
+		}
+		if !r.Parking.StartTime.Before(r.Hotel.CheckOut) || !r.Parking.EndTime.After(r.Hotel.CheckIn) {
+			return fmt.Errorf("Parking window must overlap the stay")
+		}
 	}
 	return nil
 }
 
-// HotelBookingSaga ホテル予約Sagaワークフロー
-func HotelBookingSaga(ctx workflow.Context, request BookingRequest) (*BookingResult, error) {
-	logger := workflow.GetLogger(ctx)
-	logger.Info("ホテル予約Sagaワークフローを開始", "BookingID", request.BookingID, "UserID", request.UserID)
-
-	// リクエストのバリデーション
-	if err := request.Validate(); err != nil {
-		logger.Error("リクエストのバリデーションに失敗", "Error", err.Error())
-		return &BookingResult{
-			Success:   false,
-			BookingID: request.BookingID,
-			Message:   fmt.Sprintf("バリデーションエラー: %s", err.Error()),
-		}, nil // ワークフローとしては正常終了、結果でエラーを表現
-	}
+// degrade 必須ではないステップの失敗を警告として記録する（予約は継続する）
+func (r *BookingResult) degrade(step config.Step, stepName string, err error) {
+	r.Degraded = true
+	r.DegradedSteps = append(r.DegradedSteps, step)
+	r.Warnings = append(r.Warnings, fmt.Sprintf("%sに失敗したため、この予約には含まれていません: %s", stepName, failureReason(err)))
+}
 
-	// リトライポリシーの設定
-	retryPolicy := &temporal.RetryPolicy{
-		InitialInterval:    time.Second,
-		BackoffCoefficient: 2.0,
-		MaximumInterval:    time.Minute,
-		MaximumAttempts:    3,
-		NonRetryableErrorTypes: []string{
-			"*activities.BusinessError",
-			"*activities.ValidationError",
-		},
+// recordFailure 失敗したステップとエラーコードを予約結果に記録
+func (r *BookingResult) recordFailure(step config.Step, stepName string, err error) {
+	r.FailedStep = step
+	r.ErrorCode = activities.ErrorCode(err)
+	if businessErr, ok := activities.FromActivityError(err).(*activities.BusinessError); ok {
+		r.Shortages = businessErr.Shortages
 	}
+	r.Message = fmt.Sprintf("%sに失敗: %s", stepName, failureReason(err))
+}
 
-	// アクティビティオプションの設定
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: 30 * time.Second,
-		RetryPolicy:         retryPolicy,
+// failureReason 失敗したアクティビティのエラーからエラーコードに応じた失敗理由を組み立てる
+func failureReason(err error) string {
+	switch e := activities.FromActivityError(err).(type) {
+	case *activities.BusinessError:
+		switch e.Code {
+		case activities.CodeRoomFull:
+			return "指定された日程に空室がありません"
+		case activities.CodeOutOfStock:
+			if len(e.Shortages) > 0 {
+				return fmt.Sprintf("ディナー食材が在庫不足です: %s", activities.FormatShortages(e.Shortages))
+			}
+			return "ディナー食材が在庫不足です"
+		case activities.CodeParkingFull:
+			return "指定された時間帯に空いている駐車スペースがありません"
+		}
+		return e.Message
+	case *activities.ServerError:
+		return fmt.Sprintf("リトライ上限に達しました（%s）", e.Message)
+	case *activities.UnknownError:
+		return fmt.Sprintf("リトライ上限に達しました（%s）", e.Message)
+	default:
+		return err.Error()
 	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+}
 
-	// 結果の初期化
-	result := &BookingResult{
-		Success:       false,
-		BookingID:     request.BookingID,
-		Compensations: []string{},
-	}
+// compensationOptions 補償ステップのアクティビティオプションを取得
+func compensationOptions(step config.Step) *workflow.ActivityOptions {
+	options := config.GetActivityOptions(step)
+	return &options
+}
 
-	// Sagaパターンでの補償処理管理
-	var compensations Compensations
+// 補償処理の名前
+const (
+	HotelCompensationName   = "ホテルルーム補償"
+	DinnerCompensationName  = "ディナー食材補償"
+	ParkingCompensationName = "駐車場補償"
+)
 
-	// Step 1: ホテルルーム予約
-	logger.Info("ステップ 1: ホテルルーム予約を開始", "HotelID", request.Hotel.HotelID)
-	hotelRequest := activities.HotelBookingRequest{
+// hotelBookingRequest ホテルルーム予約アクティビティのリクエストを組み立てる
+func hotelBookingRequest(request BookingRequest, revision int) activities.HotelBookingRequest {
+	return activities.HotelBookingRequest{
 		BookingID: request.BookingID,
 		UserID:    request.UserID,
 		HotelID:   request.Hotel.HotelID,
+		CheckIn:   request.Hotel.CheckIn,
+		CheckOut:  request.Hotel.CheckOut,
+		RoomType:  request.Hotel.RoomType,
+		Revision:  revision,
 	}
+}
 
-	var hotelResult activities.HotelBookingResult
-	err := workflow.ExecuteActivity(ctx, activities.HotelRoomBookingActivity, hotelRequest).Get(ctx, &hotelResult)
-	if err != nil {
-		logger.Error("ホテルルーム予約に失敗", "Error", err.Error())
-		result.Message = fmt.Sprintf("ホテルルーム予約に失敗: %s", err.Error())
-		return result, nil
+// dinnerBookingRequest ディナー食材予約アクティビティのリクエストを組み立てる
+func dinnerBookingRequest(request BookingRequest, revision int) activities.DinnerBookingRequest {
+	return activities.DinnerBookingRequest{
+		BookingID: request.BookingID,
+		UserID:    request.UserID,
+		MenuType:  request.Dinner.MenuType,
+		DateTime:  request.Dinner.DateTime,
+		Guests:    request.Dinner.Guests,
+		Revision:  revision,
 	}
+}
 
-	result.HotelResult = &hotelResult
-	logger.Info("ステップ 1: ホテルルーム予約が完了", "ResourceID", hotelResult.ResourceID)
-
-	// 補償アクティビティの追加
-	compensations.AddCompensation(activities.CompensateHotelRoomActivity)
-
-	// Step 2: ディナー食材予約
-	logger.Info("ステップ 2: ディナー食材予約を開始", "MenuType", request.Dinner.MenuType)
-	dinnerRequest := activities.DinnerBookingRequest{
+// parkingBookingRequest 駐車場予約アクティビティのリクエストを組み立てる
+func parkingBookingRequest(request BookingRequest, revision int) activities.ParkingBookingRequest {
+	return activities.ParkingBookingRequest{
 		BookingID: request.BookingID,
 		UserID:    request.UserID,
-		MenuType:  request.Dinner.MenuType,
+		SpaceType: request.Parking.SpaceType,
+		StartTime: request.Parking.StartTime,
+		EndTime:   request.Parking.EndTime,
+		Revision:  revision,
 	}
+}
 
-	var dinnerResult activities.DinnerBookingResult
-	err = workflow.ExecuteActivity(ctx, activities.DinnerFoodBookingActivity, dinnerRequest).Get(ctx, &dinnerResult)
-	if err != nil {
-		logger.Error("ディナー食材予約に失敗", "Error", err.Error())
-		result.Message = fmt.Sprintf("ディナー食材予約に失敗: %s", err.Error())
-		// 補償処理を実行
-		logger.Info("補償処理を開始")
-		compensations.Compensate(ctx, false) // 順次実行
-		return result, nil
+// bookingPipeline ホテル予約Sagaのステップ
+// 順次実行ではホテル → ディナー → 駐車場の順に、並行実行ではホテルの完了後にディナーと駐車場を同時に実行する
+var bookingPipeline = SagaPipeline{hotelStep, dinnerStep, parkingStep}
+
+// hotelStep ホテルルーム予約ステップ（常に必須）
+var hotelStep = SagaStep{
+	Name:     "ホテルルーム予約",
+	Step:     config.StepHotel,
+	Phase:    PhaseBookingHotel,
+	Activity: activities.HotelRoomBookingActivity,
+	Request: func(request BookingRequest, revision int) interface{} {
+		return hotelBookingRequest(request, revision)
+	},
+	NewResult: func() activities.ReservationResult { return &activities.HotelBookingResult{} },
+	Modified:  func(m ModificationRequest) bool { return m.Hotel != nil },
+	Collect: func(result *BookingResult, status *BookingStatus, stepResult activities.ReservationResult) {
+		hotelResult := stepResult.(*activities.HotelBookingResult)
+		result.HotelResult, status.HotelResult = hotelResult, hotelResult
+	},
+	Compensation: SagaCompensation{
+		Name:     HotelCompensationName,
+		Activity: activities.CompensateHotelRoomActivity,
+		Step:     config.StepHotelCompensation,
+	},
+}
+
+// dinnerStep ディナー食材予約ステップ（指定がない場合はスキップ）
+var dinnerStep = SagaStep{
+	Name:     "ディナー食材予約",
+	Step:     config.StepDinner,
+	Phase:    PhaseBookingDinner,
+	Activity: activities.DinnerFoodBookingActivity,
+	Request: func(request BookingRequest, revision int) interface{} {
+		if request.Dinner == nil {
+			return nil
+		}
+		return dinnerBookingRequest(request, revision)
+	},
+	DependsOn:  []config.Step{config.StepHotel},
+	NewResult:  func() activities.ReservationResult { return &activities.DinnerBookingResult{} },
+	BestEffort: func(request BookingRequest) bool { return request.Dinner != nil && request.Dinner.BestEffort },
+	Modified:   func(m ModificationRequest) bool { return m.Dinner != nil },
+	Collect: func(result *BookingResult, status *BookingStatus, stepResult activities.ReservationResult) {
+		dinnerResult := stepResult.(*activities.DinnerBookingResult)
+		result.DinnerResult, status.DinnerResult = dinnerResult, dinnerResult
+	},
+	Compensation: SagaCompensation{
+		Name:     DinnerCompensationName,
+		Activity: activities.CompensateDinnerFoodActivity,
+		Step:     config.StepDinnerCompensation,
+	},
+}
+
+// parkingStep 駐車場予約ステップ（指定がない場合はスキップ）
+var parkingStep = SagaStep{
+	Name:     "駐車場予約",
+	Step:     config.StepParking,
+	Phase:    PhaseBookingParking,
+	Activity: activities.ParkingBookingActivity,
+	Request: func(request BookingRequest, revision int) interface{} {
+		if request.Parking == nil {
+			return nil
+		}
+		return parkingBookingRequest(request, revision)
+	},
+	DependsOn:  []config.Step{config.StepHotel},
+	NewResult:  func() activities.ReservationResult { return &activities.ParkingBookingResult{} },
+	BestEffort: func(request BookingRequest) bool { return request.Parking != nil && request.Parking.BestEffort },
+	Modified:   func(m ModificationRequest) bool { return m.Parking != nil },
+	Collect: func(result *BookingResult, status *BookingStatus, stepResult activities.ReservationResult) {
+		parkingResult := stepResult.(*activities.ParkingBookingResult)
+		result.ParkingResult, status.ParkingResult = parkingResult, parkingResult
+	},
+	Compensation: SagaCompensation{
+		Name:     ParkingCompensationName,
+		Activity: activities.CompensateParkingActivity,
+		Step:     config.StepParkingCompensation,
+	},
+}
+
+// compensate 補償処理を逆順に実行し、失敗した補償はオペレーターの手動対応を待ってから結果に反映
+func compensate(ctx workflow.Context, bookingID string, compensations Compensations, result *BookingResult, status *BookingStatus) {
+	result.applyCompensationReport(runCompensations(ctx, bookingID, compensations, status))
+}
+
+// runCompensations 補償処理を逆順に実行し、失敗した補償はオペレーターの手動対応を待ってからレポートを返す
+func runCompensations(ctx workflow.Context, bookingID string, compensations Compensations, status *BookingStatus) CompensationReport {
+	status.enter(PhaseCompensating)
+	report := compensations.Compensate(ctx, false) // 順次実行
+	status.CompensationReport = &report
+	if len(report.Failed()) > 0 {
+		status.enter(PhaseAwaitingManualResolution)
 	}
+	return awaitManualResolution(ctx, bookingID, compensations, report)
+}
+
+// HotelBookingSaga ホテル予約Sagaワークフロー
+func HotelBookingSaga(ctx workflow.Context, request BookingRequest) (*BookingResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("ホテル予約Sagaワークフローを開始", "BookingID", request.BookingID, "UserID", request.UserID)
 
-	result.DinnerResult = &dinnerResult
-	logger.Info("ステップ 2: ディナー食材予約が完了", "ResourceID", dinnerResult.ResourceID)
+	// 予約状況の問い合わせに応答できるようにする
+	status := newBookingStatus(ctx, request.BookingID)
+	recordBookingStarted(ctx, request)
 
-	// 補償アクティビティの追加
-	compensations.AddCompensation(activities.CompensateDinnerFoodActivity)
+	// 変更IDの導入前に開始した予約は、導入前の手順とバリデーションで実行する
+	validate := request.Validate
+	baseline := bookingStepsVersionOf(ctx) == workflow.DefaultVersion
+	if baseline {
+		validate = request.validateBaseline
+	}
 
-	// Step 3: 駐車場予約
-	logger.Info("ステップ 3: 駐車場予約を開始", "SpaceType", request.Parking.SpaceType)
-	parkingRequest := activities.ParkingBookingRequest{
-		BookingID: request.BookingID,
-		UserID:    request.UserID,
-		SpaceType: request.Parking.SpaceType,
+	// リクエストのバリデーション
+	if err := validate(); err != nil {
+		logger.Error("リクエストのバリデーションに失敗", "Error", err.Error())
+		result := &BookingResult{
+			Success:   false,
+			BookingID: request.BookingID,
+			Message:   fmt.Sprintf("バリデーションエラー: %s", err.Error()),
+		}
+		status.finish(result)
+		recordFailedStep(ctx, failedStepValidation)
+		return result, nil // ワークフローとしては正常終了、結果でエラーを表現
 	}
 
-	var parkingResult activities.ParkingBookingResult
-	err = workflow.ExecuteActivity(ctx, activities.ParkingBookingActivity, parkingRequest).Get(ctx, &parkingResult)
-	if err != nil {
-		logger.Error("駐車場予約に失敗", "Error", err.Error())
-		result.Message = fmt.Sprintf("駐車場予約に失敗: %s", err.Error())
+	// 結果の初期化
+	result := &BookingResult{
+		Success:       false,
+		BookingID:     request.BookingID,
+		Compensations: []string{},
+	}
+	defer status.finish(result)
 
-		// 補償処理を実行
-		logger.Info("補償処理を開始")
-		compensations.Compensate(ctx, false) // 順次実行
+	if baseline {
+		runBaselineBookingSteps(ctx, request, result, status)
+		if result.Success {
+			recordBookingSucceeded(ctx, result)
+		} else {
+			recordBookingFailed(ctx, result)
+		}
 		return result, nil
 	}
 
-	result.ParkingResult = &parkingResult
-	logger.Info("ステップ 3: 駐車場予約が完了", "ResourceID", parkingResult.ResourceID)
+	// 予約変更は全ての予約が確定した後にのみ受け付ける
+	booking := newConfirmedBooking(ctx, status)
 
-	// 補償アクティビティの追加
-	compensations.AddCompensation(activities.CompensateParkingActivity)
+	// 各ステップを順番に実行する（失敗やお客様のキャンセル時は完了済みのステップを補償する）
+	run := &sagaRun{
+		request:      request,
+		result:       result,
+		status:       status,
+		cancellation: newCancellationListener(ctx),
+	}
+	runPipeline := bookingPipeline.run
+	if request.Execution == ExecutionParallel {
+		runPipeline = bookingPipeline.runGraph
+	}
+	if !runPipeline(ctx, run) {
+		recordBookingFailed(ctx, result)
+		return result, nil
+	}
 
-	// 全て成功した場合
+	// 全て成功した場合（必須ではないステップの失敗は警告として残す）
 	result.Success = true
 	result.Message = "ホテル予約Sagaが正常に完了しました"
-	logger.Info("ホテル予約Sagaワークフローが正常完了", "BookingID", request.BookingID)
+	if result.Degraded {
+		result.Message = fmt.Sprintf("ホテル予約Sagaが完了しました（%d件の予約は含まれていません）", len(result.Warnings))
+	}
+	logger.Info("全ての予約が完了", "BookingID", request.BookingID)
+	recordBookingSucceeded(ctx, result)
+
+	// チェックアウトまで予約を管理する（チェックイン前はお客様のキャンセルと予約変更を受け付ける）
+	booking.confirm(request, bookingPipeline, run.compensations, result)
+	notifyParent(ctx, result)
+	holdUntilCheckOut(ctx, booking, run.cancellation)
 
+	logger.Info("ホテル予約Sagaワークフローが正常完了", "BookingID", request.BookingID, "Lifecycle", result.Lifecycle)
 	return result, nil
 }
@@ -3,10 +3,13 @@ package workflows
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"go.temporal.io/sdk/testsuite"
 	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+	"temporal-hotel-sample/internal/inventory"
 )
 
 // TestHotelBookingSagaWorkflow_WithMissCompensation
@@ -19,6 +22,7 @@ import (
 //   - ホテルルーム、ディナー食材、駐車場の順に成功する、補償アクションは動かない
 //
 // 準異常系
+//   - ホテルルーム予約でサーバーエラーが2回発生するが、リトライにより成功する
 //   - ディナー食材予約で失敗するが、ホテルルームの補償アクションが1回で成功して整合性を保てる
 //   - ディナー食材予約で失敗するが、ホテルルームの補償アクションが2回失敗してから成功して整合性を保てる
 //   - 駐車場予約で失敗するが、ホテルルームとディナー食材の補償アクションが成功して整合性を保てる
@@ -56,15 +60,17 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 		expectedHotelSuccess    bool
 		expectedDinnerSuccess   bool
 		expectedParkingSuccess  bool
+		expectedCompensations   []string
+		expectedRollbackStatus  CompensationStatus
 	}{
 		// 正常系: ホテルルーム、ディナー食材、駐車場の順に成功する、補償アクションは動かない
 		"正常系 - ホテルルーム、ディナー食材、駐車場の順に成功": {
 			request: BookingRequest{
 				BookingID: "booking-success-001",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "standard"},
-				Parking:   ParkingRequest{SpaceType: "standard"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -88,14 +94,46 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			expectedDinnerSuccess:   true,
 			expectedParkingSuccess:  true,
 		},
+		// 準異常系: ホテルルーム予約でサーバーエラーが2回発生するが、リトライにより成功する
+		"準異常系 - ホテルルーム予約サーバーエラー2回後にリトライで成功": {
+			request: BookingRequest{
+				BookingID: "booking-hotel-retry-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			},
+			mockHotelError: &activities.ServerError{Message: "ネットワークエラーが発生しました"},
+			mockHotelTimes: 2, // サーバーエラーはリトライされる
+			mockHotelResult: &activities.HotelBookingResult{
+				Success:    true,
+				ResourceID: "room-008",
+				Message:    "ホテルルーム予約が完了しました",
+			},
+			mockDinnerResult: &activities.DinnerBookingResult{
+				Success:    true,
+				ResourceID: "food-008",
+				Message:    "ディナー食材予約が完了しました",
+			},
+			mockParkingResult: &activities.ParkingBookingResult{
+				Success:    true,
+				ResourceID: "parking-008",
+				Message:    "駐車場予約が完了しました",
+			},
+
+			expectedWorkflowSuccess: true,
+			expectedHotelSuccess:    true,
+			expectedDinnerSuccess:   true,
+			expectedParkingSuccess:  true,
+		},
 		// 準異常系: ディナー食材予約で失敗するが、ホテルルームの補償アクションが1回で成功
 		"準異常系 - ディナー食材予約失敗、ホテルルーム補償1回で成功": {
 			request: BookingRequest{
 				BookingID: "booking-dinner-fail-001",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "out-of-stock"},
-				Parking:   ParkingRequest{SpaceType: "standard"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "out-of-stock", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -104,7 +142,7 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			},
 
 			mockDinnerError: &activities.BusinessError{Message: "指定されたメニューの食材が在庫不足です"},
-			mockDinnerTimes: 3,
+			mockDinnerTimes: 1, // ビジネスエラーはリトライされない
 			mockHotelCompensationResult: &activities.CompensationResult{
 				Success: true,
 				Message: "ホテルルーム補償が完了しました",
@@ -114,15 +152,17 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			expectedHotelSuccess:    true,
 			expectedDinnerSuccess:   false,
 			expectedParkingSuccess:  false,
+			expectedCompensations:   []string{"ホテルルーム補償(room-002)"},
+			expectedRollbackStatus:  CompensationStatusRolledBack,
 		},
 		// 準異常系: ディナー食材予約で失敗するが、ホテルルームの補償アクションが2回失敗してから成功
 		"準異常系 - ディナー食材予約失敗、ホテルルーム補償2回失敗後成功": {
 			request: BookingRequest{
 				BookingID: "booking-dinner-fail-002",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "out-of-stock"},
-				Parking:   ParkingRequest{SpaceType: "standard"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "out-of-stock", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -131,7 +171,7 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			},
 
 			mockDinnerError:            &activities.BusinessError{Message: "指定されたメニューの食材が在庫不足です"},
-			mockDinnerTimes:            3,
+			mockDinnerTimes:            1, // ビジネスエラーはリトライされない
 			mockHotelCompensationError: &activities.ServerError{Message: "補償処理で一時的エラーが発生しました"},
 			mockHotelCompensationTimes: 2,
 			mockHotelCompensationResult: &activities.CompensationResult{
@@ -143,15 +183,17 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			expectedHotelSuccess:    true,
 			expectedDinnerSuccess:   false,
 			expectedParkingSuccess:  false,
+			expectedCompensations:   []string{"ホテルルーム補償(room-003)"},
+			expectedRollbackStatus:  CompensationStatusRolledBack,
 		},
 		// 準異常系: 駐車場予約で失敗するが、ホテルルーム・ディナー食材の補償アクションが成功
 		"準異常系 - 駐車場予約失敗、ホテルルーム・ディナー食材補償成功": {
 			request: BookingRequest{
 				BookingID: "booking-parking-fail-001",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "standard"},
-				Parking:   ParkingRequest{SpaceType: "full"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "full", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -166,7 +208,7 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			},
 
 			mockParkingError: &activities.BusinessError{Message: "指定された駐車場は満車です"},
-			mockParkingTimes: 3,
+			mockParkingTimes: 1, // ビジネスエラーはリトライされない
 			mockHotelCompensationResult: &activities.CompensationResult{
 				Success: true,
 				Message: "ホテルルーム補償が完了しました",
@@ -181,15 +223,17 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			expectedHotelSuccess:    true,
 			expectedDinnerSuccess:   true,
 			expectedParkingSuccess:  false,
+			expectedCompensations:   []string{"ディナー食材補償(food-004)", "ホテルルーム補償(room-004)"},
+			expectedRollbackStatus:  CompensationStatusRolledBack,
 		},
 		// 準異常系: 駐車場予約で失敗、ホテルルーム・ディナー食材の補償アクションが2回ずつ失敗してから成功
 		"準異常系 - 駐車場予約失敗、ホテルルーム・ディナー食材補償2回失敗後成功": {
 			request: BookingRequest{
 				BookingID: "booking-parking-fail-002",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "standard"},
-				Parking:   ParkingRequest{SpaceType: "full"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "full", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -204,7 +248,7 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			},
 
 			mockParkingError:           &activities.BusinessError{Message: "指定された駐車場は満車です"},
-			mockParkingTimes:           3,
+			mockParkingTimes:           1, // ビジネスエラーはリトライされない
 			mockHotelCompensationError: &activities.ServerError{Message: "ホテル補償処理で一時的エラーが発生しました"},
 			mockHotelCompensationTimes: 2,
 			mockHotelCompensationResult: &activities.CompensationResult{
@@ -223,18 +267,20 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			expectedHotelSuccess:    true,
 			expectedDinnerSuccess:   true,
 			expectedParkingSuccess:  false,
+			expectedCompensations:   []string{"ディナー食材補償(food-005)", "ホテルルーム補償(room-005)"},
+			expectedRollbackStatus:  CompensationStatusRolledBack,
 		},
 		// 異常系: ホテルルーム予約で失敗して、エラーを返却して終了
 		"異常系 - ホテルルーム予約失敗、エラー返却終了": {
 			request: BookingRequest{
 				BookingID: "booking-hotel-fail-001",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-full"},
-				Dinner:    DinnerRequest{MenuType: "standard"},
-				Parking:   ParkingRequest{SpaceType: "standard"},
+				Hotel:     HotelRequest{HotelID: "hotel-full", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelError:          &activities.BusinessError{Message: "指定されたホテルは満室です"},
-			mockHotelTimes:          3,
+			mockHotelTimes:          1, // ビジネスエラーはリトライされない
 			expectedWorkflowSuccess: false,
 			expectedHotelSuccess:    false,
 			expectedDinnerSuccess:   false,
@@ -245,9 +291,9 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			request: BookingRequest{
 				BookingID: "booking-dinner-fail-comp-fail-001",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "out-of-stock"},
-				Parking:   ParkingRequest{SpaceType: "standard"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "out-of-stock", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -256,22 +302,23 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			},
 
 			mockDinnerError:            &activities.BusinessError{Message: "指定されたメニューの食材が在庫不足です"},
-			mockDinnerTimes:            3,
+			mockDinnerTimes:            1, // ビジネスエラーはリトライされない
 			mockHotelCompensationError: &activities.ServerError{Message: "補償処理システムがダウンしています"},
 			mockHotelCompensationTimes: 3, // リトライ回数上限
 			expectedWorkflowSuccess:    false,
 			expectedHotelSuccess:       true,
 			expectedDinnerSuccess:      false,
 			expectedParkingSuccess:     false,
+			expectedRollbackStatus:     CompensationStatusPartiallyFailed,
 		},
 		// 異常系: 駐車場予約で失敗、ホテルルーム・ディナー食材の補償アクションが3回ずつ失敗してエラー終了
 		"異常系 - 駐車場予約失敗、ホテルルーム・ディナー食材補償3回失敗": {
 			request: BookingRequest{
 				BookingID: "booking-parking-fail-comp-fail-001",
 				UserID:    "user-001",
-				Hotel:     HotelRequest{HotelID: "hotel-001"},
-				Dinner:    DinnerRequest{MenuType: "standard"},
-				Parking:   ParkingRequest{SpaceType: "full"},
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "full", StartTime: testParkingStart, EndTime: testParkingEnd},
 			},
 			mockHotelResult: &activities.HotelBookingResult{
 				Success:    true,
@@ -286,7 +333,7 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			},
 
 			mockParkingError:            &activities.BusinessError{Message: "指定された駐車場は満車です"},
-			mockParkingTimes:            3,
+			mockParkingTimes:            1, // ビジネスエラーはリトライされない
 			mockHotelCompensationError:  &activities.ServerError{Message: "ホテル補償処理システムがダウンしています"},
 			mockHotelCompensationTimes:  3, // リトライ回数上限
 			mockDinnerCompensationError: &activities.ServerError{Message: "ディナー補償処理システムがダウンしています"},
@@ -295,6 +342,7 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			expectedHotelSuccess:        true,
 			expectedDinnerSuccess:       true,
 			expectedParkingSuccess:      false,
+			expectedRollbackStatus:      CompensationStatusPartiallyFailed,
 		},
 	}
 
@@ -311,6 +359,8 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			testEnv.RegisterActivity(activities.CompensateHotelRoomActivity)
 			testEnv.RegisterActivity(activities.CompensateDinnerFoodActivity)
 			testEnv.RegisterActivity(activities.CompensateParkingActivity)
+			testEnv.RegisterActivity(activities.RecordStuckCompensationActivity)
+			testEnv.RegisterActivity(activities.UpdateStuckCompensationActivity)
 
 			// モックの設定
 			// ホテルルーム予約
@@ -345,21 +395,21 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 
 			// ホテルルーム補償処理
 			if tt.mockHotelCompensationTimes > 0 {
-				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, tt.request.BookingID, tt.mockHotelResult.ResourceID).Return(
 					nil, tt.mockHotelCompensationError).Times(tt.mockHotelCompensationTimes)
 			}
 			if tt.mockHotelCompensationResult != nil {
-				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, tt.request.BookingID, tt.mockHotelResult.ResourceID).Return(
 					tt.mockHotelCompensationResult, nil)
 			}
 
 			// ディナー食材補償処理
 			if tt.mockDinnerCompensationTimes > 0 {
-				testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, tt.request.BookingID, tt.mockDinnerResult.ResourceID).Return(
 					nil, tt.mockDinnerCompensationError).Times(tt.mockDinnerCompensationTimes)
 			}
 			if tt.mockDinnerCompensationResult != nil {
-				testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, tt.request.BookingID, tt.mockDinnerResult.ResourceID).Return(
 					tt.mockDinnerCompensationResult, nil)
 			}
 
@@ -390,9 +440,367 @@ func TestHotelBookingSagaWorkflow_WithMissCompensation(t *testing.T) {
 			assert.Equal(t, tt.expectedHotelSuccess, result.HotelResult != nil && result.HotelResult.Success)
 			assert.Equal(t, tt.expectedDinnerSuccess, result.DinnerResult != nil && result.DinnerResult.Success)
 			assert.Equal(t, tt.expectedParkingSuccess, result.ParkingResult != nil && result.ParkingResult.Success)
+			assert.Equal(t, tt.expectedCompensations, result.Compensations)
+			assert.Equal(t, tt.expectedRollbackStatus, result.RollbackStatus)
+			assert.Equal(t, tt.expectedRollbackStatus == CompensationStatusPartiallyFailed, result.ManualActionRequired)
 
 			// 補償処理の呼び出し確認（リトライを含む）
 			testEnv.AssertExpectations(t)
 		})
 	}
 }
+
+// testケース
+// 異常系:
+//   - ホテルルーム予約がROOM_FULLで失敗した時、エラーコードと失敗ステップが結果に記録される
+//   - ディナー食材予約がOUT_OF_STOCKで失敗した時、エラーコードと失敗ステップ、不足する食材が結果に記録される
+//   - 駐車場予約がサーバーエラーでリトライ上限に達した時、エラーコードと失敗ステップが結果に記録される
+func TestHotelBookingSaga_FailureErrorCode(t *testing.T) {
+	tests := map[string]struct {
+		hotelErr   error
+		dinnerErr  error
+		parkingErr error
+
+		expectedFailedStep config.Step
+		expectedErrorCode  string
+		expectedMessage    string
+		expectedShortages  []inventory.Shortage
+	}{
+		"異常系 - ホテルルーム予約がROOM_FULLで失敗": {
+			hotelErr:           activities.NewBusinessError("指定されたホテルは満室です", activities.CodeRoomFull),
+			expectedFailedStep: config.StepHotel,
+			expectedErrorCode:  activities.CodeRoomFull,
+			expectedMessage:    "ホテルルーム予約に失敗: 指定された日程に空室がありません",
+		},
+		"異常系 - ディナー食材予約がOUT_OF_STOCKで失敗": {
+			dinnerErr: activities.NewOutOfStockError("指定されたメニューの食材が在庫不足です: wagyu（必要 2 / 在庫 1）",
+				[]inventory.Shortage{{Ingredient: "wagyu", Required: 2, Available: 1}}),
+			expectedFailedStep: config.StepDinner,
+			expectedErrorCode:  activities.CodeOutOfStock,
+			expectedMessage:    "ディナー食材予約に失敗: ディナー食材が在庫不足です: wagyu（必要 2 / 在庫 1）",
+			expectedShortages:  []inventory.Shortage{{Ingredient: "wagyu", Required: 2, Available: 1}},
+		},
+		"異常系 - 駐車場予約がサーバーエラーでリトライ上限に到達": {
+			parkingErr:         activities.NewServerError("駐車場管理システムへの接続に失敗しました", "CONNECTION_ERROR"),
+			expectedFailedStep: config.StepParking,
+			expectedErrorCode:  "CONNECTION_ERROR",
+			expectedMessage:    "駐車場予約に失敗: リトライ上限に達しました（駐車場管理システムへの接続に失敗しました）",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			testSuite := &testsuite.WorkflowTestSuite{}
+			testEnv := testSuite.NewTestWorkflowEnvironment()
+			testEnv.RegisterActivity(activities.HotelRoomBookingActivity)
+			testEnv.RegisterActivity(activities.DinnerFoodBookingActivity)
+			testEnv.RegisterActivity(activities.ParkingBookingActivity)
+			testEnv.RegisterActivity(activities.CompensateHotelRoomActivity)
+			testEnv.RegisterActivity(activities.CompensateDinnerFoodActivity)
+
+			hotelResult := &activities.HotelBookingResult{Success: true, ResourceID: "room-201"}
+			dinnerResult := &activities.DinnerBookingResult{Success: true, ResourceID: "food-201"}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				hotelResult, activities.ToApplicationError(tt.hotelErr))
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, mock.Anything).Return(
+				dinnerResult, activities.ToApplicationError(tt.dinnerErr)).Maybe()
+			testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, mock.Anything).Return(
+				nil, activities.ToApplicationError(tt.parkingErr)).Maybe()
+			testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, nil).Maybe()
+			testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, nil).Maybe()
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, BookingRequest{
+				BookingID: "booking-error-code-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			})
+
+			// then
+			if !testEnv.IsWorkflowCompleted() {
+				t.Fatal("ワークフローが完了していません")
+			}
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.False(t, result.Success)
+			assert.Equal(t, tt.expectedFailedStep, result.FailedStep)
+			assert.Equal(t, tt.expectedErrorCode, result.ErrorCode)
+			assert.Equal(t, tt.expectedMessage, result.Message)
+			assert.Equal(t, tt.expectedShortages, result.Shortages)
+		})
+	}
+}
+
+func TestHotelBookingSaga_ForwardsSubRequests(t *testing.T) {
+	// given
+	testSuite := &testsuite.WorkflowTestSuite{}
+	testEnv := testSuite.NewTestWorkflowEnvironment()
+	testEnv.RegisterActivity(activities.HotelRoomBookingActivity)
+	testEnv.RegisterActivity(activities.DinnerFoodBookingActivity)
+	testEnv.RegisterActivity(activities.ParkingBookingActivity)
+
+	request := BookingRequest{
+		BookingID: "booking-forward-001",
+		UserID:    "user-001",
+		Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut, RoomType: "deluxe"},
+		Dinner:    &DinnerRequest{MenuType: "course", DateTime: testDinnerTime, Guests: 3},
+		Parking:   &ParkingRequest{SpaceType: "large", StartTime: testParkingStart, EndTime: testParkingEnd},
+	}
+
+	// then: サブリクエストの内容がアクティビティにそのまま渡される
+	testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, activities.HotelBookingRequest{
+		BookingID: "booking-forward-001",
+		UserID:    "user-001",
+		HotelID:   "hotel-001",
+		CheckIn:   testCheckIn,
+		CheckOut:  testCheckOut,
+		RoomType:  "deluxe",
+	}).Return(&activities.HotelBookingResult{Success: true, ResourceID: "room-hotel-001-201-2026-11-01"}, nil).Once()
+	testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, activities.DinnerBookingRequest{
+		BookingID: "booking-forward-001",
+		UserID:    "user-001",
+		MenuType:  "course",
+		DateTime:  testDinnerTime,
+		Guests:    3,
+	}).Return(&activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}, nil).Once()
+	testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, activities.ParkingBookingRequest{
+		BookingID: "booking-forward-001",
+		UserID:    "user-001",
+		SpaceType: "large",
+		StartTime: testParkingStart,
+		EndTime:   testParkingEnd,
+	}).Return(&activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}, nil).Once()
+
+	// when
+	testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+	// then
+	var result BookingResult
+	assert.NoError(t, testEnv.GetWorkflowResult(&result))
+	assert.True(t, result.Success)
+	testEnv.AssertExpectations(t)
+}
+
+// テストケースについて
+// 正常系:
+//   - 宿泊期間内のディナーと、宿泊期間と重なる駐車場の予約は妥当
+//   - ディナーと駐車場を省略したホテルのみの予約は妥当
+//   - 並行実行を指定した予約は妥当
+//
+// 異常系:
+//   - 不明な実行方式を指定した時、エラーが返却される
+//   - チェックアウトがチェックイン以前の時、エラーが返却される
+//   - チェックアウトがチェックインと同じ日付の時、エラーが返却される
+//   - 宿泊が最大泊数を超える時、エラーが返却される
+//   - ディナーの人数が0の時、エラーが返却される
+//   - ディナーの日時が宿泊期間外の時、エラーが返却される
+//   - 駐車場の終了時刻が開始時刻以前の時、エラーが返却される
+//   - 駐車場の利用時間が宿泊期間と重ならない時、エラーが返却される
+func TestBookingRequest_Validate(t *testing.T) {
+	valid := func() BookingRequest {
+		return BookingRequest{
+			BookingID: "booking-001",
+			UserID:    "user-001",
+			Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+			Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+			Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+		}
+	}
+
+	testcases := map[string]struct {
+		modify      func(r *BookingRequest)
+		expectedErr string
+	}{
+		"正常系: 宿泊期間内のディナーと宿泊期間と重なる駐車場": {
+			modify: func(r *BookingRequest) {},
+		},
+		"正常系: ディナーと駐車場を省略": {
+			modify: func(r *BookingRequest) { r.Dinner, r.Parking = nil, nil },
+		},
+		"正常系: 並行実行を指定": {
+			modify: func(r *BookingRequest) { r.Execution = ExecutionParallel },
+		},
+		"異常系: 不明な実行方式": {
+			modify:      func(r *BookingRequest) { r.Execution = "random" },
+			expectedErr: `Execution must be "sequential" or "parallel"`,
+		},
+		"異常系: チェックアウトがチェックイン以前": {
+			modify:      func(r *BookingRequest) { r.Hotel.CheckOut = r.Hotel.CheckIn },
+			expectedErr: "Hotel.CheckOut must be after Hotel.CheckIn",
+		},
+		"異常系: チェックアウトがチェックインと同じ日付": {
+			modify:      func(r *BookingRequest) { r.Hotel.CheckOut = r.Hotel.CheckIn.Add(time.Hour) },
+			expectedErr: "Hotel stay is invalid: invalid stay: check-out must be after check-in",
+		},
+		"異常系: 宿泊が最大泊数を超える": {
+			modify:      func(r *BookingRequest) { r.Hotel.CheckOut = r.Hotel.CheckIn.AddDate(0, 0, inventory.MaxStayNights+1) },
+			expectedErr: "Hotel stay is invalid: invalid stay: stay exceeds 30 nights",
+		},
+		"異常系: ディナーの人数が0": {
+			modify:      func(r *BookingRequest) { r.Dinner.Guests = 0 },
+			expectedErr: "Dinner.Guests must be greater than 0",
+		},
+		"異常系: ディナーの日時がチェックアウト以降": {
+			modify:      func(r *BookingRequest) { r.Dinner.DateTime = testCheckOut.Add(9 * time.Hour) },
+			expectedErr: "Dinner.DateTime must be within the stay",
+		},
+		"異常系: ディナーの日時がチェックイン前": {
+			modify:      func(r *BookingRequest) { r.Dinner.DateTime = testCheckIn.Add(-time.Hour) },
+			expectedErr: "Dinner.DateTime must be within the stay",
+		},
+		"異常系: 駐車場の終了時刻が開始時刻以前": {
+			modify:      func(r *BookingRequest) { r.Parking.EndTime = r.Parking.StartTime },
+			expectedErr: "Parking.EndTime must be after Parking.StartTime",
+		},
+		"異常系: 駐車場の利用時間が宿泊期間と重ならない": {
+			modify: func(r *BookingRequest) {
+				r.Parking.StartTime = testCheckOut.Add(time.Hour)
+				r.Parking.EndTime = testCheckOut.Add(3 * time.Hour)
+			},
+			expectedErr: "Parking window must overlap the stay",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			request := valid()
+			tc.modify(&request)
+
+			// when
+			err := request.Validate()
+
+			// then
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+// テストケースについて
+// 正常系:
+//   - ディナーと駐車場を省略した時、ホテルのみを予約する
+//   - 必須ではない駐車場が満車の時、ホテルとディナーの予約を維持し、警告付きで完了する（ノーショーでディナーを解放する）
+//   - 必須ではないディナーが在庫不足の時、ホテルと駐車場の予約を継続し、警告付きで完了する（ノーショーで駐車場を解放する）
+//
+// 準異常系:
+//   - 必須の駐車場が満車の時、ホテルとディナーを補償する
+func TestHotelBookingSaga_OptionalSteps(t *testing.T) {
+	parkingFull := activities.NewBusinessError("指定された駐車場は満車です", activities.CodeParkingFull)
+	outOfStock := activities.NewBusinessError("指定されたメニューの食材が在庫不足です", activities.CodeOutOfStock)
+
+	tests := map[string]struct {
+		dinner     *DinnerRequest
+		parking    *ParkingRequest
+		dinnerErr  error
+		parkingErr error
+
+		expectedSuccess       bool
+		expectedDinner        bool
+		expectedParking       bool
+		expectedDegradedSteps []config.Step
+		expectedWarnings      []string
+		expectedReleased      []string
+	}{
+		"正常系 - ディナーと駐車場を省略する": {
+			expectedSuccess: true,
+		},
+		"正常系 - 必須ではない駐車場が満車": {
+			dinner:                &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+			parking:               &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd, BestEffort: true},
+			parkingErr:            parkingFull,
+			expectedSuccess:       true,
+			expectedDinner:        true,
+			expectedDegradedSteps: []config.Step{config.StepParking},
+			expectedWarnings:      []string{"駐車場予約に失敗したため、この予約には含まれていません: 指定された時間帯に空いている駐車スペースがありません"},
+			expectedReleased:      []string{"food-001"},
+		},
+		"正常系 - 必須ではないディナーが在庫不足": {
+			dinner:                &DinnerRequest{MenuType: "course", DateTime: testDinnerTime, Guests: 2, BestEffort: true},
+			parking:               &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			dinnerErr:             outOfStock,
+			expectedSuccess:       true,
+			expectedParking:       true,
+			expectedDegradedSteps: []config.Step{config.StepDinner},
+			expectedWarnings:      []string{"ディナー食材予約に失敗したため、この予約には含まれていません: ディナー食材が在庫不足です"},
+			expectedReleased:      []string{"parking-001"},
+		},
+		"準異常系 - 必須の駐車場が満車": {
+			dinner:           &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+			parking:          &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			parkingErr:       parkingFull,
+			expectedDinner:   true,
+			expectedReleased: []string{"food-001", "room-001"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.SetStartTime(testCheckIn.AddDate(0, 0, -7))
+
+			request := BookingRequest{
+				BookingID: "booking-optional-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    tt.dinner,
+				Parking:   tt.parking,
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			if tt.dinner != nil {
+				dinnerResult := &activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}
+				if tt.dinnerErr != nil {
+					dinnerResult = nil
+				}
+				testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, dinnerBookingRequest(request, 0)).Return(
+					dinnerResult, activities.ToApplicationError(tt.dinnerErr)).Once()
+			}
+			if tt.parking != nil {
+				parkingResult := &activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}
+				if tt.parkingErr != nil {
+					parkingResult = nil
+				}
+				testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, parkingBookingRequest(request, 0)).Return(
+					parkingResult, activities.ToApplicationError(tt.parkingErr)).Once()
+			}
+			for _, released := range tt.expectedReleased {
+				compensation := map[string]interface{}{
+					"room-001":    activities.CompensateHotelRoomActivity,
+					"food-001":    activities.CompensateDinnerFoodActivity,
+					"parking-001": activities.CompensateParkingActivity,
+				}[released]
+				testEnv.OnActivity(compensation, mock.Anything, request.BookingID, released).Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+			testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, mock.Anything).Return(
+				&activities.NotificationResult{Success: true}, nil).Maybe()
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.Equal(t, tt.expectedSuccess, result.Success)
+			assert.NotNil(t, result.HotelResult)
+			assert.Equal(t, tt.expectedDinner, result.DinnerResult != nil)
+			assert.Equal(t, tt.expectedParking, result.ParkingResult != nil)
+			assert.Equal(t, len(tt.expectedWarnings) > 0, result.Degraded)
+			assert.Equal(t, tt.expectedDegradedSteps, result.DegradedSteps)
+			assert.Equal(t, tt.expectedWarnings, result.Warnings)
+			assert.Equal(t, tt.expectedReleased, result.ReleasedResources)
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
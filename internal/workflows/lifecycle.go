@@ -0,0 +1,237 @@
+package workflows
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+)
+
+// CheckInSignal お客様のチェックインを通知するシグナル名
+const CheckInSignal = "check-in"
+
+const (
+	// FreeCancellationPeriod チェックインの何時間前まで無料でキャンセルできるか
+	FreeCancellationPeriod = 48 * time.Hour
+	// CheckInReminderLead チェックインの何時間前にリマインダーを送信するか
+	CheckInReminderLead = 24 * time.Hour
+)
+
+// noShowReleasedSteps ノーショーの時に補償して解放するステップ
+// 客室はノーショーとして料金が発生するため確保したままとし、使われなかったディナー食材と駐車場を在庫に戻す
+var noShowReleasedSteps = []config.Step{config.StepDinner, config.StepParking}
+
+// LifecycleState 予約完了後の予約の状態
+type LifecycleState string
+
+const (
+	// LifecycleConfirmed 予約が確定し、チェックインを待っている（無料でキャンセルできる）
+	LifecycleConfirmed LifecycleState = "confirmed"
+	// LifecycleNonRefundable 無料キャンセル期限を過ぎ、チェックインを待っている（キャンセルには料金が発生する）
+	LifecycleNonRefundable LifecycleState = "non_refundable"
+	// LifecycleCheckedIn チェックイン済み
+	LifecycleCheckedIn LifecycleState = "checked_in"
+	// LifecycleCompleted チェックアウトにより予約が完了した
+	LifecycleCompleted LifecycleState = "completed"
+	// LifecycleNoShow チェックアウト時刻までにチェックインがなかった
+	LifecycleNoShow LifecycleState = "no_show"
+	// LifecycleCancelled お客様のキャンセルにより取り消された
+	LifecycleCancelled LifecycleState = "cancelled"
+)
+
+// awaitingCheckIn チェックインを待っている状態かどうか
+func (s LifecycleState) awaitingCheckIn() bool {
+	return s == LifecycleConfirmed || s == LifecycleNonRefundable
+}
+
+// CheckInRequest チェックインの通知内容
+type CheckInRequest struct {
+	ReceivedBy string `json:"received_by,omitempty"`
+}
+
+// lifecycleEvent 予約完了後の待機中に発生したイベント
+type lifecycleEvent int
+
+const (
+	lifecycleEventTimer lifecycleEvent = iota
+	lifecycleEventCancel
+	lifecycleEventCheckIn
+	lifecycleEventModified
+	lifecycleEventFreeCancellationExpired
+)
+
+// freeCancellationDeadline 無料キャンセルの期限
+func freeCancellationDeadline(checkIn time.Time) time.Time {
+	return checkIn.Add(-FreeCancellationPeriod)
+}
+
+// awaitLifecycleEvent 指定時刻まで、キャンセルと予約変更、チェックイン（チェックイン前のみ）を待つ
+// 無料キャンセル期限（ゼロ値の場合は待たない）が先に来た場合は、期限を過ぎたことを返す
+func awaitLifecycleEvent(ctx workflow.Context, until, deadline time.Time, cancellation *cancellationListener, modified, checkIn workflow.ReceiveChannel) lifecycleEvent {
+	if cancellation.requested() {
+		return lifecycleEventCancel
+	}
+
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+
+	event := lifecycleEventTimer
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(cancellation.ch, func(c workflow.ReceiveChannel, more bool) {
+		cancellation.receive(ctx, c)
+		event = lifecycleEventCancel
+	})
+	selector.AddReceive(modified, func(c workflow.ReceiveChannel, more bool) {
+		var revision int
+		c.Receive(ctx, &revision)
+		event = lifecycleEventModified
+	})
+	if checkIn != nil {
+		selector.AddReceive(checkIn, func(c workflow.ReceiveChannel, more bool) {
+			var request CheckInRequest
+			c.Receive(ctx, &request)
+			event = lifecycleEventCheckIn
+		})
+	}
+	if wait := deadline.Sub(workflow.Now(ctx)); !deadline.IsZero() && wait > 0 {
+		selector.AddFuture(workflow.NewTimer(timerCtx, wait), func(f workflow.Future) {
+			event = lifecycleEventFreeCancellationExpired
+		})
+	}
+	if wait := until.Sub(workflow.Now(ctx)); wait > 0 {
+		selector.AddFuture(workflow.NewTimer(timerCtx, wait), func(f workflow.Future) {})
+	} else {
+		// 既に時刻を過ぎている場合は受信済みのシグナルのみ処理する
+		selector.AddDefault(func() {})
+	}
+	selector.Select(ctx)
+
+	return event
+}
+
+// sendCheckInReminder チェックイン前のリマインダーを送信する（失敗しても予約は継続する）
+func sendCheckInReminder(ctx workflow.Context, request BookingRequest) bool {
+	reminderCtx := workflow.WithActivityOptions(ctx, config.GetActivityOptions(config.StepCheckInReminder))
+	reminder := activities.CheckInReminder{
+		BookingID: request.BookingID,
+		UserID:    request.UserID,
+		HotelID:   request.Hotel.HotelID,
+		CheckIn:   request.Hotel.CheckIn,
+	}
+	var result activities.NotificationResult
+	if err := workflow.ExecuteActivity(reminderCtx, activities.SendCheckInReminderActivity, reminder).Get(ctx, &result); err != nil {
+		workflow.GetLogger(ctx).Error("チェックインのリマインダー送信に失敗", "BookingID", request.BookingID, "Error", err.Error())
+		return false
+	}
+	return true
+}
+
+// holdUntilCheckOut 全ての予約が完了した後、チェックアウトまで予約を管理する
+//   - チェックイン前のキャンセルは完了済みの全ステップを補償する（無料キャンセル期限後は料金が発生する）
+//   - 無料キャンセル期限を過ぎた時点で、予約の状態をnon_refundableに変える
+//   - チェックインの前日にリマインダーを送信する
+//   - チェックアウト時刻までにチェックインがなければノーショーとして扱い、ディナー食材と駐車場を解放する
+//   - 予約変更で日程が変わった場合は、新しい日程でタイマーを設定し直す
+func holdUntilCheckOut(ctx workflow.Context, booking *confirmedBooking, cancellation *cancellationListener) {
+	logger := workflow.GetLogger(ctx)
+	checkInCh := workflow.GetSignalChannel(ctx, CheckInSignal)
+	result, status := booking.result, booking.status
+	reminderSent := false
+
+	result.Lifecycle = LifecycleConfirmed
+	status.enter(PhaseAwaitingCheckIn)
+	status.Lifecycle = result.Lifecycle
+	status.Success, status.Message = result.Success, result.Message
+
+	for result.Lifecycle.awaitingCheckIn() {
+		revision := booking.revision
+		hotel := booking.request.Hotel
+		deadline := freeCancellationDeadline(hotel.CheckIn)
+		result.FreeCancellationDeadline = deadline
+
+		// 無料キャンセル期限を過ぎていれば状態を変える（予約変更で期限が延びた場合は元に戻す）
+		lifecycle := LifecycleConfirmed
+		if !workflow.Now(ctx).Before(deadline) {
+			lifecycle = LifecycleNonRefundable
+		}
+		if lifecycle != result.Lifecycle {
+			logger.Info("無料キャンセル期限により予約の状態が変わりました", "BookingID", booking.request.BookingID,
+				"Deadline", deadline, "From", result.Lifecycle, "To", lifecycle)
+			result.Lifecycle = lifecycle
+			status.Lifecycle = lifecycle
+		}
+
+		reminderDue := !reminderSent && workflow.Now(ctx).Before(hotel.CheckIn)
+		next := hotel.CheckOut
+		if reminderDue {
+			next = hotel.CheckIn.Add(-CheckInReminderLead)
+		}
+
+		event := awaitLifecycleEvent(ctx, next, deadline, cancellation, booking.modified, checkInCh)
+		booking.awaitIdle(ctx)
+		if event == lifecycleEventTimer && booking.revision != revision {
+			// 予約変更中に変更前の日程のタイマーが発火した場合は新しい日程で待ち直す
+			continue
+		}
+		switch event {
+		case lifecycleEventModified, lifecycleEventFreeCancellationExpired:
+			continue
+		case lifecycleEventCancel:
+			result.CancellationFeeApplies = !workflow.Now(ctx).Before(deadline)
+			cancelBooking(ctx, booking.request.BookingID, booking.compensations, result, status, *cancellation.request)
+			return
+		case lifecycleEventCheckIn:
+			logger.Info("お客様がチェックインしました", "BookingID", booking.request.BookingID)
+			result.Lifecycle = LifecycleCheckedIn
+			result.CheckedInAt = workflow.Now(ctx)
+			status.enter(PhaseCheckedIn)
+		case lifecycleEventTimer:
+			if reminderDue {
+				reminderSent = true
+				result.ReminderSent = sendCheckInReminder(ctx, booking.request)
+				continue
+			}
+			logger.Warn("チェックアウト時刻までにチェックインがなかったためノーショーとして扱います", "BookingID", booking.request.BookingID)
+			result.Lifecycle = LifecycleNoShow
+			result.Message = "チェックインがなかったためノーショーとして処理しました"
+			releaseNoShow(ctx, booking)
+		}
+		status.Lifecycle = result.Lifecycle
+	}
+	if result.Lifecycle != LifecycleCheckedIn {
+		return
+	}
+
+	// チェックイン後はキャンセルを受け付けず、チェックアウトで完了とする
+	for awaitLifecycleEvent(ctx, booking.request.Hotel.CheckOut, time.Time{}, cancellation, booking.modified, nil) != lifecycleEventTimer {
+		if cancellation.request != nil {
+			logger.Warn("チェックイン済みのためキャンセル要求を受け付けません", "BookingID", booking.request.BookingID, "Reason", cancellation.request.Reason)
+			cancellation.request = nil
+		}
+	}
+	result.Lifecycle = LifecycleCompleted
+	result.Message = "チェックアウトにより予約が完了しました"
+}
+
+// releaseNoShow ノーショーの予約のディナー食材と駐車場を補償して解放する（客室は解放しない）
+// 補償に失敗した場合は通常の補償と同じく手動対応を待つ
+// 予約自体は取り消さないため、ロールバックの状態ではなく解放したリソースと補償のレポートのみを結果に記録する
+func releaseNoShow(ctx workflow.Context, booking *confirmedBooking) {
+	compensations := booking.compensations.forSteps(noShowReleasedSteps...)
+	if len(compensations) == 0 {
+		return
+	}
+	workflow.GetLogger(ctx).Info("ノーショーのためディナー食材と駐車場を解放します",
+		"BookingID", booking.request.BookingID, "Compensations", len(compensations))
+
+	result := booking.result
+	report := runCompensations(ctx, booking.request.BookingID, compensations, booking.status)
+	result.CompensationReport = &report
+	result.ManualActionRequired = len(report.Failed()) > 0
+	for _, o := range report.Outcomes {
+		if o.Succeeded() && o.ResourceID != "" {
+			result.ReleasedResources = append(result.ReleasedResources, o.ResourceID)
+		}
+	}
+}
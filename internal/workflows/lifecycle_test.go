@@ -0,0 +1,244 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"temporal-hotel-sample/internal/activities"
+)
+
+// testケース
+// 正常系:
+//   - チェックインした時、チェックインの前日にリマインダーを送信し、チェックアウトで予約が完了する
+//   - チェックイン後のキャンセル要求は受け付けず、チェックアウトで予約が完了する
+//   - リマインダーの送信に失敗しても、予約は継続して完了する
+//
+// 準異常系:
+//   - チェックアウト時刻までにチェックインがない時、ノーショーとして扱い客室は確保したままディナー食材と駐車場を補償する
+//   - 無料キャンセル期限前にキャンセルされた時、料金なしで全てのリソースを補償する
+//   - 無料キャンセル期限後にキャンセルされた時、キャンセル料ありで全てのリソースを補償する
+func TestHotelBookingSaga_Lifecycle(t *testing.T) {
+	// 予約はチェックインの7日前に行う
+	startTime := testCheckIn.AddDate(0, 0, -7)
+	untilBefore := func(at time.Time, d time.Duration) time.Duration { return at.Add(-d).Sub(startTime) }
+
+	tests := map[string]struct {
+		checkInAfter time.Duration // 0の場合はチェックインしない
+		cancelAfter  time.Duration // 0の場合はキャンセルしない
+		reminderErr  error
+
+		expectedReminderCalls int
+		expectedLifecycle     LifecycleState
+		expectedSuccess       bool
+		expectedMessage       string
+		expectedReminderSent  bool
+		expectedFee           bool
+		expectedReleased      []string
+		expectedEndAt         time.Time
+	}{
+		"正常系 - チェックインし、チェックアウトで予約が完了する": {
+			checkInAfter:          untilBefore(testCheckIn, -time.Hour),
+			expectedReminderCalls: 1,
+			expectedLifecycle:     LifecycleCompleted,
+			expectedSuccess:       true,
+			expectedMessage:       "チェックアウトにより予約が完了しました",
+			expectedReminderSent:  true,
+			expectedEndAt:         testCheckOut,
+		},
+		"正常系 - チェックイン後のキャンセル要求は受け付けない": {
+			checkInAfter:          untilBefore(testCheckIn, -time.Hour),
+			cancelAfter:           untilBefore(testCheckIn, -2*time.Hour),
+			expectedReminderCalls: 1,
+			expectedLifecycle:     LifecycleCompleted,
+			expectedSuccess:       true,
+			expectedMessage:       "チェックアウトにより予約が完了しました",
+			expectedReminderSent:  true,
+			expectedEndAt:         testCheckOut,
+		},
+		"正常系 - リマインダーの送信に失敗しても予約は継続する": {
+			checkInAfter:          untilBefore(testCheckIn, -time.Hour),
+			reminderErr:           activities.NewServerError("リマインダーの送信に失敗しました", "NOTIFICATION_ERROR"),
+			expectedReminderCalls: 5,
+			expectedLifecycle:     LifecycleCompleted,
+			expectedSuccess:       true,
+			expectedMessage:       "チェックアウトにより予約が完了しました",
+			expectedReminderSent:  false,
+			expectedEndAt:         testCheckOut,
+		},
+		"準異常系 - チェックインがないままチェックアウト時刻を過ぎる": {
+			expectedReminderCalls: 1,
+			expectedLifecycle:     LifecycleNoShow,
+			expectedSuccess:       true,
+			expectedMessage:       "チェックインがなかったためノーショーとして処理しました",
+			expectedReminderSent:  true,
+			expectedReleased:      []string{"parking-001", "food-001"},
+			expectedEndAt:         testCheckOut,
+		},
+		"準異常系 - 無料キャンセル期限前にキャンセルされる": {
+			cancelAfter:       untilBefore(testCheckIn, FreeCancellationPeriod+time.Hour),
+			expectedLifecycle: LifecycleCancelled,
+			expectedMessage:   "お客様のキャンセルにより予約を取り消しました",
+			expectedFee:       false,
+			expectedReleased:  []string{"parking-001", "food-001", "room-001"},
+			expectedEndAt:     testCheckIn.Add(-FreeCancellationPeriod - time.Hour),
+		},
+		"準異常系 - 無料キャンセル期限後にキャンセルされる": {
+			cancelAfter:           untilBefore(testCheckIn, CheckInReminderLead-time.Hour),
+			expectedReminderCalls: 1,
+			expectedLifecycle:     LifecycleCancelled,
+			expectedMessage:       "お客様のキャンセルにより予約を取り消しました",
+			expectedReminderSent:  true,
+			expectedFee:           true,
+			expectedReleased:      []string{"parking-001", "food-001", "room-001"},
+			expectedEndAt:         testCheckIn.Add(-CheckInReminderLead + time.Hour),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.SetStartTime(startTime)
+
+			request := BookingRequest{
+				BookingID: "booking-lifecycle-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}, nil).Once()
+			testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}, nil).Once()
+
+			if tt.expectedReminderCalls > 0 {
+				reminder := activities.CheckInReminder{
+					BookingID: request.BookingID,
+					UserID:    request.UserID,
+					HotelID:   request.Hotel.HotelID,
+					CheckIn:   testCheckIn,
+				}
+				reminderResult := &activities.NotificationResult{Success: true}
+				if tt.reminderErr != nil {
+					reminderResult = nil
+				}
+				testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, reminder).Return(
+					reminderResult, tt.reminderErr).Times(tt.expectedReminderCalls)
+			}
+			for _, released := range tt.expectedReleased {
+				compensation := map[string]interface{}{
+					"room-001":    activities.CompensateHotelRoomActivity,
+					"food-001":    activities.CompensateDinnerFoodActivity,
+					"parking-001": activities.CompensateParkingActivity,
+				}[released]
+				testEnv.OnActivity(compensation, mock.Anything, request.BookingID, released).Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+
+			if tt.checkInAfter > 0 {
+				testEnv.RegisterDelayedCallback(func() {
+					testEnv.SignalWorkflow(CheckInSignal, CheckInRequest{ReceivedBy: "front-001"})
+				}, tt.checkInAfter)
+			}
+			if tt.cancelAfter > 0 {
+				testEnv.RegisterDelayedCallback(func() {
+					testEnv.SignalWorkflow(CancelBookingSignal, CancellationRequest{Reason: "予定変更"})
+				}, tt.cancelAfter)
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.Equal(t, tt.expectedLifecycle, result.Lifecycle)
+			assert.Equal(t, tt.expectedSuccess, result.Success)
+			assert.Equal(t, tt.expectedMessage, result.Message)
+			assert.Equal(t, tt.expectedReminderSent, result.ReminderSent)
+			assert.Equal(t, tt.expectedFee, result.CancellationFeeApplies)
+			assert.Equal(t, tt.expectedReleased, result.ReleasedResources)
+			assert.True(t, testCheckIn.Add(-FreeCancellationPeriod).Equal(result.FreeCancellationDeadline))
+			assert.True(t, tt.expectedEndAt.Equal(testEnv.Now()), "終了時刻: %s", testEnv.Now())
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
+
+// testケース
+// 正常系:
+//   - 無料キャンセル期限の前は confirmed、期限を過ぎると non_refundable として予約状況に反映される
+//   - 無料キャンセル期限を過ぎてから予約した時、予約の確定時点で non_refundable になる
+func TestHotelBookingSaga_FreeCancellationDeadline(t *testing.T) {
+	deadline := testCheckIn.Add(-FreeCancellationPeriod)
+
+	tests := map[string]struct {
+		startTime time.Time
+		queryAt   []time.Time
+
+		expectedLifecycles []LifecycleState
+	}{
+		"正常系 - 無料キャンセル期限を過ぎると non_refundable になる": {
+			startTime:          testCheckIn.AddDate(0, 0, -7),
+			queryAt:            []time.Time{deadline.Add(-time.Hour), deadline.Add(time.Hour)},
+			expectedLifecycles: []LifecycleState{LifecycleConfirmed, LifecycleNonRefundable},
+		},
+		"正常系 - 無料キャンセル期限後の予約は確定時点で non_refundable になる": {
+			startTime:          deadline.Add(time.Hour),
+			queryAt:            []time.Time{deadline.Add(2 * time.Hour)},
+			expectedLifecycles: []LifecycleState{LifecycleNonRefundable},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.SetStartTime(tt.startTime)
+
+			request := BookingRequest{
+				BookingID: "booking-deadline-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, mock.Anything).Return(
+				&activities.NotificationResult{Success: true}, nil).Once()
+
+			var lifecycles []LifecycleState
+			for _, at := range tt.queryAt {
+				testEnv.RegisterDelayedCallback(func() {
+					value, err := testEnv.QueryWorkflow(BookingStatusQuery)
+					if assert.NoError(t, err) {
+						var status BookingStatus
+						assert.NoError(t, value.Get(&status))
+						lifecycles = append(lifecycles, status.Lifecycle)
+					}
+				}, at.Sub(tt.startTime))
+			}
+			testEnv.RegisterDelayedCallback(func() {
+				testEnv.SignalWorkflow(CheckInSignal, CheckInRequest{ReceivedBy: "front-001"})
+			}, testCheckIn.Add(time.Hour).Sub(tt.startTime))
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.Equal(t, LifecycleCompleted, result.Lifecycle)
+			assert.Equal(t, tt.expectedLifecycles, lifecycles)
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package workflows
+
+import (
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/config"
+)
+
+// 予約Sagaのメトリクス名
+// ワークフローのメトリクスハンドラーはリプレイ中の記録を無視するため、ワークフローのコードから直接記録してよい
+const (
+	MetricBookingStarted      = "hotel_booking_started_total"        // 開始した予約（execution）
+	MetricBookingSucceeded    = "hotel_booking_succeeded_total"      // 確定した予約（degraded: 必須ではないステップが失敗したか）
+	MetricBookingFailed       = "hotel_booking_failed_total"         // 確定できなかった予約（failed_step）
+	MetricStepLatency         = "hotel_booking_step_latency_seconds" // ステップの所要時間（リトライを含む。step, outcome。キャンセルしたステップはcancelled）
+	MetricCompensationOutcome = "hotel_booking_compensations_total"  // 補償処理の結果（resource_type, outcome）
+)
+
+// failed_stepタグの値（ステップの失敗以外の理由で予約を確定できなかった場合）
+const (
+	failedStepValidation = "validation" // リクエストのバリデーションで失敗した
+	failedStepCancelled  = "cancelled"  // 予約の確定前にお客様がキャンセルした
+	failedStepUnknown    = "unknown"    // 失敗したステップを特定できない（ステップの定義が不正など）
+)
+
+// outcomeタグの値
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeCancelled = "cancelled"
+)
+
+// recordBookingStarted 予約の開始を記録する
+func recordBookingStarted(ctx workflow.Context, request BookingRequest) {
+	execution := request.Execution
+	if execution == "" {
+		execution = ExecutionSequential
+	}
+	workflow.GetMetricsHandler(ctx).WithTags(map[string]string{"execution": string(execution)}).
+		Counter(MetricBookingStarted).Inc(1)
+}
+
+// recordBookingSucceeded 予約の確定を記録する
+func recordBookingSucceeded(ctx workflow.Context, result *BookingResult) {
+	workflow.GetMetricsHandler(ctx).WithTags(map[string]string{"degraded": strconv.FormatBool(result.Degraded)}).
+		Counter(MetricBookingSucceeded).Inc(1)
+}
+
+// recordBookingFailed 予約を確定できなかったことを、失敗したステップごとに記録する
+func recordBookingFailed(ctx workflow.Context, result *BookingResult) {
+	failedStep := string(result.FailedStep)
+	switch {
+	case result.CancelledByCustomer:
+		failedStep = failedStepCancelled
+	case failedStep == "":
+		failedStep = failedStepUnknown
+	}
+	recordFailedStep(ctx, failedStep)
+}
+
+// recordFailedStep 予約を確定できなかったことを記録する
+func recordFailedStep(ctx workflow.Context, failedStep string) {
+	workflow.GetMetricsHandler(ctx).WithTags(map[string]string{"failed_step": failedStep}).
+		Counter(MetricBookingFailed).Inc(1)
+}
+
+// recordStepLatency ステップの所要時間を記録する
+func recordStepLatency(ctx workflow.Context, step config.Step, startedAt time.Time, err error) {
+	outcome := outcomeSucceeded
+	switch {
+	case temporal.IsCanceledError(err):
+		outcome = outcomeCancelled
+	case err != nil:
+		outcome = outcomeFailed
+	}
+	workflow.GetMetricsHandler(ctx).WithTags(map[string]string{"step": string(step), "outcome": outcome}).
+		Timer(MetricStepLatency).Record(workflow.Now(ctx).Sub(startedAt))
+}
+
+// recordCompensation 補償処理の結果をリソースの種類ごとに記録する
+func recordCompensation(ctx workflow.Context, step CompensationStep, outcome CompensationOutcome) {
+	result := outcomeSucceeded
+	if !outcome.Succeeded() {
+		result = outcomeFailed
+	}
+	resourceType := string(step.Resource)
+	if resourceType == "" {
+		resourceType = "unknown"
+	}
+	workflow.GetMetricsHandler(ctx).WithTags(map[string]string{"resource_type": resourceType, "outcome": result}).
+		Counter(MetricCompensationOutcome).Inc(1)
+}
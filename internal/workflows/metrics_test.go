@@ -0,0 +1,170 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/sdk/testsuite"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/metrics"
+	"temporal-hotel-sample/internal/metrics/metricstest"
+)
+
+// testケース
+// 正常系:
+//   - 全てのステップが成功した時、予約の開始と確定、各ステップの所要時間が記録される
+//   - 必須ではないディナーが失敗した時、degradedとして予約の確定が記録される
+//
+// 異常系:
+//   - ホテルルーム予約が失敗した時、失敗したステップとしてhotelが記録される
+//   - 駐車場予約が失敗した時、ホテルとディナーの補償の成功が記録される
+//   - 並行実行中に駐車場予約が失敗した時、キャンセルしたディナーの所要時間がcancelledとして記録される
+//   - ディナーの補償が失敗した時、ディナーの補償の失敗が記録される
+//   - バリデーションに失敗した時、失敗したステップとしてvalidationが記録される
+func TestHotelBookingSaga_Metrics(t *testing.T) {
+	tests := map[string]struct {
+		request         func(r BookingRequest) BookingRequest
+		dinnerDelay     time.Duration
+		hotelErr        error
+		dinnerErr       error
+		parkingErr      error
+		compensationErr error
+
+		expectedCounters  []expectedMetric  // 値が1となるカウンター
+		expectedLatencies map[string]string // step → outcome
+	}{
+		"正常系 - 全てのステップが成功": {
+			expectedCounters: []expectedMetric{
+				{MetricBookingStarted, map[string]string{"execution": "sequential"}},
+				{MetricBookingSucceeded, map[string]string{"degraded": "false"}},
+			},
+			expectedLatencies: map[string]string{"hotel": "succeeded", "dinner": "succeeded", "parking": "succeeded"},
+		},
+		"正常系 - 必須ではないディナーが失敗": {
+			request: func(r BookingRequest) BookingRequest {
+				r.Dinner.BestEffort = true
+				return r
+			},
+			dinnerErr: activities.NewBusinessError("指定されたメニューの食材が在庫不足です", activities.CodeOutOfStock),
+			expectedCounters: []expectedMetric{
+				{MetricBookingSucceeded, map[string]string{"degraded": "true"}},
+			},
+			expectedLatencies: map[string]string{"hotel": "succeeded", "dinner": "failed", "parking": "succeeded"},
+		},
+		"異常系 - ホテルルーム予約が失敗": {
+			hotelErr: activities.NewBusinessError("指定されたホテルは満室です", activities.CodeRoomFull),
+			expectedCounters: []expectedMetric{
+				{MetricBookingStarted, map[string]string{"execution": "sequential"}},
+				{MetricBookingFailed, map[string]string{"failed_step": "hotel"}},
+			},
+			expectedLatencies: map[string]string{"hotel": "failed"},
+		},
+		"異常系 - 駐車場予約が失敗": {
+			parkingErr: activities.NewBusinessError("空いている駐車スペースがありません", activities.CodeParkingFull),
+			expectedCounters: []expectedMetric{
+				{MetricBookingFailed, map[string]string{"failed_step": "parking"}},
+				{MetricCompensationOutcome, map[string]string{"resource_type": "hotel", "outcome": "succeeded"}},
+				{MetricCompensationOutcome, map[string]string{"resource_type": "dinner", "outcome": "succeeded"}},
+			},
+			expectedLatencies: map[string]string{"hotel": "succeeded", "dinner": "succeeded", "parking": "failed"},
+		},
+		"異常系 - 並行実行中に駐車場予約が失敗し、ディナーをキャンセル": {
+			request: func(r BookingRequest) BookingRequest {
+				r.Execution = ExecutionParallel
+				return r
+			},
+			dinnerDelay: time.Hour,
+			parkingErr:  activities.NewBusinessError("空いている駐車スペースがありません", activities.CodeParkingFull),
+			expectedCounters: []expectedMetric{
+				{MetricBookingStarted, map[string]string{"execution": "parallel"}},
+				{MetricBookingFailed, map[string]string{"failed_step": "parking"}},
+			},
+			expectedLatencies: map[string]string{"hotel": "succeeded", "dinner": "cancelled", "parking": "failed"},
+		},
+		"異常系 - ディナーの補償が失敗": {
+			parkingErr:      activities.NewBusinessError("空いている駐車スペースがありません", activities.CodeParkingFull),
+			compensationErr: activities.NewBusinessError("補償できない予約です", "NOT_COMPENSABLE"),
+			expectedCounters: []expectedMetric{
+				{MetricCompensationOutcome, map[string]string{"resource_type": "dinner", "outcome": "failed"}},
+			},
+		},
+		"異常系 - バリデーションに失敗": {
+			request: func(r BookingRequest) BookingRequest {
+				r.UserID = ""
+				return r
+			},
+			expectedCounters: []expectedMetric{
+				{MetricBookingStarted, map[string]string{"execution": "sequential"}},
+				{MetricBookingFailed, map[string]string{"failed_step": "validation"}},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			scope := tally.NewTestScope("", nil)
+			testSuite := &testsuite.WorkflowTestSuite{}
+			testSuite.SetMetricsHandler(metrics.NewHandler(scope))
+			testEnv := testSuite.NewTestWorkflowEnvironment()
+			testEnv.RegisterActivity(activities.HotelRoomBookingActivity)
+			testEnv.RegisterActivity(activities.DinnerFoodBookingActivity)
+			testEnv.RegisterActivity(activities.ParkingBookingActivity)
+			testEnv.RegisterActivity(activities.CompensateHotelRoomActivity)
+			testEnv.RegisterActivity(activities.CompensateDinnerFoodActivity)
+			testEnv.RegisterActivity(activities.RecordStuckCompensationActivity)
+			testEnv.RegisterActivity(activities.UpdateStuckCompensationActivity)
+			testEnv.RegisterActivity(activities.SendCheckInReminderActivity)
+
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-201"}, activities.ToApplicationError(tt.hotelErr)).Maybe()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-201"}, activities.ToApplicationError(tt.dinnerErr)).After(tt.dinnerDelay).Maybe()
+			testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.ParkingBookingResult{Success: true, ResourceID: "parking-201"}, activities.ToApplicationError(tt.parkingErr)).Maybe()
+			testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, nil).Maybe()
+			testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, mock.Anything, mock.Anything).Return(
+				&activities.CompensationResult{Success: true}, activities.ToApplicationError(tt.compensationErr)).Maybe()
+			testEnv.OnActivity(activities.RecordStuckCompensationActivity, mock.Anything, mock.Anything).Return(nil).Maybe()
+			testEnv.OnActivity(activities.UpdateStuckCompensationActivity, mock.Anything, mock.Anything).Return(nil).Maybe()
+			testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, mock.Anything).Return(
+				&activities.NotificationResult{Success: true}, nil).Maybe()
+
+			request := BookingRequest{
+				BookingID: "booking-metrics-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			}
+			if tt.request != nil {
+				request = tt.request(request)
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			if !testEnv.IsWorkflowCompleted() {
+				t.Fatal("ワークフローが完了していません")
+			}
+			assert.NoError(t, testEnv.GetWorkflowError())
+			for _, expected := range tt.expectedCounters {
+				assert.Equal(t, 1.0, metricstest.Value(scope, expected.name, expected.labels), expected.name, expected.labels)
+			}
+			for step, outcome := range tt.expectedLatencies {
+				assert.Equal(t, 1.0, metricstest.Value(scope, MetricStepLatency, map[string]string{"step": step, "outcome": outcome}), step)
+			}
+		})
+	}
+}
+
+// expectedMetric 記録されることを期待するメトリクス
+type expectedMetric struct {
+	name   string
+	labels map[string]string
+}
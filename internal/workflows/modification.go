@@ -0,0 +1,205 @@
+package workflows
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+)
+
+// ModifyBookingUpdate 予約完了後の予約変更のアップデート名
+const ModifyBookingUpdate = "modify-booking"
+
+// 予約変更のアップデートが返すApplicationErrorのエラー種別
+const (
+	ModificationConflictErrorType = "ModificationConflict" // 予約の状態により変更を受け付けられない（確定前・変更中）
+	InvalidModificationErrorType  = "InvalidModification"  // 変更内容が不正
+	ModificationFailedErrorType   = "ModificationFailed"   // 予約し直しに失敗し、元の予約を維持した
+)
+
+// ModificationRequest 予約変更の要求内容
+// 指定したサブリクエストのリソースだけを取り直す（未指定のものは現在の予約を維持する）
+// ディナーや駐車場を予約していなかった場合は、指定すると新たに予約する
+type ModificationRequest struct {
+	Hotel   *HotelRequest   `json:"hotel,omitempty"`
+	Dinner  *DinnerRequest  `json:"dinner,omitempty"`
+	Parking *ParkingRequest `json:"parking,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+}
+
+// apply 変更内容を反映した予約リクエストを返す
+func (m ModificationRequest) apply(current BookingRequest) BookingRequest {
+	modified := current
+	if m.Hotel != nil {
+		modified.Hotel = *m.Hotel
+	}
+	if m.Dinner != nil {
+		modified.Dinner = m.Dinner
+	}
+	if m.Parking != nil {
+		modified.Parking = m.Parking
+	}
+	return modified
+}
+
+// confirmedBooking 確定した予約の現在の内容
+// 予約変更のアップデートとチェックアウトまでの管理で共有する
+type confirmedBooking struct {
+	request       BookingRequest
+	pipeline      SagaPipeline // 予約変更で予約し直すステップの構成
+	revision      int          // 予約変更の版（失敗した変更も版を消費する）
+	compensations Compensations
+	result        *BookingResult
+	status        *BookingStatus
+
+	confirmed bool
+	modifying bool
+	modified  workflow.Channel // 日程の変更を待機中の処理に知らせる
+}
+
+// newConfirmedBooking 予約変更のアップデートハンドラーを登録する
+// 予約が確定するまでは変更を受け付けない
+func newConfirmedBooking(ctx workflow.Context, status *BookingStatus) *confirmedBooking {
+	booking := &confirmedBooking{
+		status:   status,
+		modified: workflow.NewBufferedChannel(ctx, 1),
+	}
+	err := workflow.SetUpdateHandlerWithOptions(ctx, ModifyBookingUpdate, booking.modify, workflow.UpdateHandlerOptions{
+		Validator: booking.validateModification,
+	})
+	if err != nil {
+		workflow.GetLogger(ctx).Error("予約変更アップデートハンドラーの登録に失敗", "Error", err)
+	}
+	return booking
+}
+
+// confirm 全ての予約が完了した内容で予約を確定する
+func (b *confirmedBooking) confirm(request BookingRequest, pipeline SagaPipeline, compensations Compensations, result *BookingResult) {
+	b.request = request
+	b.pipeline = pipeline
+	b.compensations = compensations
+	b.result = result
+	b.confirmed = true
+}
+
+// awaitIdle 実行中の予約変更の完了を待つ
+func (b *confirmedBooking) awaitIdle(ctx workflow.Context) {
+	_ = workflow.Await(ctx, func() bool { return !b.modifying })
+}
+
+// validateModification 予約変更の要求を検証する（拒否された要求は履歴に残らない）
+func (b *confirmedBooking) validateModification(ctx workflow.Context, modification ModificationRequest) error {
+	if !b.confirmed || b.status.Phase != PhaseAwaitingCheckIn {
+		return temporal.NewApplicationError("booking can only be modified while awaiting check-in", ModificationConflictErrorType)
+	}
+	if b.modifying {
+		return temporal.NewApplicationError("another modification is in progress", ModificationConflictErrorType)
+	}
+	if modification.Hotel == nil && modification.Dinner == nil && modification.Parking == nil {
+		return temporal.NewApplicationError("modification must change at least one of Hotel, Dinner or Parking", InvalidModificationErrorType)
+	}
+	modified := modification.apply(b.request)
+	if err := modified.Validate(); err != nil {
+		return temporal.NewApplicationError(err.Error(), InvalidModificationErrorType)
+	}
+	return nil
+}
+
+// modify 変更対象のリソースだけを新しい版で予約し直すミニSaga
+// 全て成功した場合は元のリソースを解放して入れ替え、途中で失敗した場合は新しく確保したリソースを補償して元の予約を維持する
+// 新しい版は同じ予約のリソースと重なっても確保できるため、日程をずらすだけの変更は空きがなくても成功する
+// 補償処理が失敗した場合は手動対応待ちとして記録し、解決されるまで変更の完了を待たせる
+func (b *confirmedBooking) modify(ctx workflow.Context, modification ModificationRequest) (*BookingResult, error) {
+	logger := workflow.GetLogger(ctx)
+	b.modifying = true
+	defer func() { b.modifying = false }()
+	b.status.enter(PhaseModifying)
+	defer b.status.enter(PhaseAwaitingCheckIn)
+
+	b.revision++
+	revision := b.revision
+	request := modification.apply(b.request)
+	logger.Info("予約変更を開始", "BookingID", request.BookingID, "Revision", revision, "Reason", modification.Reason)
+
+	var added, replaced Compensations
+	rollback := func(stepName string, err error) error {
+		logger.Error("予約変更に失敗したため新しく確保したリソースを補償", "Step", stepName, "Error", err.Error())
+		report := b.compensate(ctx, request.BookingID, added)
+		if failed := report.Failed(); len(failed) > 0 {
+			logger.Error("予約変更のロールバックに失敗", "BookingID", request.BookingID, "Failed", len(failed))
+			return temporal.NewApplicationError(fmt.Sprintf("%sに失敗: %s（ロールバックが一部失敗しました。手動対応が必要です）", stepName, failureReason(err)), ModificationFailedErrorType)
+		}
+		return temporal.NewApplicationError(fmt.Sprintf("%sに失敗: %s", stepName, failureReason(err)), ModificationFailedErrorType)
+	}
+
+	// 変更対象のステップだけを新しい版で予約し直す
+	type completedStep struct {
+		step   SagaStep
+		result activities.ReservationResult
+	}
+	var completed []completedStep
+	for _, step := range b.pipeline {
+		if !step.modifiedBy(modification) {
+			continue
+		}
+		stepResult, _, err := step.execute(ctx, step.Request(request, revision))
+		if err != nil {
+			return nil, rollback(step.Name, err)
+		}
+		if compensation, ok := step.compensation(request.BookingID, revision, stepResult.GetResourceID()); ok {
+			added.AddCompensation(compensation)
+		}
+		if old, ok := b.compensations.find(step.Compensation.Name); ok {
+			replaced.AddCompensation(old)
+		}
+		completed = append(completed, completedStep{step: step, result: stepResult})
+	}
+
+	// 元のリソースを解放する（失敗した場合は新しい予約を維持したまま手動対応とする）
+	message := "予約を変更しました"
+	report := b.compensate(ctx, request.BookingID, replaced)
+	if failed := report.Failed(); len(failed) > 0 {
+		logger.Error("変更前のリソースの解放に失敗", "BookingID", request.BookingID, "Failed", len(failed))
+		b.result.ManualActionRequired = true
+		message = fmt.Sprintf("%s（変更前のリソースの解放に失敗しました。手動対応が必要です）", message)
+	}
+
+	// 補償処理と結果を新しいリソースに入れ替える（変更前に予約していなかったものは追加する）
+	for _, step := range added {
+		if !b.compensations.replace(step) {
+			b.compensations.AddCompensation(step)
+		}
+	}
+	for _, c := range completed {
+		c.step.collect(b.result, b.status, c.result)
+	}
+	b.status.Compensations = make([]string, 0, len(b.compensations))
+	for _, step := range b.compensations {
+		b.status.Compensations = append(b.status.Compensations, step.String())
+	}
+	b.request = request
+	b.result.Revision = revision
+	b.result.Message = message
+	b.status.Message = b.result.Message
+
+	// チェックアウトまでの待機を新しい日程でやり直す
+	b.modified.SendAsync(revision)
+
+	logger.Info("予約変更が完了", "BookingID", request.BookingID, "Revision", revision)
+	modified := *b.result
+	return &modified, nil
+}
+
+// compensate 予約変更で不要になったリソースを補償する
+// 失敗した補償処理は手動対応待ちとして記録し、解決されるかタイムアウトするまで待つ
+func (b *confirmedBooking) compensate(ctx workflow.Context, bookingID string, compensations Compensations) CompensationReport {
+	report := compensations.Compensate(ctx, false)
+	if len(report.Failed()) == 0 {
+		return report
+	}
+	b.status.enter(PhaseAwaitingManualResolution)
+	report = awaitManualResolution(ctx, bookingID, compensations, report)
+	b.status.enter(PhaseModifying)
+	return report
+}
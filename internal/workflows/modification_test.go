@@ -0,0 +1,327 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"temporal-hotel-sample/internal/activities"
+)
+
+// updateRecorder アップデートの受付結果と実行結果を記録する
+type updateRecorder struct {
+	accepted bool
+	rejected error
+	result   interface{}
+	err      error
+}
+
+func (r *updateRecorder) Accept()          { r.accepted = true }
+func (r *updateRecorder) Reject(err error) { r.rejected = err }
+func (r *updateRecorder) Complete(success interface{}, err error) {
+	r.result, r.err = success, err
+}
+
+// testケース
+// 正常系:
+//   - 人数を変更した時、ディナーだけを新しい版で予約し直し、元の食材を解放する。その後のキャンセルでは新しい食材が補償される
+//   - 日程を変更した時、全てのリソースを予約し直し、チェックアウトまでの待機が新しい日程に従う（ノーショーで新しいディナーと駐車場を解放する）
+//
+// 準異常系:
+//   - 駐車場の予約し直しに失敗した時、新しく確保したホテルとディナーを補償して元の予約を維持する
+//
+// 異常系:
+//   - 宿泊期間外のディナーに変更しようとした時、アップデートが拒否される
+func TestHotelBookingSaga_ModifyBooking(t *testing.T) {
+	startTime := testCheckIn.AddDate(0, 0, -7)
+	newCheckIn := testCheckIn.AddDate(0, 0, 7)
+	newCheckOut := testCheckOut.AddDate(0, 0, 7)
+	newHotel := HotelRequest{HotelID: "hotel-001", CheckIn: newCheckIn, CheckOut: newCheckOut}
+	newParking := ParkingRequest{SpaceType: "standard", StartTime: testParkingStart.AddDate(0, 0, 7), EndTime: testParkingEnd.AddDate(0, 0, 7)}
+	newDinner := DinnerRequest{MenuType: "standard", DateTime: testDinnerTime.AddDate(0, 0, 7), Guests: 2}
+
+	tests := map[string]struct {
+		modification ModificationRequest
+		parkingErr   error
+		cancelAfter  time.Duration // 0の場合はキャンセルしない
+
+		expectedRejected      string
+		expectedErr           string
+		expectedRebooked      []string          // 新しい版で予約し直されるリソース
+		expectedReleased      map[string]string // 補償されるリソースIDと予約キー
+		expectedResult        func(t *testing.T, result BookingResult)
+		expectedEndAt         time.Time
+		expectedFinalReleased []string
+	}{
+		"正常系 - 人数を変更し、その後キャンセルする": {
+			modification:     ModificationRequest{Dinner: &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 4}},
+			cancelAfter:      24 * time.Hour,
+			expectedRebooked: []string{"dinner"},
+			expectedReleased: map[string]string{
+				"food-001":    "booking-modify-001",
+				"room-001":    "booking-modify-001",
+				"parking-001": "booking-modify-001",
+				"food-002":    activities.ReservationKey("booking-modify-001", 1),
+			},
+			expectedResult: func(t *testing.T, result BookingResult) {
+				assert.Equal(t, "food-002", result.DinnerResult.ResourceID)
+				assert.Equal(t, 1, result.Revision)
+			},
+			expectedEndAt:         startTime.Add(24 * time.Hour),
+			expectedFinalReleased: []string{"parking-001", "food-002", "room-001"},
+		},
+		"正常系 - 日程を変更し、新しい日程でノーショーになる": {
+			modification:     ModificationRequest{Hotel: &newHotel, Dinner: &newDinner, Parking: &newParking},
+			expectedRebooked: []string{"hotel", "dinner", "parking"},
+			expectedReleased: map[string]string{
+				"room-001":    "booking-modify-001",
+				"food-001":    "booking-modify-001",
+				"parking-001": "booking-modify-001",
+				"food-002":    activities.ReservationKey("booking-modify-001", 1),
+				"parking-002": activities.ReservationKey("booking-modify-001", 1),
+			},
+			expectedResult: func(t *testing.T, result BookingResult) {
+				assert.Equal(t, "room-002", result.HotelResult.ResourceID)
+				assert.Equal(t, "food-002", result.DinnerResult.ResourceID)
+				assert.Equal(t, "parking-002", result.ParkingResult.ResourceID)
+			},
+			expectedEndAt:         newCheckOut,
+			expectedFinalReleased: []string{"parking-002", "food-002"},
+		},
+		"準異常系 - 駐車場の予約し直しに失敗し、元の予約を維持する": {
+			modification:     ModificationRequest{Hotel: &newHotel, Dinner: &newDinner, Parking: &newParking},
+			parkingErr:       activities.NewBusinessError("指定された駐車場は満車です", activities.CodeParkingFull),
+			cancelAfter:      24 * time.Hour,
+			expectedErr:      "駐車場予約に失敗: 指定された時間帯に空いている駐車スペースがありません",
+			expectedRebooked: []string{"hotel", "dinner", "parking"},
+			expectedReleased: map[string]string{
+				"food-002":    activities.ReservationKey("booking-modify-001", 1),
+				"room-002":    activities.ReservationKey("booking-modify-001", 1),
+				"room-001":    "booking-modify-001",
+				"food-001":    "booking-modify-001",
+				"parking-001": "booking-modify-001",
+			},
+			expectedEndAt:         startTime.Add(24 * time.Hour),
+			expectedFinalReleased: []string{"parking-001", "food-001", "room-001"},
+		},
+		"異常系 - 宿泊期間外のディナーに変更しようとする": {
+			modification:     ModificationRequest{Dinner: &newDinner},
+			expectedRejected: "Dinner.DateTime must be within the stay",
+			expectedReleased: map[string]string{
+				"food-001":    "booking-modify-001",
+				"parking-001": "booking-modify-001",
+			},
+			expectedEndAt:         testCheckOut,
+			expectedFinalReleased: []string{"parking-001", "food-001"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.SetStartTime(startTime)
+
+			request := BookingRequest{
+				BookingID: "booking-modify-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			}
+			modified := tt.modification.apply(request)
+
+			// 初回の予約
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(request, 0)).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, dinnerBookingRequest(request, 0)).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}, nil).Once()
+			testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, parkingBookingRequest(request, 0)).Return(
+				&activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}, nil).Once()
+			testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, mock.Anything).Return(
+				&activities.NotificationResult{Success: true}, nil).Maybe()
+
+			// 予約変更（版1）
+			for _, step := range tt.expectedRebooked {
+				switch step {
+				case "hotel":
+					testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(modified, 1)).Return(
+						&activities.HotelBookingResult{Success: true, ResourceID: "room-002"}, nil).Once()
+				case "dinner":
+					testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, dinnerBookingRequest(modified, 1)).Return(
+						&activities.DinnerBookingResult{Success: true, ResourceID: "food-002"}, nil).Once()
+				case "parking":
+					if tt.parkingErr != nil {
+						testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, parkingBookingRequest(modified, 1)).Return(
+							nil, activities.ToApplicationError(tt.parkingErr)).Once()
+					} else {
+						testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, parkingBookingRequest(modified, 1)).Return(
+							&activities.ParkingBookingResult{Success: true, ResourceID: "parking-002"}, nil).Once()
+					}
+				}
+			}
+			for resourceID, key := range tt.expectedReleased {
+				compensation := map[string]interface{}{
+					"room": activities.CompensateHotelRoomActivity,
+					"food": activities.CompensateDinnerFoodActivity,
+					"park": activities.CompensateParkingActivity,
+				}[resourceID[:4]]
+				testEnv.OnActivity(compensation, mock.Anything, key, resourceID).Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+
+			recorder := &updateRecorder{}
+			testEnv.RegisterDelayedCallback(func() {
+				testEnv.UpdateWorkflow(ModifyBookingUpdate, "modify-1", recorder, tt.modification)
+			}, time.Hour)
+			if tt.cancelAfter > 0 {
+				testEnv.RegisterDelayedCallback(func() {
+					testEnv.SignalWorkflow(CancelBookingSignal, CancellationRequest{Reason: "予定変更"})
+				}, tt.cancelAfter)
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			if tt.expectedRejected != "" {
+				assert.False(t, recorder.accepted)
+				assert.ErrorContains(t, recorder.rejected, tt.expectedRejected)
+			} else {
+				assert.True(t, recorder.accepted)
+			}
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, recorder.err, tt.expectedErr)
+			}
+			if tt.expectedResult != nil {
+				assert.NoError(t, recorder.err)
+				modifiedResult, ok := recorder.result.(*BookingResult)
+				if assert.True(t, ok, "アップデートの結果: %T", recorder.result) {
+					tt.expectedResult(t, *modifiedResult)
+				}
+			}
+			assert.Equal(t, tt.expectedFinalReleased, result.ReleasedResources)
+			assert.True(t, tt.expectedEndAt.Equal(testEnv.Now()), "終了時刻: %s", testEnv.Now())
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
+
+// テストケースについて
+// 正常系:
+//   - 変更前のリソースの解放に失敗した時、手動対応待ちとして記録され、オペレーターの再実行指示で解放されて予約変更が完了する
+//   - 変更前のリソースの解放に失敗した時、オペレーターが手動で解決済みとすると予約変更が完了する
+func TestHotelBookingSaga_ModifyBooking_ReleaseFailure(t *testing.T) {
+	tests := map[string]struct {
+		resolution CompensationResolution
+
+		expectedRecordedStatus activities.StuckCompensationStatus
+	}{
+		"正常系 - オペレーターの再実行指示で変更前の食材が解放される": {
+			resolution:             CompensationResolution{Step: "ディナー食材補償", ResourceID: "food-001", Action: CompensationActionRetry},
+			expectedRecordedStatus: activities.StuckCompensationRetried,
+		},
+		"正常系 - オペレーターが手動で解決済みとする": {
+			resolution:             CompensationResolution{Step: "ディナー食材補償", ResourceID: "food-001", Action: CompensationActionMarkResolved, Note: "台帳を手動で修正済み"},
+			expectedRecordedStatus: activities.StuckCompensationManuallyResolved,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			sink := activities.NewInMemoryStuckCompensationSink()
+			activities.SetStuckCompensationSink(sink)
+			defer activities.SetStuckCompensationSink(activities.NewInMemoryStuckCompensationSink())
+
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			startTime := testCheckIn.AddDate(0, 0, -7)
+			testEnv.SetStartTime(startTime)
+
+			request := BookingRequest{
+				BookingID: "booking-modify-002",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+			}
+			modification := ModificationRequest{Dinner: &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 4}}
+			modified := modification.apply(request)
+
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(request, 0)).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, dinnerBookingRequest(request, 0)).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}, nil).Once()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, dinnerBookingRequest(modified, 1)).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-002"}, nil).Once()
+			testEnv.OnActivity(activities.SendCheckInReminderActivity, mock.Anything, mock.Anything).Return(
+				&activities.NotificationResult{Success: true}, nil).Maybe()
+
+			// 変更前の食材の解放は全てのリトライで失敗する
+			testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, request.BookingID, "food-001").Return(
+				nil, SystemDownError).Times(3)
+			if tt.resolution.Action == CompensationActionRetry {
+				testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, request.BookingID, "food-001").Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+			// キャンセル時は変更後の食材を補償する
+			testEnv.OnActivity(activities.CompensateDinnerFoodActivity, mock.Anything, activities.ReservationKey(request.BookingID, 1), "food-002").Return(
+				&activities.CompensationResult{Success: true}, nil).Once()
+			testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, request.BookingID, "room-001").Return(
+				&activities.CompensationResult{Success: true}, nil).Once()
+
+			recorder := &updateRecorder{}
+			var awaitingPhase BookingPhase
+			testEnv.RegisterDelayedCallback(func() {
+				testEnv.UpdateWorkflow(ModifyBookingUpdate, "modify-1", recorder, modification)
+			}, time.Hour)
+			testEnv.RegisterDelayedCallback(func() {
+				value, err := testEnv.QueryWorkflow(BookingStatusQuery)
+				if assert.NoError(t, err) {
+					var status BookingStatus
+					assert.NoError(t, value.Get(&status))
+					awaitingPhase = status.Phase
+				}
+				testEnv.SignalWorkflow(ResolveCompensationSignal, tt.resolution)
+			}, 2*time.Hour)
+			testEnv.RegisterDelayedCallback(func() {
+				testEnv.SignalWorkflow(CancelBookingSignal, CancellationRequest{Reason: "予定変更"})
+			}, 24*time.Hour)
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then: 解決されるまで手動対応待ちとなり、解決後に予約変更が完了する
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			assert.Equal(t, PhaseAwaitingManualResolution, awaitingPhase)
+			assert.True(t, recorder.accepted)
+			assert.NoError(t, recorder.err)
+			modifiedResult, ok := recorder.result.(*BookingResult)
+			if assert.True(t, ok, "アップデートの結果: %T", recorder.result) {
+				assert.False(t, modifiedResult.ManualActionRequired)
+				assert.Equal(t, "予約を変更しました", modifiedResult.Message)
+				assert.Equal(t, "food-002", modifiedResult.DinnerResult.ResourceID)
+			}
+
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.Equal(t, []string{"food-002", "room-001"}, result.ReleasedResources)
+
+			recorded, err := sink.List(context.Background())
+			assert.NoError(t, err)
+			if assert.Len(t, recorded, 1) {
+				assert.Equal(t, "booking-modify-002", recorded[0].BookingID)
+				assert.Equal(t, "ディナー食材補償", recorded[0].Step)
+				assert.Equal(t, "food-001", recorded[0].ResourceID)
+				assert.Equal(t, tt.expectedRecordedStatus, recorded[0].Status)
+			}
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
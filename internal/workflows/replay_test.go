@@ -0,0 +1,36 @@
+package workflows
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/worker"
+)
+
+// TestReplayWorkflowHistories 記録済みの実行履歴（testdata/replay）を現在のワークフローで再生する
+// ワークフローの変更で過去の履歴と異なるコマンドを発行した場合は、決定性エラーとして失敗する
+// 実行中の予約を取り残さないよう、履歴が再生できない変更はGetVersionで分岐させること（versioning.go）
+//
+// 履歴の追加: temporal workflow show --workflow-id <ID> --output json > testdata/replay/<名前>.json
+func TestReplayWorkflowHistories(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "replay", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			// given
+			replayer := worker.NewWorkflowReplayer()
+			replayer.RegisterWorkflow(HotelBookingSaga)
+			replayer.RegisterWorkflow(GroupBookingWorkflow)
+
+			// when
+			err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, file)
+
+			// then
+			assert.NoError(t, err)
+		})
+	}
+}
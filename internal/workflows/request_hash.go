@@ -0,0 +1,48 @@
+package workflows
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MemoRequestHash 予約Sagaの開始時のリクエストのハッシュを記録するメモのキー
+// 同じ予約IDの再送信が同じ内容かどうかを照合するため、予約Sagaを開始する時は必ず記録する
+const MemoRequestHash = "request_hash"
+
+// RequestHash 予約リクエストの内容のハッシュ
+// 同じ日時をタイムゾーン違いで指定しても同じ内容として扱うため、日時はUTCに揃える
+// グループ予約の子ワークフローの開始時にも使うため、決定的に計算すること
+func RequestHash(request BookingRequest) (string, error) {
+	normalized := request
+	normalized.Hotel.CheckIn = utc(request.Hotel.CheckIn)
+	normalized.Hotel.CheckOut = utc(request.Hotel.CheckOut)
+	if request.Dinner != nil {
+		dinner := *request.Dinner
+		dinner.DateTime = utc(dinner.DateTime)
+		normalized.Dinner = &dinner
+	}
+	if request.Parking != nil {
+		parking := *request.Parking
+		parking.StartTime = utc(parking.StartTime)
+		parking.EndTime = utc(parking.EndTime)
+		normalized.Parking = &parking
+	}
+	if normalized.Execution == "" {
+		normalized.Execution = ExecutionSequential
+	}
+
+	b, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("hash booking request: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// utc 日時をUTCに揃える（モノトニック時計の値も取り除く）
+func utc(t time.Time) time.Time {
+	return t.UTC().Round(0)
+}
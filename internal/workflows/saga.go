@@ -1,56 +1,263 @@
 package workflows
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	enumspb "go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
 )
 
+// CompensationStep 補償処理の1ステップ
+// 補償アクティビティと、その実行に必要な引数・アクティビティオプションをまとめて保持する
+type CompensationStep struct {
+	Name       string                    // 補償処理の名前（ログや結果の表示用）
+	Resource   config.Step               // 補償対象のリソースの種類（メトリクスの集計用）
+	ResourceID string                    // 補償対象のリソースID
+	Activity   interface{}               // 補償アクティビティ
+	Args       []interface{}             // 補償アクティビティの引数
+	Options    *workflow.ActivityOptions // 個別のアクティビティオプション（nilの場合はデフォルトを使用）
+}
+
+// String 補償ステップの表示用文字列
+func (c CompensationStep) String() string {
+	if c.ResourceID == "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%s(%s)", c.Name, c.ResourceID)
+}
+
+// CompensationStatus 補償処理全体の状態
+type CompensationStatus string
+
+const (
+	// CompensationStatusNone 補償処理は実行されていない
+	CompensationStatusNone CompensationStatus = "none"
+	// CompensationStatusRolledBack 全ての補償処理が成功し、完全にロールバックされた
+	CompensationStatusRolledBack CompensationStatus = "rolled_back"
+	// CompensationStatusPartiallyFailed 一部の補償処理が失敗した（手動対応が必要）
+	CompensationStatusPartiallyFailed CompensationStatus = "partially_failed"
+)
+
+// CompensationOutcome 補償ステップ1件の実行結果
+type CompensationOutcome struct {
+	Step       string                         `json:"step"`
+	ResourceID string                         `json:"resource_id,omitempty"`
+	Attempts   int32                          `json:"attempts"`
+	Error      string                         `json:"error,omitempty"`
+	Result     *activities.CompensationResult `json:"result,omitempty"`
+	Duration   time.Duration                  `json:"duration"`
+
+	ManuallyResolved bool `json:"manually_resolved,omitempty"` // オペレーターが手動で解決済みとしたか
+}
+
+// Succeeded 補償ステップが成功したかどうか（手動で解決済みとされたものを含む）
+func (o CompensationOutcome) Succeeded() bool {
+	return o.Error == "" || o.ManuallyResolved
+}
+
+// String 補償ステップ実行結果の表示用文字列
+func (o CompensationOutcome) String() string {
+	return CompensationStep{Name: o.Step, ResourceID: o.ResourceID}.String()
+}
+
+// CompensationReport 補償処理の実行レポート
+type CompensationReport struct {
+	Outcomes []CompensationOutcome `json:"outcomes"`
+}
+
+// Status 補償処理全体の状態を返す
+func (r CompensationReport) Status() CompensationStatus {
+	if len(r.Outcomes) == 0 {
+		return CompensationStatusNone
+	}
+	if len(r.Failed()) > 0 {
+		return CompensationStatusPartiallyFailed
+	}
+	return CompensationStatusRolledBack
+}
+
+// Compensated 補償に成功したステップの一覧を返す
+func (r CompensationReport) Compensated() []string {
+	compensated := []string{}
+	for _, o := range r.Outcomes {
+		if o.Succeeded() {
+			compensated = append(compensated, o.String())
+		}
+	}
+	return compensated
+}
+
+// Failed 補償に失敗したステップの実行結果を返す
+func (r CompensationReport) Failed() []CompensationOutcome {
+	var failed []CompensationOutcome
+	for _, o := range r.Outcomes {
+		if !o.Succeeded() {
+			failed = append(failed, o)
+		}
+	}
+	return failed
+}
+
 // Compensations 補償処理のスライス
-type Compensations []interface{}
+type Compensations []CompensationStep
 
 // AddCompensation 補償処理を追加
-func (s *Compensations) AddCompensation(activity interface{}) {
-	*s = append(*s, activity)
+func (s *Compensations) AddCompensation(step CompensationStep) {
+	*s = append(*s, step)
 }
 
-// Compensate 補償処理を実行
-// inParallel: true=並列実行, false=順次実行（逆順）
-func (s Compensations) Compensate(ctx workflow.Context, inParallel bool) {
-	// 補償処理用のActivityOptions
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: time.Minute * 5,
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:    time.Second,
-			BackoffCoefficient: 2.0,
-			MaximumInterval:    time.Minute,
-			MaximumAttempts:    3,
-		},
+// find 指定した名前の補償処理を返す
+func (s Compensations) find(name string) (CompensationStep, bool) {
+	for _, step := range s {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return CompensationStep{}, false
+}
+
+// forSteps 指定したリソースの種類の補償処理だけを登録順に返す
+func (s Compensations) forSteps(steps ...config.Step) Compensations {
+	var selected Compensations
+	for _, step := range s {
+		for _, resource := range steps {
+			if step.Resource == resource {
+				selected = append(selected, step)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// replace 同じ名前の補償処理を入れ替える（実行順は維持する）。見つからない場合はfalseを返す
+func (s Compensations) replace(step CompensationStep) bool {
+	for i := range s {
+		if s[i].Name == step.Name {
+			s[i] = step
+			return true
+		}
+	}
+	return false
+}
+
+// defaultCompensationOptions 補償処理用のデフォルトActivityOptions
+func defaultCompensationOptions() workflow.ActivityOptions {
+	return config.GetActivityOptions(config.StepCompensation)
+}
+
+// execute 補償ステップを実行
+func (c CompensationStep) execute(ctx workflow.Context) (workflow.Future, workflow.ActivityOptions) {
+	activityOptions := defaultCompensationOptions()
+	if c.Options != nil {
+		activityOptions = *c.Options
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	return workflow.ExecuteActivity(ctx, c.Activity, c.Args...), activityOptions
+}
+
+// outcome 補償アクティビティの完了結果から補償ステップの実行結果を組み立てる
+func (c CompensationStep) outcome(ctx workflow.Context, f workflow.Future, options workflow.ActivityOptions, startedAt time.Time) CompensationOutcome {
+	var compensationResult *activities.CompensationResult
+	err := f.Get(ctx, &compensationResult)
+
+	outcome := CompensationOutcome{
+		Step:       c.Name,
+		ResourceID: c.ResourceID,
+		Attempts:   1,
+		Result:     compensationResult,
+		Duration:   workflow.Now(ctx).Sub(startedAt),
+	}
+	if compensationResult != nil && compensationResult.Attempt > 0 {
+		outcome.Attempts = compensationResult.Attempt
+	}
+	if err != nil {
+		outcome.Error = err.Error()
+		if exhaustedRetries(err, options.RetryPolicy) {
+			outcome.Attempts = options.RetryPolicy.MaximumAttempts
+		}
+	}
+	return outcome
+}
+
+// exhaustedRetries 補償アクティビティがリトライ上限まで実行された上で失敗したかどうか
+// リトライ不可エラーやタイムアウトの場合は試行回数を特定できないためfalseを返す
+func exhaustedRetries(err error, policy *temporal.RetryPolicy) bool {
+	if policy == nil || policy.MaximumAttempts <= 0 {
+		return false
+	}
+	var activityErr *temporal.ActivityError
+	if !errors.As(err, &activityErr) {
+		return false
+	}
+	switch activityErr.RetryState() {
+	case enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED:
+		return true
+	case enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, enumspb.RETRY_STATE_TIMEOUT:
+		return false
+	}
+	// RetryStateが設定されない環境（テスト環境など）ではエラー種別から判定する
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		if appErr.NonRetryable() {
+			return false
+		}
+		for _, nonRetryable := range policy.NonRetryableErrorTypes {
+			if appErr.Type() == nonRetryable {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Compensate 補償処理を実行し、ステップごとの実行結果をレポートとして返す
+// inParallel: true=並列実行, false=順次実行（逆順）
+func (s Compensations) Compensate(ctx workflow.Context, inParallel bool) CompensationReport {
+	logger := workflow.GetLogger(ctx)
+	report := CompensationReport{Outcomes: []CompensationOutcome{}}
+
+	logOutcome := func(step CompensationStep, outcome CompensationOutcome) {
+		recordCompensation(ctx, step, outcome)
+		if !outcome.Succeeded() {
+			logger.Error("Executing compensation failed",
+				"Step", outcome.Step, "ResourceID", outcome.ResourceID, "Attempts", outcome.Attempts, "Error", outcome.Error)
+		}
+	}
 
 	if !inParallel {
 		// 順次実行（逆順）
 		for i := len(s) - 1; i >= 0; i-- {
-			errCompensation := workflow.ExecuteActivity(ctx, s[i]).Get(ctx, nil)
-			if errCompensation != nil {
-				workflow.GetLogger(ctx).Error("Executing compensation failed", "Error", errCompensation)
-			}
+			startedAt := workflow.Now(ctx)
+			future, options := s[i].execute(ctx)
+			outcome := s[i].outcome(ctx, future, options, startedAt)
+			logOutcome(s[i], outcome)
+			report.Outcomes = append(report.Outcomes, outcome)
 		}
 	} else {
-		// 並列実行
+		// 並列実行（結果は登録順に格納）
+		outcomes := make([]CompensationOutcome, len(s))
 		selector := workflow.NewSelector(ctx)
+		startedAt := workflow.Now(ctx)
 		for i := 0; i < len(s); i++ {
-			execution := workflow.ExecuteActivity(ctx, s[i])
-			selector.AddFuture(execution, func(f workflow.Future) {
-				if errCompensation := f.Get(ctx, nil); errCompensation != nil {
-					workflow.GetLogger(ctx).Error("Executing compensation failed", "Error", errCompensation)
-				}
+			i := i
+			future, options := s[i].execute(ctx)
+			selector.AddFuture(future, func(f workflow.Future) {
+				outcomes[i] = s[i].outcome(ctx, f, options, startedAt)
+				logOutcome(s[i], outcomes[i])
 			})
 		}
 		for range s {
 			selector.Select(ctx)
 		}
+		report.Outcomes = outcomes
 	}
+
+	return report
 }
@@ -0,0 +1,334 @@
+package workflows
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+)
+
+// SagaStep 予約Sagaの1ステップの宣言的な定義
+// 予約アクティビティとリクエストの組み立て方、補償処理、失敗時の扱いをまとめて保持する
+// 予約の流れにステップを追加する場合は、定義をSagaPipelineに追加するだけでよい
+type SagaStep struct {
+	Name  string       // ステップの名前（ログや失敗理由の表示用）
+	Step  config.Step  // リトライポリシーの選択と試行回数の記録に使用
+	Phase BookingPhase // 実行中に予約状況として公開するフェーズ
+
+	DependsOn []config.Step // 並行実行時に完了を待つステップ（順次実行では定義順に従う）
+
+	Activity   interface{}                                            // 予約アクティビティ
+	Request    func(request BookingRequest, revision int) interface{} // アクティビティのリクエストを組み立てる（nilを返した場合はステップをスキップ）
+	NewResult  func() activities.ReservationResult                    // アクティビティの結果の受け取り先を作成
+	BestEffort func(request BookingRequest) bool                      // 失敗しても予約全体を取り消さないか（nilの場合は常に必須）
+	Modified   func(modification ModificationRequest) bool            // 予約変更で予約し直す対象か（nilの場合は変更できない）
+
+	// Collect 完了した結果を予約結果と予約状況に反映する（nilの場合は反映しない）
+	Collect func(result *BookingResult, status *BookingStatus, stepResult activities.ReservationResult)
+
+	Compensation SagaCompensation // 完了したステップを取り消す補償処理
+}
+
+// SagaCompensation 予約Sagaのステップを取り消す補償処理の定義
+type SagaCompensation struct {
+	Name     string      // 補償処理の名前（ログや結果の表示用）
+	Activity interface{} // 補償アクティビティ（nilの場合は補償しない）
+	Step     config.Step // 補償アクティビティのリトライポリシー
+
+	// Args 補償アクティビティの引数を組み立てる（nilの場合は予約キーとリソースID）
+	Args func(bookingID string, revision int, resourceID string) []interface{}
+}
+
+// bestEffort ステップが必須ではないかどうか
+func (s SagaStep) bestEffort(request BookingRequest) bool {
+	return s.BestEffort != nil && s.BestEffort(request)
+}
+
+// modifiedBy 予約変更で予約し直す対象かどうか
+func (s SagaStep) modifiedBy(modification ModificationRequest) bool {
+	return s.Modified != nil && s.Modified(modification)
+}
+
+// collect 完了した結果を予約結果と予約状況に反映する
+func (s SagaStep) collect(result *BookingResult, status *BookingStatus, stepResult activities.ReservationResult) {
+	if s.Collect != nil {
+		s.Collect(result, status, stepResult)
+	}
+}
+
+// compensation 完了したステップの補償処理を組み立てる（補償アクティビティがない場合はfalse）
+func (s SagaStep) compensation(bookingID string, revision int, resourceID string) (CompensationStep, bool) {
+	c := s.Compensation
+	if c.Activity == nil {
+		return CompensationStep{}, false
+	}
+	args := []interface{}{activities.ReservationKey(bookingID, revision), resourceID}
+	if c.Args != nil {
+		args = c.Args(bookingID, revision, resourceID)
+	}
+	return CompensationStep{
+		Name:       c.Name,
+		Resource:   s.Step,
+		ResourceID: resourceID,
+		Activity:   c.Activity,
+		Args:       args,
+		Options:    compensationOptions(c.Step),
+	}, true
+}
+
+// execute 予約アクティビティを実行し、結果と試行回数の判定に使うリトライポリシーを返す
+func (s SagaStep) execute(ctx workflow.Context, activityRequest interface{}) (activities.ReservationResult, *temporal.RetryPolicy, error) {
+	options := config.GetActivityOptions(s.Step)
+	stepResult := s.NewResult()
+	err := workflow.ExecuteActivity(workflow.WithActivityOptions(ctx, options), s.Activity, activityRequest).Get(ctx, stepResult)
+	return stepResult, options.RetryPolicy, err
+}
+
+// SagaPipeline 予約Sagaのステップ
+// runで定義順に1つずつ、runGraphで依存関係に従って並行して実行する
+type SagaPipeline []SagaStep
+
+// validate ステップの重複と、依存先の存在・循環をチェックする
+func (p SagaPipeline) validate() error {
+	steps := map[config.Step]SagaStep{}
+	for _, step := range p {
+		if _, ok := steps[step.Step]; ok {
+			return fmt.Errorf("step %q is defined more than once", step.Step)
+		}
+		steps[step.Step] = step
+	}
+	for _, step := range p {
+		for _, dep := range step.DependsOn {
+			if _, ok := steps[dep]; !ok {
+				return fmt.Errorf("step %q depends on undefined step %q", step.Step, dep)
+			}
+		}
+	}
+
+	// 依存先のないステップから順に取り除き、残ったものがあれば循環している
+	resolved := map[config.Step]bool{}
+	for len(resolved) < len(p) {
+		progressed := false
+		for _, step := range p {
+			if !resolved[step.Step] && dependenciesFinished(step, resolved) {
+				resolved[step.Step] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			return fmt.Errorf("step dependencies contain a cycle")
+		}
+	}
+	return nil
+}
+
+// dependenciesFinished 依存先のステップが全て終了しているかどうか
+func dependenciesFinished(step SagaStep, finished map[config.Step]bool) bool {
+	for _, dep := range step.DependsOn {
+		if !finished[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// sagaRun 予約Sagaの実行中の状態
+type sagaRun struct {
+	request       BookingRequest
+	result        *BookingResult
+	status        *BookingStatus
+	compensations Compensations
+	cancellation  *cancellationListener
+}
+
+// cancelIfRequested お客様のキャンセル要求があれば、完了済みのステップを全て補償する
+func (r *sagaRun) cancelIfRequested(ctx workflow.Context) bool {
+	if !r.cancellation.requested() {
+		return false
+	}
+	cancelBooking(ctx, r.request.BookingID, r.compensations, r.result, r.status, *r.cancellation.request)
+	return true
+}
+
+// run 各ステップを順番に実行し、予約を継続できる場合にtrueを返す
+//   - 必須のステップが失敗した場合は、完了済みのステップを補償してfalseを返す
+//   - 必須ではないステップが失敗した場合は、警告として記録して次のステップに進む
+//   - お客様のキャンセル要求は各ステップの前後でのみ確認し、完了済みのステップを全て補償する
+func (p SagaPipeline) run(ctx workflow.Context, run *sagaRun) bool {
+	logger := workflow.GetLogger(ctx)
+	request := run.request
+
+	if run.cancelIfRequested(ctx) {
+		return false
+	}
+	for i, step := range p {
+		activityRequest := step.Request(request, 0)
+		if activityRequest == nil {
+			logger.Info(fmt.Sprintf("ステップ %d: %sの指定がないためスキップ", i+1, step.Name))
+			continue
+		}
+		bestEffort := step.bestEffort(request)
+		run.status.enter(step.Phase)
+		logger.Info(fmt.Sprintf("ステップ %d: %sを開始", i+1, step.Name), "BookingID", request.BookingID, "BestEffort", bestEffort)
+
+		startedAt := workflow.Now(ctx)
+		stepResult, policy, err := step.execute(ctx, activityRequest)
+		recordStepLatency(ctx, step.Step, startedAt, err)
+		run.status.recordAttempts(step.Step, stepResult.GetAttempt(), err, policy)
+		switch {
+		case err == nil:
+			step.collect(run.result, run.status, stepResult)
+			logger.Info(fmt.Sprintf("ステップ %d: %sが完了", i+1, step.Name), "ResourceID", stepResult.GetResourceID())
+
+			// 補償アクティビティの追加
+			if compensation, ok := step.compensation(request.BookingID, 0, stepResult.GetResourceID()); ok {
+				run.compensations.AddCompensation(compensation)
+				run.status.registerCompensation(compensation)
+			}
+		case bestEffort:
+			logger.Warn(fmt.Sprintf("%sに失敗したが、必須ではないため予約を継続", step.Name), "Error", err.Error())
+			run.result.degrade(step.Step, step.Name, err)
+		default:
+			logger.Error(fmt.Sprintf("%sに失敗", step.Name), "Error", err.Error())
+			run.result.recordFailure(step.Step, step.Name, err)
+			if len(run.compensations) > 0 {
+				logger.Info("補償処理を開始")
+				compensate(ctx, request.BookingID, run.compensations, run.result, run.status)
+			}
+			return false
+		}
+		if run.cancelIfRequested(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// runGraph 依存関係が解決したステップから並行して実行し、予約を継続できる場合にtrueを返す
+//   - 依存先が全て終了（完了・スキップ・必須ではないステップの失敗）したステップから開始する
+//   - 必須のステップが失敗した場合は、実行中の他のステップをキャンセルし、完了した全てのステップを補償する
+//   - お客様のキャンセル要求を受けた時点で、同様に実行中のステップをキャンセルしてから補償する
+//   - キャンセルしたステップも試行回数と所要時間を記録する
+//
+// ステップの依存関係はワーカー起動時にValidatePipelinesで検証済みであること
+func (p SagaPipeline) runGraph(ctx workflow.Context, run *sagaRun) bool {
+	logger := workflow.GetLogger(ctx)
+	request := run.request
+
+	if run.cancelIfRequested(ctx) {
+		return false
+	}
+
+	stepsCtx, cancelSteps := workflow.WithCancel(ctx)
+	defer cancelSteps()
+	selector := workflow.NewSelector(ctx)
+	// 実行中のステップの終了を待たずにキャンセル要求を受け取る
+	selector.AddReceive(run.cancellation.ch, func(c workflow.ReceiveChannel, more bool) {
+		run.cancellation.receive(ctx, c)
+	})
+	started := map[config.Step]bool{}
+	finished := map[config.Step]bool{}
+	running := map[config.Step]BookingPhase{}
+	failed, cancelled := false, false
+
+	start := func(step SagaStep, activityRequest interface{}) {
+		bestEffort := step.bestEffort(request)
+		logger.Info(fmt.Sprintf("%sを開始", step.Name), "BookingID", request.BookingID, "BestEffort", bestEffort)
+
+		options := config.GetActivityOptions(step.Step)
+		// キャンセルが間に合わずに完了した予約も補償できるよう、アクティビティの終了を待つ
+		options.WaitForCancellation = true
+		stepResult := step.NewResult()
+		startedAt := workflow.Now(ctx)
+		future := workflow.ExecuteActivity(workflow.WithActivityOptions(stepsCtx, options), step.Activity, activityRequest)
+		running[step.Step] = step.Phase
+		selector.AddFuture(future, func(f workflow.Future) {
+			delete(running, step.Step)
+			finished[step.Step] = true
+			err := f.Get(ctx, stepResult)
+			recordStepLatency(ctx, step.Step, startedAt, err)
+			run.status.recordAttempts(step.Step, stepResult.GetAttempt(), err, options.RetryPolicy)
+			switch {
+			case temporal.IsCanceledError(err):
+				logger.Info(fmt.Sprintf("%sをキャンセルしました", step.Name))
+			case err == nil:
+				step.collect(run.result, run.status, stepResult)
+				logger.Info(fmt.Sprintf("%sが完了", step.Name), "ResourceID", stepResult.GetResourceID())
+
+				// 他のステップの失敗後に完了した場合も補償の対象とする
+				if compensation, ok := step.compensation(request.BookingID, 0, stepResult.GetResourceID()); ok {
+					run.compensations.AddCompensation(compensation)
+					run.status.registerCompensation(compensation)
+				}
+			case bestEffort:
+				logger.Warn(fmt.Sprintf("%sに失敗したが、必須ではないため予約を継続", step.Name), "Error", err.Error())
+				run.result.degrade(step.Step, step.Name, err)
+			case failed || cancelled:
+				logger.Error(fmt.Sprintf("%sに失敗", step.Name), "Error", err.Error())
+			default:
+				logger.Error(fmt.Sprintf("%sに失敗したため、実行中のステップをキャンセル", step.Name), "Error", err.Error())
+				run.result.recordFailure(step.Step, step.Name, err)
+				failed = true
+				cancelSteps()
+			}
+		})
+	}
+
+	for {
+		// 依存先が終了したステップを開始する（スキップしたステップで新たに開始できるものがあれば繰り返す）
+		for progressed := !failed && !cancelled; progressed; {
+			progressed = false
+			for _, step := range p {
+				if started[step.Step] || !dependenciesFinished(step, finished) {
+					continue
+				}
+				started[step.Step] = true
+				activityRequest := step.Request(request, 0)
+				if activityRequest == nil {
+					logger.Info(fmt.Sprintf("%sの指定がないためスキップ", step.Name))
+					finished[step.Step] = true
+					progressed = true
+					continue
+				}
+				start(step, activityRequest)
+			}
+		}
+		if len(running) == 0 {
+			break
+		}
+		run.status.enter(runningPhase(running))
+		selector.Select(ctx)
+
+		if !failed && !cancelled && run.cancellation.requested() {
+			logger.Info("キャンセル要求を受けたため、実行中のステップをキャンセル", "BookingID", request.BookingID)
+			cancelled = true
+			cancelSteps()
+		}
+	}
+
+	switch {
+	case cancelled:
+		cancelBooking(ctx, request.BookingID, run.compensations, run.result, run.status, *run.cancellation.request)
+		return false
+	case failed:
+		if len(run.compensations) > 0 {
+			logger.Info("補償処理を開始")
+			compensate(ctx, request.BookingID, run.compensations, run.result, run.status)
+		}
+		return false
+	}
+	return !run.cancelIfRequested(ctx)
+}
+
+// runningPhase 実行中のステップから予約状況として公開するフェーズを決める
+func runningPhase(running map[config.Step]BookingPhase) BookingPhase {
+	if len(running) > 1 {
+		return PhaseBookingInParallel
+	}
+	for _, phase := range running {
+		return phase
+	}
+	return PhaseBookingHotel
+}
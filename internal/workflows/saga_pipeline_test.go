@@ -0,0 +1,369 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+)
+
+// テスト用の追加ステップ（スパ予約）
+const stepSpa config.Step = "spa"
+
+type spaBookingRequest struct {
+	BookingID string `json:"booking_id"`
+	Course    string `json:"course"`
+}
+
+type spaBookingResult struct {
+	ResourceID string `json:"resource_id"`
+	Attempt    int32  `json:"attempt,omitempty"`
+}
+
+func (r *spaBookingResult) GetResourceID() string { return r.ResourceID }
+func (r *spaBookingResult) GetAttempt() int32     { return r.Attempt }
+
+func spaBookingActivity(ctx context.Context, req spaBookingRequest) (*spaBookingResult, error) {
+	return &spaBookingResult{ResourceID: "spa-001"}, nil
+}
+
+func compensateSpaActivity(ctx context.Context, bookingID string, resourceID string) (*activities.CompensationResult, error) {
+	return &activities.CompensationResult{Success: true}, nil
+}
+
+// spaPipeline ホテル・ディナー・スパの順に実行するパイプライン
+func spaPipeline(spaBestEffort bool) SagaPipeline {
+	spaStep := SagaStep{
+		Name:     "スパ予約",
+		Step:     stepSpa,
+		Phase:    BookingPhase("booking_spa"),
+		Activity: spaBookingActivity,
+		Request: func(request BookingRequest, revision int) interface{} {
+			return spaBookingRequest{BookingID: activities.ReservationKey(request.BookingID, revision), Course: "aroma"}
+		},
+		NewResult:  func() activities.ReservationResult { return &spaBookingResult{} },
+		BestEffort: func(request BookingRequest) bool { return spaBestEffort },
+		Compensation: SagaCompensation{
+			Name:     "スパ補償",
+			Activity: compensateSpaActivity,
+			Step:     config.StepCompensation,
+		},
+	}
+	return SagaPipeline{hotelStep, dinnerStep, spaStep}
+}
+
+// pipelineTestWorkflow パイプラインだけを実行するテスト用ワークフロー
+func pipelineTestWorkflow(ctx workflow.Context, request BookingRequest, spaBestEffort bool) (*pipelineTestResult, error) {
+	status := newBookingStatus(ctx, request.BookingID)
+	result := &BookingResult{BookingID: request.BookingID}
+	run := &sagaRun{
+		request:      request,
+		result:       result,
+		status:       status,
+		cancellation: newCancellationListener(ctx),
+	}
+	result.Success = spaPipeline(spaBestEffort).run(ctx, run)
+	return &pipelineTestResult{Result: *result, Status: *status}, nil
+}
+
+type pipelineTestResult struct {
+	Result BookingResult
+	Status BookingStatus
+}
+
+// testケース
+// 正常系:
+//   - 追加したスパ予約ステップがホテルの後に実行され、指定のないディナーはスキップされる
+//
+// 準異常系:
+//   - 必須ではないスパ予約が失敗した時、ホテルを補償せずに一部の予約なしで継続する
+//
+// 異常系:
+//   - 必須のスパ予約が失敗した時、ホテルを補償して失敗したステップを記録する
+func TestSagaPipeline_Run(t *testing.T) {
+	tests := map[string]struct {
+		spaBestEffort bool
+		spaErr        error
+
+		expectedSuccess       bool
+		expectedCompensations []string
+		expectedFailedStep    config.Step
+		expectedDegradedSteps []config.Step
+		expectedReleased      []string
+	}{
+		"正常系 - スパ予約を追加したパイプラインが完了する": {
+			expectedSuccess:       true,
+			expectedCompensations: []string{"ホテルルーム補償(room-001)", "スパ補償(spa-001)"},
+		},
+		"準異常系 - 必須ではないスパ予約が失敗し、一部の予約なしで継続する": {
+			spaBestEffort:         true,
+			spaErr:                activities.NewBusinessError("指定されたコースは満席です", "SPA_FULL"),
+			expectedSuccess:       true,
+			expectedCompensations: []string{"ホテルルーム補償(room-001)"},
+			expectedDegradedSteps: []config.Step{stepSpa},
+		},
+		"異常系 - 必須のスパ予約が失敗し、ホテルを補償する": {
+			spaErr:                activities.NewBusinessError("指定されたコースは満席です", "SPA_FULL"),
+			expectedCompensations: []string{"ホテルルーム補償(room-001)"},
+			expectedFailedStep:    stepSpa,
+			expectedReleased:      []string{"room-001"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.RegisterActivity(spaBookingActivity)
+			testEnv.RegisterActivity(compensateSpaActivity)
+			testEnv.RegisterWorkflow(pipelineTestWorkflow)
+
+			request := BookingRequest{
+				BookingID: "booking-pipeline-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(request, 0)).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			spaRequest := spaBookingRequest{BookingID: request.BookingID, Course: "aroma"}
+			if tt.spaErr != nil {
+				testEnv.OnActivity(spaBookingActivity, mock.Anything, spaRequest).Return(
+					nil, activities.ToApplicationError(tt.spaErr)).Once()
+			} else {
+				testEnv.OnActivity(spaBookingActivity, mock.Anything, spaRequest).Return(
+					&spaBookingResult{ResourceID: "spa-001", Attempt: 1}, nil).Once()
+			}
+			if tt.expectedReleased != nil {
+				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, request.BookingID, "room-001").Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(pipelineTestWorkflow, request, tt.spaBestEffort)
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var got pipelineTestResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&got))
+			assert.Equal(t, tt.expectedSuccess, got.Result.Success)
+			assert.Equal(t, tt.expectedCompensations, got.Status.Compensations)
+			assert.Equal(t, tt.expectedFailedStep, got.Result.FailedStep)
+			assert.Equal(t, tt.expectedDegradedSteps, got.Result.DegradedSteps)
+			assert.Equal(t, tt.expectedReleased, got.Result.ReleasedResources)
+			assert.NotContains(t, got.Status.Attempts, config.StepDinner)
+			assert.Contains(t, got.Status.Attempts, stepSpa)
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
+
+// graphTestResult 並行実行の結果と所要時間
+type graphTestResult struct {
+	Result  BookingResult
+	Status  BookingStatus
+	Elapsed time.Duration
+}
+
+// graphTestWorkflow 予約Sagaのステップを並行実行するテスト用ワークフロー
+func graphTestWorkflow(ctx workflow.Context, request BookingRequest) (*graphTestResult, error) {
+	startedAt := workflow.Now(ctx)
+	status := newBookingStatus(ctx, request.BookingID)
+	result := &BookingResult{BookingID: request.BookingID}
+	run := &sagaRun{
+		request:      request,
+		result:       result,
+		status:       status,
+		cancellation: newCancellationListener(ctx),
+	}
+	result.Success = bookingPipeline.runGraph(ctx, run)
+	return &graphTestResult{Result: *result, Status: *status, Elapsed: workflow.Now(ctx).Sub(startedAt)}, nil
+}
+
+// testケース
+// 正常系:
+//   - ホテルの完了後、ディナーと駐車場が並行して実行され、所要時間は長い方のステップに揃う
+//
+// 準異常系:
+//   - 必須ではない駐車場が失敗した時、ディナーの完了を待って一部の予約なしで継続する
+//
+// 異常系:
+//   - 駐車場が失敗した時、実行中のディナーをキャンセルし、完了したホテルを補償する
+//   - ディナーの完了後に駐車場が失敗した時、完了したディナーとホテルを補償する
+//   - 実行中にキャンセル要求を受けた時、ステップの終了を待たずにキャンセルし、キャンセルしたステップの試行回数を記録する
+func TestSagaPipeline_RunGraph(t *testing.T) {
+	tests := map[string]struct {
+		dinnerDelay       time.Duration
+		parkingDelay      time.Duration
+		parkingBestEffort bool
+		parkingErr        error
+		cancelAfter       time.Duration // 0の場合はキャンセルしない
+
+		expectedSuccess       bool
+		expectedElapsed       time.Duration
+		expectedCompensations []string
+		expectedFailedStep    config.Step
+		expectedDegradedSteps []config.Step
+		expectedReleased      []string
+		expectedAttempts      map[config.Step]int32
+	}{
+		"正常系 - ディナーと駐車場を並行して予約する": {
+			dinnerDelay:           2 * time.Hour,
+			parkingDelay:          time.Hour,
+			expectedSuccess:       true,
+			expectedElapsed:       2 * time.Hour,
+			expectedCompensations: []string{"ホテルルーム補償(room-001)", "駐車場補償(parking-001)", "ディナー食材補償(food-001)"},
+		},
+		"準異常系 - 必須ではない駐車場が満車になり、ディナーの完了を待って継続する": {
+			dinnerDelay:           2 * time.Hour,
+			parkingBestEffort:     true,
+			parkingErr:            activities.NewBusinessError("指定された駐車場は満車です", activities.CodeParkingFull),
+			expectedSuccess:       true,
+			expectedElapsed:       2 * time.Hour,
+			expectedCompensations: []string{"ホテルルーム補償(room-001)", "ディナー食材補償(food-001)"},
+			expectedDegradedSteps: []config.Step{config.StepParking},
+		},
+		"異常系 - 駐車場が満車になり、実行中のディナーをキャンセルする": {
+			dinnerDelay:           2 * time.Hour,
+			parkingErr:            activities.NewBusinessError("指定された駐車場は満車です", activities.CodeParkingFull),
+			expectedCompensations: []string{"ホテルルーム補償(room-001)"},
+			expectedFailedStep:    config.StepParking,
+			expectedReleased:      []string{"room-001"},
+		},
+		"異常系 - ディナーの完了後に駐車場が満車になり、ディナーとホテルを補償する": {
+			parkingDelay:          time.Hour,
+			parkingErr:            activities.NewBusinessError("指定された駐車場は満車です", activities.CodeParkingFull),
+			expectedElapsed:       time.Hour,
+			expectedCompensations: []string{"ホテルルーム補償(room-001)", "ディナー食材補償(food-001)"},
+			expectedFailedStep:    config.StepParking,
+			expectedReleased:      []string{"food-001", "room-001"},
+		},
+		"異常系 - 実行中にキャンセル要求を受け、ディナーと駐車場をキャンセルする": {
+			dinnerDelay:           2 * time.Hour,
+			parkingDelay:          2 * time.Hour,
+			cancelAfter:           30 * time.Minute,
+			expectedElapsed:       30 * time.Minute,
+			expectedCompensations: []string{"ホテルルーム補償(room-001)"},
+			expectedReleased:      []string{"room-001"},
+			expectedAttempts:      map[config.Step]int32{config.StepHotel: 1, config.StepDinner: 1, config.StepParking: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			helper := NewWorkflowTestHelper()
+			testEnv := helper.testEnv
+			testEnv.RegisterWorkflow(graphTestWorkflow)
+
+			request := BookingRequest{
+				BookingID: "booking-graph-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd, BestEffort: tt.parkingBestEffort},
+				Execution: ExecutionParallel,
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, hotelBookingRequest(request, 0)).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-001"}, nil).Once()
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, dinnerBookingRequest(request, 0)).Return(
+				&activities.DinnerBookingResult{Success: true, ResourceID: "food-001"}, nil).After(tt.dinnerDelay).Once()
+			if tt.parkingErr != nil {
+				testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, parkingBookingRequest(request, 0)).Return(
+					nil, activities.ToApplicationError(tt.parkingErr)).After(tt.parkingDelay).Once()
+			} else {
+				testEnv.OnActivity(activities.ParkingBookingActivity, mock.Anything, parkingBookingRequest(request, 0)).Return(
+					&activities.ParkingBookingResult{Success: true, ResourceID: "parking-001"}, nil).After(tt.parkingDelay).Once()
+			}
+			for _, resourceID := range tt.expectedReleased {
+				compensation := map[string]interface{}{
+					"room": activities.CompensateHotelRoomActivity,
+					"food": activities.CompensateDinnerFoodActivity,
+				}[resourceID[:4]]
+				testEnv.OnActivity(compensation, mock.Anything, request.BookingID, resourceID).Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+
+			if tt.cancelAfter > 0 {
+				testEnv.RegisterDelayedCallback(func() {
+					testEnv.SignalWorkflow(CancelBookingSignal, CancellationRequest{Reason: "予定変更"})
+				}, tt.cancelAfter)
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(graphTestWorkflow, request)
+
+			// then
+			assert.True(t, testEnv.IsWorkflowCompleted())
+			var got graphTestResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&got))
+			assert.Equal(t, tt.expectedSuccess, got.Result.Success)
+			assert.Equal(t, tt.expectedElapsed, got.Elapsed)
+			assert.Equal(t, tt.expectedCompensations, got.Status.Compensations)
+			assert.Equal(t, tt.expectedFailedStep, got.Result.FailedStep)
+			assert.Equal(t, tt.expectedDegradedSteps, got.Result.DegradedSteps)
+			assert.Equal(t, tt.expectedReleased, got.Result.ReleasedResources)
+			assert.Equal(t, tt.cancelAfter > 0, got.Result.CancelledByCustomer)
+			if tt.expectedAttempts != nil {
+				assert.Equal(t, tt.expectedAttempts, got.Status.Attempts)
+			}
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
+
+// testケース
+// 正常系:
+//   - 予約Sagaのステップの依存関係は妥当
+//
+// 異常系:
+//   - 存在しないステップに依存している時、エラーが返却される
+//   - 依存関係が循環している時、エラーが返却される
+func TestSagaPipeline_Validate(t *testing.T) {
+	tests := map[string]struct {
+		pipeline    SagaPipeline
+		expectedErr string
+	}{
+		"正常系 - 予約Sagaのステップ": {
+			pipeline: bookingPipeline,
+		},
+		"異常系 - 存在しないステップに依存する": {
+			pipeline:    SagaPipeline{dinnerStep},
+			expectedErr: `step "dinner" depends on undefined step "hotel"`,
+		},
+		"異常系 - 依存関係が循環する": {
+			pipeline: SagaPipeline{
+				{Step: "a", DependsOn: []config.Step{"b"}},
+				{Step: "b", DependsOn: []config.Step{"a"}},
+			},
+			expectedErr: "step dependencies contain a cycle",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			err := tt.pipeline.validate()
+
+			// then
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidatePipelines(t *testing.T) {
+	// when: ワーカー起動時に予約ステップの構成を検証する
+	err := ValidatePipelines()
+
+	// then
+	assert.NoError(t, err)
+}
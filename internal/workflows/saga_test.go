@@ -4,6 +4,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/workflow"
 )
@@ -55,7 +57,7 @@ func TestCompensations_AddCompensation(t *testing.T) {
 
 			// When
 			for _, activity := range tt.given.activities {
-				compensations.AddCompensation(activity)
+				compensations.AddCompensation(CompensationStep{Name: "テスト補償", Activity: activity})
 			}
 
 			// Then
@@ -76,9 +78,9 @@ func TestCompensations_Compensate_Sequential_Success(t *testing.T) {
 	activity2 := func() error { return nil }
 	activity3 := func() error { return nil }
 
-	compensations.AddCompensation(activity1)
-	compensations.AddCompensation(activity2)
-	compensations.AddCompensation(activity3)
+	compensations.AddCompensation(CompensationStep{Name: "補償1", Activity: activity1})
+	compensations.AddCompensation(CompensationStep{Name: "補償2", Activity: activity2})
+	compensations.AddCompensation(CompensationStep{Name: "補償3", Activity: activity3})
 
 	// アクティビティの登録
 	env.RegisterActivity(activity1)
@@ -111,9 +113,9 @@ func TestCompensations_Compensate_Sequential_WithErrors(t *testing.T) {
 	activity2 := func() error { return errors.New("補償処理でエラー発生") }
 	activity3 := func() error { return nil }
 
-	compensations.AddCompensation(activity1)
-	compensations.AddCompensation(activity2)
-	compensations.AddCompensation(activity3)
+	compensations.AddCompensation(CompensationStep{Name: "補償1", Activity: activity1})
+	compensations.AddCompensation(CompensationStep{Name: "補償2", Activity: activity2})
+	compensations.AddCompensation(CompensationStep{Name: "補償3", Activity: activity3})
 
 	// アクティビティの登録
 	env.RegisterActivity(activity1)
@@ -147,9 +149,9 @@ func TestCompensations_Compensate_Parallel_Success(t *testing.T) {
 	activity2 := func() error { return nil }
 	activity3 := func() error { return nil }
 
-	compensations.AddCompensation(activity1)
-	compensations.AddCompensation(activity2)
-	compensations.AddCompensation(activity3)
+	compensations.AddCompensation(CompensationStep{Name: "補償1", Activity: activity1})
+	compensations.AddCompensation(CompensationStep{Name: "補償2", Activity: activity2})
+	compensations.AddCompensation(CompensationStep{Name: "補償3", Activity: activity3})
 
 	// アクティビティの登録
 	env.RegisterActivity(activity1)
@@ -182,9 +184,9 @@ func TestCompensations_Compensate_Parallel_WithErrors(t *testing.T) {
 	activity2 := func() error { return errors.New("並列補償処理でエラー発生") }
 	activity3 := func() error { return nil }
 
-	compensations.AddCompensation(activity1)
-	compensations.AddCompensation(activity2)
-	compensations.AddCompensation(activity3)
+	compensations.AddCompensation(CompensationStep{Name: "補償1", Activity: activity1})
+	compensations.AddCompensation(CompensationStep{Name: "補償2", Activity: activity2})
+	compensations.AddCompensation(CompensationStep{Name: "補償3", Activity: activity3})
 
 	// アクティビティの登録
 	env.RegisterActivity(activity1)
@@ -230,3 +232,144 @@ func TestCompensations_Compensate_EmptyCompensations(t *testing.T) {
 		t.Errorf("予期しないエラーが発生しました: %v", err)
 	}
 }
+
+func TestCompensations_Compensate_WithArgs(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	// Given
+	var receivedArgs [][]string
+	releaseRoom := func(bookingID string, resourceID string) error {
+		receivedArgs = append(receivedArgs, []string{bookingID, resourceID})
+		return nil
+	}
+	releaseFood := func(bookingID string, resourceID string) error {
+		receivedArgs = append(receivedArgs, []string{bookingID, resourceID})
+		return nil
+	}
+	env.RegisterActivityWithOptions(releaseRoom, activity.RegisterOptions{Name: "releaseRoom"})
+	env.RegisterActivityWithOptions(releaseFood, activity.RegisterOptions{Name: "releaseFood"})
+
+	compensations := Compensations{}
+	compensations.AddCompensation(CompensationStep{
+		Name:       "ホテルルーム補償",
+		ResourceID: "room-001",
+		Activity:   "releaseRoom",
+		Args:       []interface{}{"booking-001", "room-001"},
+	})
+	compensations.AddCompensation(CompensationStep{
+		Name:       "ディナー食材補償",
+		ResourceID: "food-001",
+		Activity:   "releaseFood",
+		Args:       []interface{}{"booking-001", "food-001"},
+	})
+
+	// When
+	env.ExecuteWorkflow(func(ctx workflow.Context) (CompensationReport, error) {
+		return compensations.Compensate(ctx, false), nil
+	})
+
+	// Then
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("ワークフローが完了していません")
+	}
+	var report CompensationReport
+	if err := env.GetWorkflowResult(&report); err != nil {
+		t.Fatalf("予期しないエラーが発生しました: %v", err)
+	}
+	assert.Equal(t, []string{"ディナー食材補償(food-001)", "ホテルルーム補償(room-001)"}, report.Compensated())
+	assert.Equal(t, [][]string{{"booking-001", "food-001"}, {"booking-001", "room-001"}}, receivedArgs)
+}
+
+func TestCompensations_Compensate_Report(t *testing.T) {
+	tests := map[string]struct {
+		inParallel       bool
+		failingSteps     map[string]bool
+		expectedStatus   CompensationStatus
+		expectedFailed   []string
+		expectedAttempts map[string]int32
+	}{
+		"正常系 - 順次実行で全ての補償が成功した時、rolled_backになる": {
+			inParallel:       false,
+			expectedStatus:   CompensationStatusRolledBack,
+			expectedAttempts: map[string]int32{"ホテルルーム補償": 1, "駐車場補償": 1},
+		},
+		"異常系 - 順次実行で補償がリトライ上限まで失敗した時、partially_failedになる": {
+			inParallel:       false,
+			failingSteps:     map[string]bool{"releaseRoom": true},
+			expectedStatus:   CompensationStatusPartiallyFailed,
+			expectedFailed:   []string{"ホテルルーム補償(room-001)"},
+			expectedAttempts: map[string]int32{"ホテルルーム補償": 3, "駐車場補償": 1},
+		},
+		"異常系 - 並列実行で補償がリトライ上限まで失敗した時、partially_failedになる": {
+			inParallel:       true,
+			failingSteps:     map[string]bool{"releaseParking": true},
+			expectedStatus:   CompensationStatusPartiallyFailed,
+			expectedFailed:   []string{"駐車場補償(parking-001)"},
+			expectedAttempts: map[string]int32{"ホテルルーム補償": 1, "駐車場補償": 3},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			testSuite := &testsuite.WorkflowTestSuite{}
+			env := testSuite.NewTestWorkflowEnvironment()
+			for _, activityName := range []string{"releaseRoom", "releaseParking"} {
+				fail := tt.failingSteps[activityName]
+				env.RegisterActivityWithOptions(func(bookingID string, resourceID string) error {
+					if fail {
+						return errors.New("補償処理システムがダウンしています")
+					}
+					return nil
+				}, activity.RegisterOptions{Name: activityName})
+			}
+
+			compensations := Compensations{}
+			compensations.AddCompensation(CompensationStep{
+				Name: "ホテルルーム補償", ResourceID: "room-001",
+				Activity: "releaseRoom", Args: []interface{}{"booking-001", "room-001"},
+			})
+			compensations.AddCompensation(CompensationStep{
+				Name: "駐車場補償", ResourceID: "parking-001",
+				Activity: "releaseParking", Args: []interface{}{"booking-001", "parking-001"},
+			})
+
+			// When
+			env.ExecuteWorkflow(func(ctx workflow.Context) (CompensationReport, error) {
+				return compensations.Compensate(ctx, tt.inParallel), nil
+			})
+
+			// Then
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("ワークフローが完了していません")
+			}
+			var report CompensationReport
+			if err := env.GetWorkflowResult(&report); err != nil {
+				t.Fatalf("予期しないエラーが発生しました: %v", err)
+			}
+			assert.Equal(t, tt.expectedStatus, report.Status())
+			var failed []string
+			for _, o := range report.Failed() {
+				failed = append(failed, o.String())
+				assert.NotEmpty(t, o.Error)
+			}
+			assert.Equal(t, tt.expectedFailed, failed)
+			for _, o := range report.Outcomes {
+				assert.Equal(t, tt.expectedAttempts[o.Step], o.Attempts, o.Step)
+			}
+		})
+	}
+}
+
+func TestCompensationReport_Status_Empty(t *testing.T) {
+	// Given
+	report := CompensationReport{}
+
+	// When
+	status := report.Status()
+
+	// Then
+	assert.Equal(t, CompensationStatusNone, status)
+	assert.Empty(t, report.Compensated())
+}
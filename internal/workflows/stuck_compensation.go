@@ -0,0 +1,158 @@
+package workflows
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+	"temporal-hotel-sample/internal/activities"
+	"temporal-hotel-sample/internal/config"
+)
+
+const (
+	// ResolveCompensationSignal 手動対応待ちの補償処理を解決するシグナル名
+	ResolveCompensationSignal = "resolve-compensation"
+
+	// ManualInterventionTimeout オペレーターの手動対応を待つ最大時間
+	ManualInterventionTimeout = 7 * 24 * time.Hour
+)
+
+// CompensationResolutionAction オペレーターによる解決方法
+type CompensationResolutionAction string
+
+const (
+	// CompensationActionRetry 補償アクティビティを再実行する
+	CompensationActionRetry CompensationResolutionAction = "retry"
+	// CompensationActionMarkResolved 手動で補償済みとして扱う
+	CompensationActionMarkResolved CompensationResolutionAction = "mark_resolved"
+)
+
+// CompensationResolution 手動対応待ち補償処理に対するオペレーターの指示
+type CompensationResolution struct {
+	Step       string                       `json:"step"`
+	ResourceID string                       `json:"resource_id"`
+	Action     CompensationResolutionAction `json:"action"`
+	Note       string                       `json:"note,omitempty"`
+}
+
+// awaitManualResolution リトライ上限まで失敗した補償処理を記録し、オペレーターの指示を待つ
+// 全ての補償処理が解決するか、ManualInterventionTimeoutを超えるまで待機し、更新後のレポートを返す
+func awaitManualResolution(ctx workflow.Context, bookingID string, steps Compensations, report CompensationReport) CompensationReport {
+	failed := report.Failed()
+	if len(failed) == 0 {
+		return report
+	}
+
+	logger := workflow.GetLogger(ctx)
+	info := workflow.GetInfo(ctx)
+	recordCtx := workflow.WithActivityOptions(ctx, config.GetActivityOptions(config.StepStuckCompensation))
+
+	for _, o := range failed {
+		entry := activities.StuckCompensation{
+			BookingID:  bookingID,
+			WorkflowID: info.WorkflowExecution.ID,
+			RunID:      info.WorkflowExecution.RunID,
+			Step:       o.Step,
+			ResourceID: o.ResourceID,
+			Attempts:   o.Attempts,
+			LastError:  o.Error,
+		}
+		if err := workflow.ExecuteActivity(recordCtx, activities.RecordStuckCompensationActivity, entry).Get(ctx, nil); err != nil {
+			logger.Error("手動対応待ち補償処理の記録に失敗", "Step", o.Step, "ResourceID", o.ResourceID, "Error", err)
+		}
+	}
+
+	logger.Info("補償処理の手動対応を待機", "BookingID", bookingID, "Pending", len(failed))
+
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	timer := workflow.NewTimer(timerCtx, ManualInterventionTimeout)
+	signalCh := workflow.GetSignalChannel(ctx, ResolveCompensationSignal)
+
+	timedOut := false
+	for len(report.Failed()) > 0 && !timedOut {
+		var resolution *CompensationResolution
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(signalCh, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, &resolution)
+		})
+		selector.AddFuture(timer, func(f workflow.Future) {
+			timedOut = true
+		})
+		selector.Select(ctx)
+
+		if resolution != nil {
+			report = applyResolution(ctx, bookingID, steps, report, *resolution)
+		}
+	}
+
+	if timedOut {
+		logger.Error("補償処理の手動対応待ちがタイムアウト", "BookingID", bookingID, "Pending", len(report.Failed()))
+	}
+	return report
+}
+
+// applyResolution オペレーターの指示を1件適用し、更新後のレポートを返す
+func applyResolution(ctx workflow.Context, bookingID string, steps Compensations, report CompensationReport, resolution CompensationResolution) CompensationReport {
+	logger := workflow.GetLogger(ctx)
+
+	index := -1
+	for i, o := range report.Outcomes {
+		if o.Step == resolution.Step && o.ResourceID == resolution.ResourceID && !o.Succeeded() {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		logger.Warn("対象の手動対応待ち補償処理が見つかりません", "Step", resolution.Step, "ResourceID", resolution.ResourceID)
+		return report
+	}
+
+	entry := activities.StuckCompensation{
+		BookingID:  bookingID,
+		Step:       resolution.Step,
+		ResourceID: resolution.ResourceID,
+		Note:       resolution.Note,
+	}
+
+	switch resolution.Action {
+	case CompensationActionRetry:
+		var step *CompensationStep
+		for i := range steps {
+			if steps[i].Name == resolution.Step && steps[i].ResourceID == resolution.ResourceID {
+				step = &steps[i]
+				break
+			}
+		}
+		if step == nil {
+			logger.Warn("再実行対象の補償ステップが見つかりません", "Step", resolution.Step, "ResourceID", resolution.ResourceID)
+			return report
+		}
+
+		logger.Info("オペレーターの指示により補償処理を再実行", "Step", resolution.Step, "ResourceID", resolution.ResourceID)
+		startedAt := workflow.Now(ctx)
+		future, options := step.execute(ctx)
+		outcome := step.outcome(ctx, future, options, startedAt)
+		outcome.Attempts += report.Outcomes[index].Attempts
+		report.Outcomes[index] = outcome
+		if !outcome.Succeeded() {
+			logger.Error("補償処理の再実行に失敗", "Step", resolution.Step, "ResourceID", resolution.ResourceID, "Error", outcome.Error)
+			return report
+		}
+		entry.Status = activities.StuckCompensationRetried
+
+	case CompensationActionMarkResolved:
+		logger.Info("オペレーターにより補償処理が手動で解決済みとされました", "Step", resolution.Step, "ResourceID", resolution.ResourceID)
+		report.Outcomes[index].ManuallyResolved = true
+		entry.Status = activities.StuckCompensationManuallyResolved
+
+	default:
+		logger.Warn("不明な解決方法が指定されました", "Action", resolution.Action)
+		return report
+	}
+
+	recordCtx := workflow.WithActivityOptions(ctx, config.GetActivityOptions(config.StepStuckCompensation))
+	if err := workflow.ExecuteActivity(recordCtx, activities.UpdateStuckCompensationActivity, entry).Get(ctx, nil); err != nil {
+		logger.Error("手動対応待ち補償処理の状態更新に失敗", "Step", resolution.Step, "ResourceID", resolution.ResourceID, "Error", err)
+	}
+	return report
+}
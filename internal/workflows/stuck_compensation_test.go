@@ -0,0 +1,128 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/testsuite"
+	"temporal-hotel-sample/internal/activities"
+)
+
+// testケース
+// 正常系:
+//   - 補償がリトライ上限まで失敗した後、オペレーターが再実行を指示して補償が成功する
+//   - 補償がリトライ上限まで失敗した後、オペレーターが手動で解決済みとする
+//
+// 異常系:
+//   - 補償がリトライ上限まで失敗し、オペレーターの指示がないまま待機がタイムアウトする
+func TestHotelBookingSaga_ManualCompensationResolution(t *testing.T) {
+	tests := map[string]struct {
+		resolution *CompensationResolution
+
+		expectedRollbackStatus CompensationStatus
+		expectedManualAction   bool
+		expectedCompensations  []string
+		expectedRecordedStatus activities.StuckCompensationStatus
+	}{
+		"正常系 - オペレーターの再実行指示で補償が成功する": {
+			resolution: &CompensationResolution{
+				Step:       "ホテルルーム補償",
+				ResourceID: "room-101",
+				Action:     CompensationActionRetry,
+			},
+			expectedRollbackStatus: CompensationStatusRolledBack,
+			expectedManualAction:   false,
+			expectedCompensations:  []string{"ホテルルーム補償(room-101)"},
+			expectedRecordedStatus: activities.StuckCompensationRetried,
+		},
+		"正常系 - オペレーターが手動で解決済みとする": {
+			resolution: &CompensationResolution{
+				Step:       "ホテルルーム補償",
+				ResourceID: "room-101",
+				Action:     CompensationActionMarkResolved,
+				Note:       "ホテルに電話してキャンセル済み",
+			},
+			expectedRollbackStatus: CompensationStatusRolledBack,
+			expectedManualAction:   false,
+			expectedCompensations:  []string{"ホテルルーム補償(room-101)"},
+			expectedRecordedStatus: activities.StuckCompensationManuallyResolved,
+		},
+		"異常系 - オペレーターの指示がないまま手動対応待ちがタイムアウトする": {
+			resolution:             nil,
+			expectedRollbackStatus: CompensationStatusPartiallyFailed,
+			expectedManualAction:   true,
+			expectedCompensations:  nil,
+			expectedRecordedStatus: activities.StuckCompensationPending,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			sink := activities.NewInMemoryStuckCompensationSink()
+			activities.SetStuckCompensationSink(sink)
+			defer activities.SetStuckCompensationSink(activities.NewInMemoryStuckCompensationSink())
+
+			testSuite := &testsuite.WorkflowTestSuite{}
+			testEnv := testSuite.NewTestWorkflowEnvironment()
+			testEnv.RegisterActivity(activities.HotelRoomBookingActivity)
+			testEnv.RegisterActivity(activities.DinnerFoodBookingActivity)
+			testEnv.RegisterActivity(activities.CompensateHotelRoomActivity)
+			testEnv.RegisterActivity(activities.RecordStuckCompensationActivity)
+			testEnv.RegisterActivity(activities.UpdateStuckCompensationActivity)
+
+			request := BookingRequest{
+				BookingID: "booking-stuck-001",
+				UserID:    "user-001",
+				Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+				Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+				Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
+			}
+			testEnv.OnActivity(activities.HotelRoomBookingActivity, mock.Anything, mock.Anything).Return(
+				&activities.HotelBookingResult{Success: true, ResourceID: "room-101"}, nil)
+			testEnv.OnActivity(activities.DinnerFoodBookingActivity, mock.Anything, mock.Anything).Return(
+				nil, OutOfStockError)
+			testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, request.BookingID, "room-101").Return(
+				nil, SystemDownError).Times(3)
+			if tt.resolution != nil && tt.resolution.Action == CompensationActionRetry {
+				testEnv.OnActivity(activities.CompensateHotelRoomActivity, mock.Anything, request.BookingID, "room-101").Return(
+					&activities.CompensationResult{Success: true}, nil).Once()
+			}
+			if tt.resolution != nil {
+				resolution := *tt.resolution
+				testEnv.RegisterDelayedCallback(func() {
+					testEnv.SignalWorkflow(ResolveCompensationSignal, resolution)
+				}, time.Hour)
+			}
+
+			// when
+			testEnv.ExecuteWorkflow(HotelBookingSaga, request)
+
+			// then
+			if !testEnv.IsWorkflowCompleted() {
+				t.Fatal("ワークフローが完了していません")
+			}
+			assert.NoError(t, testEnv.GetWorkflowError())
+
+			var result BookingResult
+			assert.NoError(t, testEnv.GetWorkflowResult(&result))
+			assert.False(t, result.Success)
+			assert.Equal(t, tt.expectedRollbackStatus, result.RollbackStatus)
+			assert.Equal(t, tt.expectedManualAction, result.ManualActionRequired)
+			assert.Equal(t, tt.expectedCompensations, result.Compensations)
+
+			recorded, err := sink.List(context.Background())
+			assert.NoError(t, err)
+			if assert.Len(t, recorded, 1) {
+				assert.Equal(t, "booking-stuck-001", recorded[0].BookingID)
+				assert.Equal(t, "ホテルルーム補償", recorded[0].Step)
+				assert.Equal(t, "room-101", recorded[0].ResourceID)
+				assert.Equal(t, tt.expectedRecordedStatus, recorded[0].Status)
+			}
+			testEnv.AssertExpectations(t)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package workflows
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -9,6 +10,15 @@ import (
 	"temporal-hotel-sample/internal/activities"
 )
 
+// テスト用の予約日程（ディナーと駐車場は宿泊期間内）
+var (
+	testCheckIn      = time.Date(2026, 11, 1, 15, 0, 0, 0, time.UTC)
+	testCheckOut     = time.Date(2026, 11, 3, 10, 0, 0, 0, time.UTC)
+	testDinnerTime   = time.Date(2026, 11, 1, 19, 0, 0, 0, time.UTC)
+	testParkingStart = time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC)
+	testParkingEnd   = time.Date(2026, 11, 3, 11, 0, 0, 0, time.UTC)
+)
+
 // TestScenario テストシナリオの定義
 type TestScenario struct {
 	Name    string
@@ -58,9 +68,9 @@ func (b *TestScenarioBuilder) WithBookingRequest(bookingID, userID string) *Test
 	b.scenario.Request = BookingRequest{
 		BookingID: bookingID,
 		UserID:    userID,
-		Hotel:     HotelRequest{HotelID: "hotel-001"},
-		Dinner:    DinnerRequest{MenuType: "standard"},
-		Parking:   ParkingRequest{SpaceType: "standard"},
+		Hotel:     HotelRequest{HotelID: "hotel-001", CheckIn: testCheckIn, CheckOut: testCheckOut},
+		Dinner:    &DinnerRequest{MenuType: "standard", DateTime: testDinnerTime, Guests: 2},
+		Parking:   &ParkingRequest{SpaceType: "standard", StartTime: testParkingStart, EndTime: testParkingEnd},
 	}
 	return b
 }
@@ -258,6 +268,9 @@ func NewWorkflowTestHelper() *WorkflowTestHelper {
 	testEnv.RegisterActivity(activities.CompensateHotelRoomActivity)
 	testEnv.RegisterActivity(activities.CompensateDinnerFoodActivity)
 	testEnv.RegisterActivity(activities.CompensateParkingActivity)
+	testEnv.RegisterActivity(activities.RecordStuckCompensationActivity)
+	testEnv.RegisterActivity(activities.UpdateStuckCompensationActivity)
+	testEnv.RegisterActivity(activities.SendCheckInReminderActivity)
 
 	return &WorkflowTestHelper{
 		testEnv: testEnv,
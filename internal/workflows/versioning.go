@@ -0,0 +1,90 @@
+package workflows
+
+import (
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// ワークフローの変更ID
+// HotelBookingSagaが発行するコマンドの種類や順序を変える変更は、変更IDかバージョンを追加してGetVersionで分岐させる
+// 実行中の予約は開始時に記録されたバージョンで再生されるため、古い分岐は対象の予約が全て終了するまで削除しないこと
+// 変更後は testdata/replay に実行履歴を追加し、TestReplayWorkflowHistories で過去の履歴が再生できることを確認する
+// 予約ステップの構成（bookingPipeline）を変える場合は、bookingStepsVersionを上げて変更前の構成を残すこと
+const (
+	// changeBookingSteps 予約ステップの構成と予約完了後の管理
+	// DefaultVersionは変更IDを導入する前に開始した予約で、ホテル → ディナー → 駐車場を順に予約した時点で終了していた
+	changeBookingSteps = "booking-steps"
+)
+
+// bookingStepsVersion 予約ステップの構成の最新バージョン
+const bookingStepsVersion workflow.Version = 1
+
+// bookingStepsVersionOf 予約に記録された予約ステップの構成のバージョンを返す
+// リクエストのバリデーションも導入前後で異なるため、予約Sagaの開始直後に呼び出すこと
+func bookingStepsVersionOf(ctx workflow.Context) workflow.Version {
+	return workflow.GetVersion(ctx, changeBookingSteps, workflow.DefaultVersion, bookingStepsVersion)
+}
+
+// baselineBookingSteps 変更IDの導入前の予約ステップの構成（変更しないこと）
+var baselineBookingSteps = []SagaStep{hotelStep, dinnerStep, parkingStep}
+
+// runBaselineBookingSteps 変更IDの導入前に開始した予約を、導入前と同じ手順で実行する
+// 全てのステップを必須として順に予約し、失敗した場合は完了済みのステップを逆順に補償する
+// 導入前はキャンセル・手動対応待ち・予約完了後の管理を行っていなかったため、ここでも行わない
+func runBaselineBookingSteps(ctx workflow.Context, request BookingRequest, result *BookingResult, status *BookingStatus) {
+	logger := workflow.GetLogger(ctx)
+
+	var compensations Compensations
+	for i, step := range baselineBookingSteps {
+		status.enter(step.Phase)
+		logger.Info(fmt.Sprintf("ステップ %d: %sを開始", i+1, step.Name), "BookingID", request.BookingID)
+		stepResult, _, err := step.execute(ctx, step.Request(request, 0))
+		if err != nil {
+			logger.Error(fmt.Sprintf("%sに失敗", step.Name), "Error", err.Error())
+			result.recordFailure(step.Step, step.Name, err)
+			if len(compensations) > 0 {
+				logger.Info("補償処理を開始")
+				status.enter(PhaseCompensating)
+				result.applyCompensationReport(compensations.Compensate(ctx, false))
+			}
+			return
+		}
+		step.collect(result, status, stepResult)
+		if compensation, ok := step.compensation(request.BookingID, 0, stepResult.GetResourceID()); ok {
+			compensations.AddCompensation(compensation)
+		}
+	}
+	result.Success = true
+	result.Message = "ホテル予約Sagaが正常に完了しました"
+}
+
+// validateBaseline 変更IDの導入前のリクエストのバリデーション（ディナーと駐車場も必須）
+func (r *BookingRequest) validateBaseline() error {
+	if strings.TrimSpace(r.BookingID) == "" {
+		return fmt.Errorf("BookingID is required")
+	}
+	if strings.TrimSpace(r.UserID) == "" {
+		return fmt.Errorf("UserID is required")
+	}
+	if strings.TrimSpace(r.Hotel.HotelID) == "" {
+		return fmt.Errorf("Hotel.HotelID is required")
+	}
+	if r.Dinner == nil || strings.TrimSpace(r.Dinner.MenuType) == "" {
+		return fmt.Errorf("Dinner.MenuType is required")
+	}
+	if r.Parking == nil || strings.TrimSpace(r.Parking.SpaceType) == "" {
+		return fmt.Errorf("Parking.SpaceType is required")
+	}
+	return nil
+}
+
+// ValidatePipelines 予約ステップの構成を検証する
+// 依存関係の誤りは予約の実行中ではなくワーカーの起動時に検出するため、ワーカー起動時に呼び出すこと
+func ValidatePipelines() error {
+	if err := bookingPipeline.validate(); err != nil {
+		return fmt.Errorf("booking pipeline: %w", err)
+	}
+	return nil
+}